@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -21,7 +22,9 @@ type GetAccountRequest struct {
 }
 
 type ListAccountsRequest struct {
-	Limit  int32 `uri:"limit" binding:"required,min=5"`
+	// the wildcard is shared with the routes nested under /accounts/:id,
+	// gin only lets wildcards at the same position have one name
+	Limit  int32 `uri:"id" binding:"required,min=5"`
 	Offset int32 `uri:"offset" binding:"required,min=5"`
 }
 
@@ -35,13 +38,25 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.CreateAccountParams{
-		Owner:    authPayload.Username,
-		Currency: req.Currency,
-		Balance:  int64(0),
+	idempotency, ok := idempotencyParams(ctx, authPayload.Username, req)
+	if !ok {
+		return
+	}
+
+	arg := db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			Owner:    authPayload.Username,
+			Currency: req.Currency,
+			Balance:  int64(0),
+		},
+		Idempotency: idempotency,
 	}
-	account, err := server.store.CreateAccount(ctx, arg)
+	result, err := server.store.CreateAccountTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
 		pqErr, ok := err.(*pq.Error)
 		if ok {
 			switch pqErr.Code.Name() {
@@ -54,7 +69,8 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	markReplayed(ctx, result.Replayed)
+	ctx.JSON(http.StatusOK, result.Account)
 }
 
 func (server *Server) getAccount(ctx *gin.Context) {
@@ -77,7 +93,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if authPayload.Username != account.Owner {
+	if !canAccessAccount(authPayload, account) {
 		err = fmt.Errorf("this account doesn't belong to the current user !")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
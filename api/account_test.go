@@ -9,9 +9,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	mockdb "github.com/brkss/simplebank/db/mock"
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
 	"github.com/brkss/simplebank/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -22,12 +24,49 @@ func TestGetAccount(t *testing.T) {
 	testCases := []struct {
 		name          string
 		accountID     int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name:      "OK",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "BankerAccess",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, utils.RandomOwner(), utils.BankerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -42,6 +81,9 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:      "NotFound",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, utils.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -55,6 +97,9 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:      "InternalError",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, utils.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -68,6 +113,9 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:      "BadRequest",
 			accountID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, utils.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -96,6 +144,7 @@ func TestGetAccount(t *testing.T) {
 			url := fmt.Sprintf("/account/%d", tc.accountID)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
@@ -127,6 +176,7 @@ func requireMatchAccount(t *testing.T, body *bytes.Buffer, account db.Account) {
 }
 
 func TestListAccounts(t *testing.T) {
+	owner := utils.RandomOwner()
 	accounts := createRandomAccounts(10)
 	testCases := []struct {
 		name          string
@@ -143,7 +193,7 @@ func TestListAccounts(t *testing.T) {
 			offset:   5,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Limit: 5, Offset: 5})).
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: owner, Limit: 5, Offset: 5})).
 					Times(1).
 					Return(accounts[0:5], nil)
 			},
@@ -175,7 +225,7 @@ func TestListAccounts(t *testing.T) {
 			offset:   5,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Limit: 5, Offset: 5})).
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Owner: owner, Limit: 5, Offset: 5})).
 					Times(1).
 					Return([]db.Account{}, sql.ErrConnDone)
 			},
@@ -202,6 +252,7 @@ func TestListAccounts(t *testing.T) {
 			url := fmt.Sprintf("/accounts/%d/%d", tc.limit, tc.offset)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner, utils.DepositorRole, time.Minute)
 
 			server.router.ServeHTTP(recorder, request)
 
@@ -299,7 +350,7 @@ func TestCreateAccount(t *testing.T) {
 			}
 	}
 */
-func checkCreateAccountResponse(t *testing.T, body *bytes.Buffer, arg CreateAccountRequest) {
+func checkCreateAccountResponse(t *testing.T, body *bytes.Buffer, owner string, arg CreateAccountRequest) {
 
 	data, err := ioutil.ReadAll(body)
 	require.NoError(t, err)
@@ -308,8 +359,8 @@ func checkCreateAccountResponse(t *testing.T, body *bytes.Buffer, arg CreateAcco
 	err = json.Unmarshal(data, &account)
 	require.NoError(t, err)
 
-	require.Equal(t, account.Balance, 0)
-	require.Equal(t, account.Owner, arg.Owner)
+	require.Equal(t, account.Balance, int64(0))
+	require.Equal(t, account.Owner, owner)
 	require.Equal(t, account.Currency, arg.Currency)
 
 }
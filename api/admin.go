@@ -0,0 +1,130 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+type ListPageRequest struct {
+	Limit  int32 `form:"limit" binding:"required,min=1,max=100"`
+	Offset int32 `form:"offset" binding:"min=0"`
+}
+
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req ListPageRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.ListUsers(ctx, db.ListUsersParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]CreateUserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, newUserResponse(user))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type UpdateUserRoleUri struct {
+	Username string `uri:"username" binding:"required"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=depositor banker admin"`
+}
+
+// updateUserRole changes the role of a user, a change signs the user out of every session
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri UpdateUserRoleUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+type UnlockUserUri struct {
+	Username string `uri:"username" binding:"required"`
+}
+
+// unlockUser lifts the lockout a user got from failed logins and resets their counter
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri UnlockUserUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.clearFailedLogins(ctx, uri.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (server *Server) listAllAccounts(ctx *gin.Context) {
+	var req ListPageRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accounts, err := server.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyHeaderKey = "x-api-key"
+	// apiKeyContextKey is set on requests authenticated with an API key
+	apiKeyContextKey = "auth_api_key"
+
+	// keys look like sbk_<secret>, the first apiKeyPrefixLength characters
+	// are stored in clear to find the key back and to tell keys apart in listings
+	apiKeyTag          = "sbk_"
+	apiKeyPrefixLength = len(apiKeyTag) + 8
+	apiKeySecretBytes  = 32
+)
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+// newAPIKey generates a key and returns it along with its lookup prefix
+func newAPIKey() (string, string, error) {
+	secret, err := utils.GenerateSecureToken(apiKeySecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+	key := apiKeyTag + secret
+	return key, key[:apiKeyPrefixLength], nil
+}
+
+// authenticateAPIKey resolves key into a principal equivalent to the payload of an access token,
+// it aborts the request and returns nil when the key isn't acceptable.
+// API keys aren't affected by the revocation store, deleting the key is what revokes it
+func authenticateAPIKey(ctx *gin.Context, store db.Store, key string) *token.Payload {
+	if !strings.HasPrefix(key, apiKeyTag) || len(key) <= apiKeyPrefixLength {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return nil
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, key[:apiKeyPrefixLength])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+			return nil
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil
+	}
+
+	hashedKey := utils.HashSecureToken(key)
+	if subtle.ConstantTimeCompare([]byte(hashedKey), []byte(apiKey.HashedKey)) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return nil
+	}
+
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		err := errors.New("api key has expired")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	// the role is read on every request so demoting the owner demotes their keys too
+	user, err := store.GetUser(ctx, apiKey.Username)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil
+	}
+
+	err = store.TouchAPIKey(ctx, apiKey.ID)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil
+	}
+
+	ctx.Set(apiKeyContextKey, apiKey.ID)
+	return &token.Payload{
+		ID:       apiKey.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Scopes:   apiKey.Scopes,
+		IssuedAt: apiKey.CreatedAt,
+		// zero for keys that never expire
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	}
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.ExpiresAt.Valid {
+		resp.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		resp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	return resp
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=64"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:read transfers:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	// Key is only ever shown here, the server keeps a hash of it
+	Key string `json:"key"`
+	APIKeyResponse
+}
+
+// createAPIKey issues a key acting for the current user with at most the scopes of their token
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req CreateAPIKeyRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, scope := range req.Scopes {
+		if !authPayload.HasScope(scope) {
+			err := fmt.Errorf("cannot grant the %s scope the current token doesn't have", scope)
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			err := errors.New("expires_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:        uuid.New(),
+		Username:  authPayload.Username,
+		Name:      req.Name,
+		Prefix:    prefix,
+		HashedKey: utils.HashSecureToken(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		Key:            key,
+		APIKeyResponse: newAPIKeyResponse(apiKey),
+	})
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	apiKeys, err := server.store.ListAPIKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type DeleteAPIKeyUri struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// deleteAPIKey revokes one of the current user's keys for good
+func (server *Server) deleteAPIKey(ctx *gin.Context) {
+	var uri DeleteAPIKeyUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	deleted, err := server.store.DeleteAPIKey(ctx, db.DeleteAPIKeyParams{
+		ID:       uuid.MustParse(uri.ID),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		err := errors.New("api key not found")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
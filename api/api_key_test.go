@@ -0,0 +1,576 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey returns a stored key of user along with the clear key it was made from
+func randomAPIKey(t *testing.T, user db.User, scopes ...string) (db.ApiKey, string) {
+	key, prefix, err := newAPIKey()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		Name:      utils.RandomOwner(),
+		Prefix:    prefix,
+		HashedKey: utils.HashSecureToken(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	return apiKey, key
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key1, prefix1, err := newAPIKey()
+	require.NoError(t, err)
+	require.True(t, len(key1) > apiKeyPrefixLength)
+	require.Equal(t, key1[:apiKeyPrefixLength], prefix1)
+	require.Equal(t, apiKeyTag, prefix1[:len(apiKeyTag)])
+
+	key2, prefix2, err := newAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key1, key2)
+	require.NotEqual(t, prefix1, prefix2)
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, key := randomAPIKey(t, user, utils.AccountsReadScope)
+
+	testCases := []struct {
+		name          string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var payload token.Payload
+				err := json.Unmarshal(recorder.Body.Bytes(), &payload)
+				require.NoError(t, err)
+				require.Equal(t, apiKey.ID, payload.ID)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, user.Role, payload.Role)
+				require.Equal(t, apiKey.Scopes, payload.Scopes)
+			},
+		},
+		{
+			name: "MissingScope",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.ApiKey{
+						ID:        apiKey.ID,
+						Username:  apiKey.Username,
+						Prefix:    apiKey.Prefix,
+						HashedKey: apiKey.HashedKey,
+						Scopes:    []string{utils.TransfersReadScope},
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WrongSecret",
+			key:  apiKey.Prefix + "not-the-secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownKey",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MalformedKey",
+			key:  "not-a-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredKey",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			server.router.GET("/auth", scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.AccountsReadScope), func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, ctx.MustGet(authorizationPayloadKey))
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+			request.Header.Set(apiKeyHeaderKey, tc.key)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAPIKeyStaffRoutes(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = utils.AdminRole
+	apiKey, key := randomAPIKey(t, admin, utils.DefaultScopes...)
+
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+	}{
+		{name: "ListUsers", method: http.MethodGet, url: "/admin/users?limit=5"},
+		{name: "UpdateUserRole", method: http.MethodPatch, url: "/admin/users/someone/role"},
+		{name: "ListAllAccounts", method: http.MethodGet, url: "/admin/accounts?limit=5"},
+		{name: "ListPendingApprovals", method: http.MethodGet, url: "/transfer_approvals?limit=5"},
+		{name: "ApproveTransfer", method: http.MethodPost, url: "/transfer_approvals/1/approve"},
+		{name: "RejectTransfer", method: http.MethodPost, url: "/transfer_approvals/1/reject"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// nothing past authentication may touch the store
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+				Times(1).
+				Return(apiKey, nil)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+				Times(1).
+				Return(admin, nil)
+			store.EXPECT().
+				TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+				Times(1).
+				Return(nil)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			request.Header.Set(apiKeyHeaderKey, key)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+		})
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":   "reconciliation",
+				"scopes": []string{utils.AccountsReadScope, utils.TransfersReadScope},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "reconciliation", arg.Name)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{
+							ID:        arg.ID,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							HashedKey: arg.HashedKey,
+							Scopes:    arg.Scopes,
+							ExpiresAt: arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response CreateAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, response.Prefix, response.Key[:apiKeyPrefixLength])
+				require.Equal(t, []string{utils.AccountsReadScope, utils.TransfersReadScope}, response.Scopes)
+				require.Nil(t, response.ExpiresAt)
+				require.NotContains(t, recorder.Body.String(), utils.HashSecureToken(response.Key))
+			},
+		},
+		{
+			name: "WithExpiry",
+			body: gin.H{
+				"name":       "partner",
+				"scopes":     []string{utils.AccountsReadScope},
+				"expires_at": time.Now().Add(24 * time.Hour),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.True(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{ID: arg.ID, Prefix: arg.Prefix, Scopes: arg.Scopes, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response CreateAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.NotNil(t, response.ExpiresAt)
+			},
+		},
+		{
+			name: "ExpiryInThePast",
+			body: gin.H{
+				"name":       "partner",
+				"scopes":     []string{utils.AccountsReadScope},
+				"expires_at": time.Now().Add(-time.Hour),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{
+				"name":   "reconciliation",
+				"scopes": []string{"everything"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ScopeBeyondToken",
+			body: gin.H{
+				"name":   "reconciliation",
+				"scopes": []string{utils.TransfersWriteScope},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithScopes(utils.AccountsReadScope))
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WithAPIKey",
+			body: gin.H{
+				"name":   "reconciliation",
+				"scopes": []string{utils.AccountsReadScope},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(apiKeyHeaderKey, "sbk_not-checked-by-this-test")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{
+						ID:        uuid.New(),
+						Username:  user.Username,
+						HashedKey: utils.HashSecureToken("sbk_not-checked-by-this-test"),
+						Scopes:    utils.DefaultScopes,
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// a leaked key must not be able to mint more keys
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"name":   "reconciliation",
+				"scopes": []string{utils.AccountsReadScope},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey1, _ := randomAPIKey(t, user, utils.AccountsReadScope)
+	apiKey2, _ := randomAPIKey(t, user, utils.TransfersReadScope)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.ApiKey{apiKey1, apiKey2}, nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/api_keys", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response []APIKeyResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Len(t, response, 2)
+	require.Equal(t, apiKey1.Prefix, response[0].Prefix)
+	require.Equal(t, apiKey2.Prefix, response[1].Prefix)
+	require.NotContains(t, recorder.Body.String(), apiKey1.HashedKey)
+}
+
+func TestDeleteAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user, utils.AccountsReadScope)
+
+	testCases := []struct {
+		name          string
+		id            string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteAPIKey(gomock.Any(), gomock.Eq(db.DeleteAPIKeyParams{ID: apiKey.ID, Username: user.Username})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   apiKey.ID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "not-a-uuid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api_keys/%s", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
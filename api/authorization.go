@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// requireRoles lets the request through only when the authenticated user holds one of the roles,
+// it must run after authMiddleware
+func requireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !hasRole(authPayload, roles...) {
+			err := fmt.Errorf("role %q is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// hasRole checks if the user behind payload holds one of the roles
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessAccount reports whether the user behind payload may view or operate on account,
+// owners always can while back-office staff can access any customer's account
+func canAccessAccount(payload *token.Payload, account db.Account) bool {
+	return payload.Username == account.Owner || hasRole(payload, utils.BankerRole, utils.AdminRole)
+}
+
+// requireVerifiedEmail blocks users who haven't confirmed their email yet when the
+// REQUIRE_EMAIL_VERIFICATION switch is on, it must run after authMiddleware
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.config.RequireEmailVerification {
+			ctx.Next()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := server.store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !user.IsEmailVerified {
+			err := fmt.Errorf("email address must be verified first")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// requireUserSession turns away requests authenticated with an API key or a token
+// delegated to an OAuth client, for routes managing the user's own credentials and
+// sessions and for staff routes, since a key carries the live role of its owner,
+// it must run after authMiddleware
+func requireUserSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(apiKeyContextKey); ok {
+			err := fmt.Errorf("api keys are not allowed to access this resource")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if authPayload.ClientID != "" {
+			err := fmt.Errorf("tokens delegated to an oauth client are not allowed to access this resource")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type BatchTransferLegRequest struct {
+	ToAccountID int64 `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64 `json:"amount" binding:"required,gt=1"`
+}
+
+type CreateBatchTransferRequest struct {
+	FromAccountID int64                     `json:"from_account_id" binding:"required,min=1"`
+	Currency      string                    `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	Mode          string                    `json:"mode" binding:"required,oneof=atomic best_effort"`
+	Legs          []BatchTransferLegRequest `json:"legs" binding:"required,min=1,max=1000,dive"`
+}
+
+type TransferBatchItemResponse struct {
+	Leg         int32  `json:"leg"`
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Status      string `json:"status"`
+	TransferID  *int64 `json:"transfer_id,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type TransferBatchResponse struct {
+	ID             int64                       `json:"id"`
+	FromAccountID  int64                       `json:"from_account_id"`
+	Currency       string                      `json:"currency"`
+	Mode           string                      `json:"mode"`
+	Status         string                      `json:"status"`
+	LegCount       int64                       `json:"leg_count"`
+	SucceededCount int64                       `json:"succeeded_count"`
+	CreatedAt      time.Time                   `json:"created_at"`
+	Items          []TransferBatchItemResponse `json:"items"`
+}
+
+func newTransferBatchResponse(batch db.TransferBatch, items []db.TransferBatchItem) TransferBatchResponse {
+	resp := TransferBatchResponse{
+		ID:             batch.ID,
+		FromAccountID:  batch.FromAccountID,
+		Currency:       batch.Currency,
+		Mode:           batch.Mode,
+		Status:         batch.Status,
+		LegCount:       batch.LegCount,
+		SucceededCount: batch.SucceededCount,
+		CreatedAt:      batch.CreatedAt,
+		Items:          make([]TransferBatchItemResponse, 0, len(items)),
+	}
+	for _, item := range items {
+		itemResp := TransferBatchItemResponse{
+			Leg:         item.Leg,
+			ToAccountID: item.ToAccountID,
+			Amount:      item.Amount,
+			Status:      item.Status,
+			Error:       item.Error,
+		}
+		if item.TransferID.Valid {
+			transferID := item.TransferID.Int64
+			itemResp.TransferID = &transferID
+		}
+		resp.Items = append(resp.Items, itemResp)
+	}
+	return resp
+}
+
+// createBatchTransfer pays several accounts from one of the current user's accounts in a single request,
+// an atomic batch is rejected as a whole when one of its legs can't be made
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req CreateBatchTransferRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	idempotency, valid := idempotencyParams(ctx, authPayload.Username, req)
+	if !valid {
+		return
+	}
+
+	legs := make([]db.BatchTransferLeg, 0, len(req.Legs))
+	for _, leg := range req.Legs {
+		legs = append(legs, db.BatchTransferLeg{
+			ToAccountID: leg.ToAccountID,
+			Amount:      leg.Amount,
+		})
+	}
+
+	result, err := server.store.BatchTransferTx(ctx, db.BatchTransferTxParams{
+		Owner:             authPayload.Username,
+		FromAccountID:     req.FromAccountID,
+		Mode:              req.Mode,
+		Legs:              legs,
+		ApprovalThreshold: server.config.TransferApprovalThreshold,
+		Idempotency:       idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(limitExceededCode, err))
+			return
+		}
+		if errors.Is(err, db.ErrApprovalRequired) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(approvalRequiredCode, err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) ||
+			errors.Is(err, db.ErrCurrencyMismatch) ||
+			errors.Is(err, db.ErrDestinationNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	markReplayed(ctx, result.Replayed)
+	ctx.JSON(http.StatusOK, newTransferBatchResponse(result.Batch, result.Items))
+}
+
+type TransferBatchUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getBatchTransfer returns a batch of the current user with the outcome of each of its legs
+func (server *Server) getBatchTransfer(ctx *gin.Context) {
+	var uri TransferBatchUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	batch, err := server.store.GetTransferBatch(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if batch.Owner != authPayload.Username {
+		err := errors.New("batch doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	items, err := server.store.ListTransferBatchItems(ctx, batch.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferBatchResponse(batch, items))
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account := randomAccount()
+	account.ID = utils.RandomInt(1, 1000)
+	account.Owner = user1.Username
+	account.Currency = "USD"
+
+	legs := []gin.H{
+		{"to_account_id": account.ID + 1, "amount": 100},
+		{"to_account_id": account.ID + 2, "amount": 200},
+	}
+	body := gin.H{
+		"from_account_id": account.ID,
+		"currency":        "USD",
+		"mode":            "best_effort",
+		"legs":            legs,
+	}
+
+	batch := db.TransferBatch{
+		ID:             utils.RandomInt(1, 1000),
+		Owner:          user1.Username,
+		FromAccountID:  account.ID,
+		Currency:       "USD",
+		Mode:           db.BatchModeBestEffort,
+		Status:         db.TransferBatchStatusPartial,
+		LegCount:       2,
+		SucceededCount: 1,
+	}
+	items := []db.TransferBatchItem{
+		{
+			BatchID:     batch.ID,
+			Leg:         0,
+			ToAccountID: account.ID + 1,
+			Amount:      100,
+			Status:      db.TransferBatchItemStatusSucceeded,
+			TransferID:  sql.NullInt64{Int64: 7, Valid: true},
+		},
+		{
+			BatchID:     batch.ID,
+			Leg:         1,
+			ToAccountID: account.ID + 2,
+			Amount:      200,
+			Status:      db.TransferBatchItemStatusFailed,
+			Error:       db.ErrInsufficientFunds.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "BestEffort",
+			body:     body,
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.BatchTransferTxParams{
+					Owner:         user1.Username,
+					FromAccountID: account.ID,
+					Mode:          db.BatchModeBestEffort,
+					Legs: []db.BatchTransferLeg{
+						{ToAccountID: account.ID + 1, Amount: 100},
+						{ToAccountID: account.ID + 2, Amount: 200},
+					},
+					ApprovalThreshold: 1000,
+				}
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.BatchTransferTxResult{Batch: batch, Items: items}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp TransferBatchResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, batch.ID, resp.ID)
+				require.Equal(t, db.TransferBatchStatusPartial, resp.Status)
+				require.Len(t, resp.Items, 2)
+				require.Equal(t, int64(7), *resp.Items[0].TransferID)
+				require.Nil(t, resp.Items[1].TransferID)
+				require.Equal(t, db.ErrInsufficientFunds.Error(), resp.Items[1].Error)
+			},
+		},
+		{
+			name: "AtomicLegFails",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "atomic",
+				"legs":            legs,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, fmt.Errorf("leg 1: %w", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "leg 1")
+			},
+		},
+		{
+			name: "AtomicLegOverLimit",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "atomic",
+				"legs":            legs,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, fmt.Errorf("leg 1: %w: daily outgoing total is 100", db.ErrLimitExceeded))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), limitExceededCode)
+				require.Contains(t, recorder.Body.String(), "leg 1")
+			},
+		},
+		{
+			name: "AtomicLegNeedsApproval",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "atomic",
+				"legs":            []gin.H{{"to_account_id": account.ID + 1, "amount": 1001}},
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, fmt.Errorf("leg 0: %w", db.ErrApprovalRequired))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), approvalRequiredCode)
+			},
+		},
+		{
+			name:     "NotOwner",
+			body:     body,
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMode",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "sometimes",
+				"legs":            legs,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoLegs",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "atomic",
+				"legs":            []gin.H{},
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLegAmount",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"currency":        "USD",
+				"mode":            "atomic",
+				"legs":            []gin.H{{"to_account_id": account.ID + 1, "amount": -5}},
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := serveAuthorizedRequest(t, store, http.MethodPost, "/transfers/batch", tc.body, tc.username)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetBatchTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	batch := db.TransferBatch{
+		ID:       utils.RandomInt(1, 1000),
+		Owner:    user1.Username,
+		Mode:     db.BatchModeAtomic,
+		Status:   db.TransferBatchStatusSucceeded,
+		LegCount: 1,
+	}
+
+	testCases := []struct {
+		name         string
+		username     string
+		buildStubs   func(store *mockdb.MockStore)
+		expectedCode int
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListTransferBatchItems(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return([]db.TransferBatchItem{}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:     "Unauthorized",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(batch, nil)
+				store.EXPECT().ListTransferBatchItems(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "NotFound",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferBatch(gomock.Any(), gomock.Eq(batch.ID)).Times(1).Return(db.TransferBatch{}, sql.ErrNoRows)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/transfers/batch/%d", batch.ID)
+			recorder := serveAuthorizedRequest(t, store, http.MethodGet, url, nil, tc.username)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/statement"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountEntriesUri identifies the account of the routes nested under /accounts/:id
+type AccountEntriesUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ListAccountEntriesRequest pages through the entries of a period, From is included and To excluded
+type ListAccountEntriesRequest struct {
+	Limit  int32     `form:"limit" binding:"required,min=1,max=100"`
+	Offset int32     `form:"offset" binding:"min=0"`
+	From   time.Time `form:"from"`
+	To     time.Time `form:"to"`
+}
+
+type StatementEntryResponse struct {
+	ID     int64 `json:"id"`
+	Amount int64 `json:"amount"`
+	// BalanceAfter is the balance of the account once the entry was posted
+	BalanceAfter int64     `json:"balance_after"`
+	CreatedAt    time.Time `json:"created_at"`
+	TransferID   *int64    `json:"transfer_id,omitempty"`
+	// CounterpartyAccountID is the other account of the transfer behind the entry
+	CounterpartyAccountID *int64 `json:"counterparty_account_id,omitempty"`
+}
+
+type AccountStatementResponse struct {
+	AccountID      int64                    `json:"account_id"`
+	Currency       string                   `json:"currency"`
+	From           *time.Time               `json:"from,omitempty"`
+	To             *time.Time               `json:"to,omitempty"`
+	OpeningBalance int64                    `json:"opening_balance"`
+	ClosingBalance int64                    `json:"closing_balance"`
+	Entries        []StatementEntryResponse `json:"entries"`
+}
+
+func newStatementEntryResponse(entry db.ListEntriesByAccountRow) StatementEntryResponse {
+	resp := StatementEntryResponse{
+		ID:           entry.ID,
+		Amount:       entry.Amount,
+		BalanceAfter: entry.BalanceAfter,
+		CreatedAt:    entry.CreatedAt,
+	}
+	if entry.TransferID.Valid {
+		resp.TransferID = &entry.TransferID.Int64
+	}
+	if entry.CounterpartyAccountID.Valid {
+		resp.CounterpartyAccountID = &entry.CounterpartyAccountID.Int64
+	}
+	return resp
+}
+
+// listAccountEntries returns the statement of an account over a period, each entry with the balance it left
+// and the balances at the start and the end of the period
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	var uri AccountEntriesUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req ListAccountEntriesRequest
+	err = ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.To.After(req.From) {
+		err := errors.New("to must come after from")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.findAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canAccessAccount(authPayload, account) {
+		err := errors.New("account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	arg := db.AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		To:        sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+	}
+	statement, err := server.store.AccountStatementTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := AccountStatementResponse{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+		Entries:        make([]StatementEntryResponse, 0, len(statement.Entries)),
+	}
+	if arg.From.Valid {
+		resp.From = &req.From
+	}
+	if arg.To.Valid {
+		resp.To = &req.To
+	}
+	for _, entry := range statement.Entries {
+		resp.Entries = append(resp.Entries, newStatementEntryResponse(entry))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// ExportStatementRequest picks the format and the period of an exported statement,
+// the period defaults to the whole life of the account up to now
+type ExportStatementRequest struct {
+	Format string    `form:"format" binding:"required,oneof=csv ofx camt053"`
+	From   time.Time `form:"from"`
+	To     time.Time `form:"to"`
+}
+
+// exportAccountStatement streams the statement of an account as a file to import in spreadsheets
+// or accounting software, entries are written out as they are read
+func (server *Server) exportAccountStatement(ctx *gin.Context) {
+	var uri AccountEntriesUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req ExportStatementRequest
+	err = ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.findAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canAccessAccount(authPayload, account) {
+		err := errors.New("account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	if req.From.IsZero() {
+		req.From = account.CreatedAt
+	}
+	if req.To.IsZero() {
+		req.To = now
+	}
+	if !req.To.After(req.From) {
+		err := errors.New("to must come after from")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	writer, err := statement.NewWriter(req.Format, ctx.Writer)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	started := false
+	err = server.store.ExportAccountStatementTx(ctx, db.ExportAccountStatementTxParams{
+		AccountID: account.ID,
+		From:      sql.NullTime{Time: req.From, Valid: true},
+		To:        sql.NullTime{Time: req.To, Valid: true},
+		OnBalances: func(openingBalance int64, closingBalance int64) error {
+			started = true
+			filename := fmt.Sprintf("statement-%d-%s-%s.%s",
+				account.ID,
+				req.From.UTC().Format("20060102"),
+				req.To.UTC().Format("20060102"),
+				statement.FileExtension(req.Format),
+			)
+			ctx.Header("Content-Type", statement.ContentType(req.Format))
+			ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			ctx.Status(http.StatusOK)
+
+			return writer.Begin(statement.Statement{
+				AccountID:      account.ID,
+				Owner:          account.Owner,
+				Currency:       account.Currency,
+				From:           req.From,
+				To:             req.To,
+				OpeningBalance: openingBalance,
+				ClosingBalance: closingBalance,
+				GeneratedAt:    now,
+			})
+		},
+		OnEntry: func(entry db.ListAccountStatementEntriesRow, balanceAfter int64) error {
+			return writer.Line(statement.Line{
+				EntryID:               entry.ID,
+				TransferID:            entry.TransferID.Int64,
+				CounterpartyAccountID: entry.CounterpartyAccountID.Int64,
+				Amount:                entry.Amount,
+				BalanceAfter:          balanceAfter,
+				BookedAt:              entry.CreatedAt,
+			})
+		},
+	})
+	if err == nil {
+		err = writer.End()
+	}
+	if err != nil {
+		if !started {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		// the status went out with the first bytes, the client is left with a truncated file
+		ctx.Error(err)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	account := randomAccount()
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	entries := []db.ListEntriesByAccountRow{
+		{
+			ID:                    1,
+			AccountID:             account.ID,
+			Amount:                -30,
+			CreatedAt:             from.Add(time.Hour),
+			Currency:              account.Currency,
+			TransferID:            sql.NullInt64{Int64: 7, Valid: true},
+			BalanceAfter:          70,
+			CounterpartyAccountID: sql.NullInt64{Int64: account.ID + 1, Valid: true},
+		},
+		{
+			ID:           2,
+			AccountID:    account.ID,
+			Amount:       -5,
+			CreatedAt:    from.Add(2 * time.Hour),
+			Currency:     account.Currency,
+			BalanceAfter: 65,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			query:    fmt.Sprintf("limit=10&offset=0&from=%s&to=%s", from.Format(time.RFC3339), to.Format(time.RFC3339)),
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AccountStatementTx(gomock.Any(), gomock.Eq(db.AccountStatementTxParams{
+						AccountID: account.ID,
+						From:      sql.NullTime{Time: from, Valid: true},
+						To:        sql.NullTime{Time: to, Valid: true},
+						Limit:     10,
+					})).
+					Times(1).
+					Return(db.AccountStatementTxResult{
+						OpeningBalance: 100,
+						ClosingBalance: 65,
+						Entries:        entries,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var statement AccountStatementResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &statement)
+				require.NoError(t, err)
+				require.Equal(t, account.ID, statement.AccountID)
+				require.Equal(t, int64(100), statement.OpeningBalance)
+				require.Equal(t, int64(65), statement.ClosingBalance)
+				require.True(t, from.Equal(*statement.From))
+				require.True(t, to.Equal(*statement.To))
+				require.Len(t, statement.Entries, 2)
+				require.Equal(t, int64(70), statement.Entries[0].BalanceAfter)
+				require.Equal(t, int64(7), *statement.Entries[0].TransferID)
+				require.Equal(t, account.ID+1, *statement.Entries[0].CounterpartyAccountID)
+				require.Nil(t, statement.Entries[1].TransferID)
+				require.Nil(t, statement.Entries[1].CounterpartyAccountID)
+			},
+		},
+		{
+			name:     "OpenPeriod",
+			query:    "limit=10",
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AccountStatementTx(gomock.Any(), gomock.Eq(db.AccountStatementTxParams{
+						AccountID: account.ID,
+						Limit:     10,
+					})).
+					Times(1).
+					Return(db.AccountStatementTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), `"from"`)
+				require.Contains(t, recorder.Body.String(), `"entries":[]`)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			query:    "limit=10",
+			username: "unauthorized_user",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			query:    "limit=10",
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidPeriod",
+			query:    fmt.Sprintf("limit=10&from=%s&to=%s", to.Format(time.RFC3339), from.Format(time.RFC3339)),
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidDate",
+			query:    "limit=10&from=yesterday",
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingLimit",
+			query:    "",
+			username: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query)
+			recorder := serveRequestAs(t, store, http.MethodGet, url, nil, tc.username, utils.DepositorRole)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// exportStatement plays the store side of ExportAccountStatementTx, feeding entries to the callbacks
+func exportStatement(t *testing.T, openingBalance int64, entries []db.ListAccountStatementEntriesRow) func(ctx context.Context, arg db.ExportAccountStatementTxParams) error {
+	return func(ctx context.Context, arg db.ExportAccountStatementTxParams) error {
+		closingBalance := openingBalance
+		for _, entry := range entries {
+			closingBalance += entry.Amount
+		}
+
+		err := arg.OnBalances(openingBalance, closingBalance)
+		require.NoError(t, err)
+
+		balance := openingBalance
+		for _, entry := range entries {
+			balance += entry.Amount
+			err = arg.OnEntry(entry, balance)
+			require.NoError(t, err)
+		}
+		return nil
+	}
+}
+
+func TestExportAccountStatementAPI(t *testing.T) {
+	account := randomAccount()
+	account.Currency = "USD"
+	account.CreatedAt = time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	period := fmt.Sprintf("from=%s&to=%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	entries := []db.ListAccountStatementEntriesRow{
+		{
+			ID:                    1,
+			Amount:                -3050,
+			CreatedAt:             from.Add(time.Hour),
+			TransferID:            sql.NullInt64{Int64: 7, Valid: true},
+			CounterpartyAccountID: sql.NullInt64{Int64: 99, Valid: true},
+		},
+		{
+			ID:        2,
+			Amount:    -25,
+			CreatedAt: from.Add(2 * time.Hour),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "CSV",
+			query:    "format=csv&" + period,
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ExportAccountStatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.ExportAccountStatementTxParams) error {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Equal(t, sql.NullTime{Time: from, Valid: true}, arg.From)
+						require.Equal(t, sql.NullTime{Time: to, Valid: true}, arg.To)
+						return exportStatement(t, 10000, entries)(ctx, arg)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+				filename := fmt.Sprintf(`attachment; filename="statement-%d-20240101-20240201.csv"`, account.ID)
+				require.Equal(t, filename, recorder.Header().Get("Content-Disposition"))
+
+				lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+				require.Len(t, lines, 3)
+				require.Equal(t, "2024-01-01T01:00:00Z,1,7,99,Transfer 7 to account 99,-30.50,USD,69.50", lines[1])
+				require.Equal(t, "2024-01-01T02:00:00Z,2,,,Entry 2,-0.25,USD,69.25", lines[2])
+			},
+		},
+		{
+			name:     "Camt053",
+			query:    "format=camt053&" + period,
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ExportAccountStatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(exportStatement(t, 10000, entries))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Body.String(), "<Cd>OPBD</Cd>")
+				require.Contains(t, recorder.Body.String(), `<Amt Ccy="USD">30.50</Amt>`)
+				require.Contains(t, recorder.Body.String(), "</Document>")
+			},
+		},
+		{
+			name:     "DefaultPeriod",
+			query:    "format=ofx",
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ExportAccountStatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.ExportAccountStatementTxParams) error {
+						require.Equal(t, sql.NullTime{Time: account.CreatedAt, Valid: true}, arg.From)
+						require.WithinDuration(t, time.Now(), arg.To.Time, time.Second)
+						return exportStatement(t, 0, nil)(ctx, arg)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/x-ofx", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Body.String(), "<DTSTART>20230601000000.000[0:GMT]</DTSTART>")
+				require.Contains(t, recorder.Body.String(), "<BALAMT>0.00</BALAMT>")
+			},
+		},
+		{
+			name:     "BankerAccess",
+			query:    "format=csv&" + period,
+			username: "banker",
+			role:     utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ExportAccountStatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(exportStatement(t, 0, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			query:    "format=csv&" + period,
+			username: "unauthorized_user",
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ExportAccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "UnsupportedFormat",
+			query:    "format=pdf&" + period,
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ExportAccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "FromAfterNow",
+			query:    "format=csv&from=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ExportAccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			query:    "format=csv&" + period,
+			username: account.Owner,
+			role:     utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ExportAccountStatementTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Content-Disposition"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/accounts/%d/statement?%s", account.ID, tc.query)
+			recorder := serveRequestAs(t, store, http.MethodGet, url, nil, tc.username, tc.role)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/fx"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateFXQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,oneof=USD EUR CAD"`
+	ToCurrency   string `json:"to_currency" binding:"required,oneof=USD EUR CAD,nefield=FromCurrency"`
+}
+
+type FXQuoteResponse struct {
+	ID           uuid.UUID `json:"id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// createFXQuote locks the current rate between two currencies for FXQuoteDuration,
+// the quote is then passed to a single cross-currency transfer
+func (server *Server) createFXQuote(ctx *gin.Context) {
+	var req CreateFXQuoteRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rate, err := server.rates.Rate(ctx, req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateUnavailable) {
+			ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := server.store.CreateFXQuote(ctx, db.CreateFXQuoteParams{
+		ID:           uuid.New(),
+		Username:     authPayload.Username,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         utils.FormatRate(rate),
+		ExpiresAt:    time.Now().Add(server.config.FXQuoteDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, FXQuoteResponse{
+		ID:           quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         quote.Rate,
+		ExpiresAt:    quote.ExpiresAt,
+	})
+}
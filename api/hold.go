@@ -0,0 +1,199 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthorizeTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" binding:"required,gt=1"`
+	Currency      string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+}
+
+// authorizeTransfer holds funds on the source account for a transfer settled later through
+// captureTransfer or voidTransfer, holds left alone are released after HoldDuration
+func (server *Server) authorizeTransfer(ctx *gin.Context) {
+	var request AuthorizeTransferRequest
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, request.FromAccountID, request.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validAccount(ctx, request.ToAccountID, request.Currency)
+	if !valid {
+		return
+	}
+
+	// a hold is captured without anyone reviewing it, amounts needing approval go through createTransfer
+	if server.requiresApproval(request.Amount) {
+		ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(approvalRequiredCode, db.ErrApprovalRequired))
+		return
+	}
+
+	idempotency, valid := idempotencyParams(ctx, authPayload.Username, request)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.AuthorizeTransferTx(ctx, db.AuthorizeTransferTxParams{
+		FromAccountId: request.FromAccountID,
+		ToAccountId:   request.ToAccountID,
+		Amount:        request.Amount,
+		ExpiresAt:     time.Now().Add(server.config.HoldDuration),
+		Idempotency:   idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrCurrencyMismatch) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(limitExceededCode, err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	markReplayed(ctx, result.Replayed)
+	ctx.JSON(http.StatusOK, result)
+}
+
+type TransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type CaptureTransferRequest struct {
+	// Amount defaults to the whole amount held
+	Amount int64 `json:"amount" binding:"omitempty,min=1"`
+}
+
+// captureTransfer settles a hold for all or part of its amount
+func (server *Server) captureTransfer(ctx *gin.Context) {
+	var uri TransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var request CaptureTransferRequest
+	err = ctx.ShouldBindJSON(&request)
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, valid := server.transferParty(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	amount := request.Amount
+	if amount == 0 {
+		amount = transfer.AuthorizedAmount
+	}
+
+	result, err := server.store.CaptureTransferTx(ctx, db.CaptureTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     amount,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrCaptureExceedsHold) || errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrTransferNotPending) || errors.Is(err, db.ErrHoldExpired) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// voidTransfer cancels a hold and makes its amount available again
+func (server *Server) voidTransfer(ctx *gin.Context) {
+	var uri TransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, valid := server.transferParty(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.VoidTransferTx(ctx, transfer.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrTransferNotPending) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// transferParty loads a transfer the current user sends or receives,
+// either side of a hold may settle it
+func (server *Server) transferParty(ctx *gin.Context, transferID int64) (db.Transfer, bool) {
+	transfer, err := server.store.GetTransfer(ctx, transferID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return transfer, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return transfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return transfer, false
+		}
+		if account.Owner == authPayload.Username {
+			return transfer, true
+		}
+	}
+
+	err = errors.New("transfer doesn't belong to the current user")
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	return transfer, false
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// idempotencyParams reads the Idempotency-Key header of a request made by username, requests without
+// the header aren't deduplicated. It answers 400 and returns false when the key is malformed
+func idempotencyParams(ctx *gin.Context, username string, request interface{}) (*db.IdempotencyParams, bool) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	// the bound request is hashed rather than the raw body so formatting changes still count as a retry,
+	// the path tells apart requests to different resources such as /transfers/:id/reverse
+	body, err := json.Marshal(request)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	sum := sha256.Sum256([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n" + string(body)))
+
+	return &db.IdempotencyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: hex.EncodeToString(sum[:]),
+	}, true
+}
+
+// markReplayed flags a response that was replayed from an earlier request with the same idempotency key
+func markReplayed(ctx *gin.Context, replayed bool) {
+	if replayed {
+		ctx.Header(idempotentReplayedHeader, "true")
+	}
+}
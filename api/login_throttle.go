@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// failed logins are tracked per username and per client ip
+const (
+	throttleKindUsername = "username"
+	throttleKindIP       = "ip"
+)
+
+var (
+	errInvalidCredentials   = errors.New("invalid username or password")
+	errTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+)
+
+// dummyPasswordHash is checked against when the user doesn't exist,
+// so unknown usernames take as long to reject as wrong passwords
+var dummyPasswordHash, _ = utils.HashPassword("simplebank-dummy-password")
+
+// checkLoginLockout answers 429 and returns false when the username or the client ip is locked out
+func (server *Server) checkLoginLockout(ctx *gin.Context, username string) bool {
+	lockedUntil, err := server.store.GetLoginLockout(ctx, db.GetLoginLockoutParams{
+		Username: username,
+		ClientIp: ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	retryAfter := time.Until(lockedUntil)
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errTooManyLoginAttempts))
+		return false
+	}
+	return true
+}
+
+// rejectLogin counts a failed attempt against the username and the client ip,
+// then answers with the same error whatever went wrong
+func (server *Server) rejectLogin(ctx *gin.Context, username string, reason error) {
+	err := server.recordFailedLogin(ctx, throttleKindUsername, username, server.config.LoginMaxAttempts)
+	if err == nil {
+		err = server.recordFailedLogin(ctx, throttleKindIP, ctx.ClientIP(), server.config.LoginMaxAttemptsPerIP)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusUnauthorized, errorResponse(reason))
+}
+
+// recordFailedLogin locks subject out once it fails maxAttempts times within the attempt window,
+// a maxAttempts of zero never locks
+func (server *Server) recordFailedLogin(ctx *gin.Context, kind string, subject string, maxAttempts int) error {
+	throttle, err := server.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		Kind:        kind,
+		Subject:     subject,
+		ResetBefore: time.Now().Add(-server.config.LoginAttemptWindow),
+	})
+	if err != nil {
+		return err
+	}
+
+	if maxAttempts <= 0 || int(throttle.FailedAttempts) < maxAttempts {
+		return nil
+	}
+
+	lockout := lockoutDuration(int(throttle.FailedAttempts)-maxAttempts, server.config.LoginLockoutDuration, server.config.LoginMaxLockoutDuration)
+	return server.store.LockLogin(ctx, db.LockLoginParams{
+		Kind:        kind,
+		Subject:     subject,
+		LockedUntil: time.Now().Add(lockout),
+	})
+}
+
+// clearFailedLogins forgets the failed attempts of username after a successful login,
+// the client ip keeps its count so one good login can't hide a spray of bad ones
+func (server *Server) clearFailedLogins(ctx *gin.Context, username string) error {
+	return server.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Kind:    throttleKindUsername,
+		Subject: username,
+	})
+}
+
+// lockoutDuration doubles base for every failure past the threshold, up to max
+func lockoutDuration(failuresPastThreshold int, base time.Duration, max time.Duration) time.Duration {
+	lockout := base
+	for i := 0; i < failuresPastThreshold && lockout < max; i++ {
+		lockout *= 2
+	}
+	if lockout > max {
+		return max
+	}
+	return lockout
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	base := time.Minute
+	max := time.Hour
+
+	require.Equal(t, time.Minute, lockoutDuration(0, base, max))
+	require.Equal(t, 2*time.Minute, lockoutDuration(1, base, max))
+	require.Equal(t, 32*time.Minute, lockoutDuration(5, base, max))
+	require.Equal(t, time.Hour, lockoutDuration(6, base, max))
+	require.Equal(t, time.Hour, lockoutDuration(1000, base, max))
+}
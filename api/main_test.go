@@ -1,11 +1,20 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	mockdb "github.com/brkss/simplebank/db/mock"
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/fx"
+	"github.com/brkss/simplebank/mail"
+	"github.com/brkss/simplebank/token"
 	"github.com/brkss/simplebank/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -13,15 +22,64 @@ import (
 
 func NewTestServer(t *testing.T, store db.Store) *Server {
 	config := utils.Config{
-		TokenSymetricKey: utils.RandomString(32),
-		TokenDuration:    time.Minute,
+		TokenSymetricKey:     utils.RandomString(32),
+		TokenDuration:        time.Minute,
+		RefreshTokenDuration: time.Hour,
+
+		PasswordResetURL:           "http://localhost:3000/reset-password",
+		PasswordResetTokenDuration: time.Minute,
+		VerifyEmailURL:             "http://localhost:8080/verify_email",
+
+		LoginMaxAttempts:        3,
+		LoginMaxAttemptsPerIP:   10,
+		LoginAttemptWindow:      time.Minute,
+		LoginLockoutDuration:    time.Minute,
+		LoginMaxLockoutDuration: time.Hour,
+
+		OAuthCodeDuration: time.Minute,
+
+		FXQuoteDuration: 30 * time.Second,
+		HoldDuration:    time.Hour,
+
+		TransferApprovalThreshold: 1000,
 	}
-	server, err := NewServer(config, store)
+	rates, err := fx.NewStaticRateProvider("USD", map[string]string{
+		"EUR": "0.8",
+		"CAD": "1.25",
+	})
+	require.NoError(t, err)
+
+	server, err := NewServer(config, store, token.NewMemoryRevocationStore(), mail.NewMemoryMailer(), rates)
 	require.NoError(t, err)
 
 	return server
 }
 
+// serveAuthorizedRequest sends body as JSON to the test server with an access token of username
+func serveAuthorizedRequest(t *testing.T, store *mockdb.MockStore, method string, url string, body gin.H, username string) *httptest.ResponseRecorder {
+	return serveRequestAs(t, store, method, url, body, username, utils.DepositorRole)
+}
+
+// serveRequestAs is serveAuthorizedRequest for a user of any role
+func serveRequestAs(t *testing.T, store *mockdb.MockStore, method string, url string, body gin.H, username string, role string) *httptest.ResponseRecorder {
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
 func TestMain(m *testing.M) {
 
 	gin.SetMode(gin.TestMode)
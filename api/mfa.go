@@ -0,0 +1,268 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	totpIssuer           = "SimpleBank"
+	recoveryCodeCount    = 10
+	mfaChallengeDuration = 5 * time.Minute
+)
+
+var errInvalidMFACode = errors.New("invalid mfa code")
+
+type EnrollTOTPResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+// enrollTOTP generates a TOTP secret and recovery codes for the current user,
+// the secret only protects logins once confirmed through verifyTOTP
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hashedCodes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashedCodes[i], err = utils.HashPassword(code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	_, err = server.store.EnrollTOTPTx(ctx, db.EnrollTOTPTxParams{
+		Username:            authPayload.Username,
+		Secret:              secret,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := fmt.Errorf("totp is already enabled")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EnrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, authPayload.Username, secret),
+		RecoveryCodes:   recoveryCodes,
+	})
+}
+
+type VerifyTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type VerifyTOTPResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// verifyTOTP confirms the enrollment with a code from the authenticator app,
+// logins require a second factor from then on
+func (server *Server) verifyTOTP(ctx *gin.Context) {
+	var req VerifyTOTPRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	totp, err := server.store.GetTOTPSecret(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ok, err := server.useTOTPCode(ctx, totp, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusForbidden, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	totp, err = server.store.ConfirmTOTPSecret(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, VerifyTOTPResponse{Enabled: totp.Confirmed})
+}
+
+type LoginMFAChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// loginMFA completes a two-step login, it exchanges the challenge token returned
+// by LoginUser plus a TOTP or recovery code for the real access and refresh tokens
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req LoginMFARequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challenge, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if !challenge.HasScope(utils.MFAChallengeScope) {
+		err := fmt.Errorf("token is not an mfa challenge")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	revoked, err := server.revocations.IsRevoked(ctx, challenge)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return
+	}
+
+	if !server.checkLoginLockout(ctx, challenge.Username) {
+		return
+	}
+
+	totp, err := server.store.GetTOTPSecret(ctx, challenge.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ok, err := server.useTOTPCode(ctx, totp, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok && len(req.Code) != utils.TOTPDigits {
+		ok, err = server.useRecoveryCode(ctx, challenge.Username, req.Code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	if !ok {
+		server.rejectLogin(ctx, challenge.Username, errInvalidMFACode)
+		return
+	}
+
+	// a challenge can only be exchanged once
+	err = server.revocations.Revoke(ctx, challenge)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.clearFailedLogins(ctx, challenge.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var scopes []string
+	for _, scope := range challenge.Scopes {
+		if scope != utils.MFAChallengeScope {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	response, err := server.startSession(ctx, challenge.Username, challenge.Role, scopes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// useTOTPCode checks code against the secret of totp and burns its time step,
+// a code is refused once it or a later one was accepted so it can't be replayed
+func (server *Server) useTOTPCode(ctx *gin.Context, totp db.TotpSecret, code string) (bool, error) {
+	step, ok := utils.VerifyTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	rows, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Step:     step,
+		Username: totp.Username,
+	})
+	if err != nil {
+		return false, err
+	}
+	// zero rows means the step, or a later one, was used already
+	return rows == 1, nil
+}
+
+// useRecoveryCode burns the unused recovery code of username matching code, if any
+func (server *Server) useRecoveryCode(ctx *gin.Context, username string, code string) (bool, error) {
+	codes, err := server.store.ListUnusedRecoveryCodes(ctx, username)
+	if err != nil {
+		return false, err
+	}
+
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, recoveryCode := range codes {
+		if utils.VerifyPassword(code, recoveryCode.HashedCode) != nil {
+			continue
+		}
+		rows, err := server.store.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			return false, err
+		}
+		// zero rows means a concurrent login burnt it first
+		return rows == 1, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,531 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, hashedCodes *[]string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, hashedCodes []string)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, hashedCodes *[]string) {
+				store.EXPECT().
+					EnrollTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnrollTOTPTxParams) (db.EnrollTOTPTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.Secret)
+						*hashedCodes = arg.HashedRecoveryCodes
+						return db.EnrollTOTPTxResult{TotpSecret: db.TotpSecret{Username: arg.Username, Secret: arg.Secret}}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, hashedCodes []string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response EnrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.NotEmpty(t, response.Secret)
+				require.Contains(t, response.ProvisioningURI, "otpauth://totp/")
+				require.Contains(t, response.ProvisioningURI, response.Secret)
+
+				// only the hashes of the recovery codes reach the store
+				require.Len(t, response.RecoveryCodes, recoveryCodeCount)
+				require.Len(t, hashedCodes, recoveryCodeCount)
+				for i, code := range response.RecoveryCodes {
+					require.NotEqual(t, code, hashedCodes[i])
+					require.NoError(t, utils.VerifyPassword(code, hashedCodes[i]))
+				}
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, hashedCodes *[]string) {
+				store.EXPECT().
+					EnrollTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EnrollTOTPTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, hashedCodes []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore, hashedCodes *[]string) {
+				store.EXPECT().
+					EnrollTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, hashedCodes []string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, hashedCodes *[]string) {
+				store.EXPECT().
+					EnrollTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EnrollTOTPTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, hashedCodes []string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var hashedCodes []string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &hashedCodes)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/mfa/totp", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, hashedCodes)
+		})
+	}
+}
+
+func TestVerifyTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	code, err := utils.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Secret: secret}, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseTOTPStepParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotZero(t, arg.Step)
+						return 1, nil
+					})
+				store.EXPECT().
+					ConfirmTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Secret: secret, Confirmed: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response VerifyTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.True(t, response.Enabled)
+			},
+		},
+		{
+			name: "WrongCode",
+			body: gin.H{"code": wrongTOTPCode(code)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Secret: secret}, nil)
+				store.EXPECT().
+					ConfirmTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Secret: secret}, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					ConfirmTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					ConfirmTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: gin.H{"code": "12ab"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/mfa/totp/verify", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(t, err)
+	code, err := utils.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+
+	recoveryCode := "abcde-23456"
+	hashedRecoveryCode, err := utils.HashPassword(recoveryCode)
+	require.NoError(t, err)
+
+	totp := db.TotpSecret{Username: user.Username, Secret: secret, Confirmed: true}
+	recoveryCodes := []db.RecoveryCode{
+		{ID: 1, Username: user.Username, HashedCode: hashedRecoveryCode},
+	}
+
+	challengeToken := func(t *testing.T, server *Server) string {
+		mfaToken, _, err := server.tokenMaker.CreateToken(user.Username, utils.DepositorRole, time.Minute,
+			token.WithScopes(utils.MFAChallengeScope, utils.AccountsReadScope))
+		require.NoError(t, err)
+		return mfaToken
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, server *Server) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "TOTPCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{"mfa_token": challengeToken(t, server), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					ListUnusedRecoveryCodes(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{Kind: throttleKindUsername, Subject: user.Username})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response LoginResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+
+				// the challenge scope is dropped, the requested ones are kept
+				payload, err := server.tokenMaker.VerifyToken(response.Accesstoken)
+				require.NoError(t, err)
+				require.Equal(t, []string{utils.AccountsReadScope}, payload.Scopes)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{"mfa_token": challengeToken(t, server), "code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					ListUnusedRecoveryCodes(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(recoveryCodes, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(int64(1))).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{Kind: throttleKindUsername, Subject: user.Username})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedTOTPCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{"mfa_token": challengeToken(t, server), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				expectFailedLogin(store, user.Username, 1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidMFACode.Error())
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{"mfa_token": challengeToken(t, server), "code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					ListUnusedRecoveryCodes(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return([]db.RecoveryCode{}, nil)
+				expectFailedLogin(store, user.Username, 1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidMFACode.Error())
+			},
+		},
+		{
+			name: "WrongCode",
+			body: func(t *testing.T, server *Server) gin.H {
+				return gin.H{"mfa_token": challengeToken(t, server), "code": wrongTOTPCode(code)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				expectFailedLogin(store, user.Username, 1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidMFACode.Error())
+			},
+		},
+		{
+			name: "NotAChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, utils.DepositorRole, time.Minute,
+					token.WithScopes(utils.DefaultScopes...))
+				require.NoError(t, err)
+				return gin.H{"mfa_token": accessToken, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReusedChallenge",
+			body: func(t *testing.T, server *Server) gin.H {
+				mfaToken := challengeToken(t, server)
+				payload, err := server.tokenMaker.VerifyToken(mfaToken)
+				require.NoError(t, err)
+				err = server.revocations.Revoke(context.Background(), payload)
+				require.NoError(t, err)
+				return gin.H{"mfa_token": mfaToken, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t, server))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestMFAChallengeTokenRejected(t *testing.T) {
+	server := NewTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/logout", nil)
+	require.NoError(t, err)
+
+	mfaToken, _, err := server.tokenMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute,
+		token.WithScopes(utils.MFAChallengeScope))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, mfaToken))
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+// wrongTOTPCode returns a six digit code different from code
+func wrongTOTPCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"strings"
 
+	db "github.com/brkss/simplebank/db/sqlc"
 	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,35 +18,91 @@ const (
 	authorizationPayloadKey = "auth_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocations token.RevocationStore, store db.Store) gin.HandlerFunc {
+	return scopedAuthMiddleware(tokenMaker, revocations, store)
+}
+
+// scopedAuthMiddleware authenticates the request like authMiddleware
+// and also requires the token to carry every one of scopes,
+// an X-API-Key header is accepted in place of a bearer token
+func scopedAuthMiddleware(tokenMaker token.Maker, revocations token.RevocationStore, store db.Store, scopes ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
-		if len(authorizationHeaderKey) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-			return
+		var payload *token.Payload
+		if apiKey := ctx.GetHeader(apiKeyHeaderKey); apiKey != "" {
+			payload = authenticateAPIKey(ctx, store, apiKey)
+		} else {
+			payload = authenticateBearerToken(ctx, tokenMaker, revocations)
 		}
-
-		fields := strings.Split(authorizationHeader, " ")
-		if len(fields) < 2 {
-			err := errors.New("invalid authorization header")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if payload == nil {
 			return
 		}
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("invalid authorization type token needs to be %s type", authorizationHeaderKey)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-		}
-
-		accessToken := fields[1]
 
-		payload, err := tokenMaker.VerifyToken(accessToken)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		for _, scope := range scopes {
+			if !payload.HasScope(scope) {
+				err := fmt.Errorf("token is missing the %s scope", scope)
+				ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
 		}
+
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 
 }
+
+// authenticateBearerToken verifies the access token of the authorization header,
+// it aborts the request and returns nil when the token isn't acceptable
+func authenticateBearerToken(ctx *gin.Context, tokenMaker token.Maker, revocations token.RevocationStore) *token.Payload {
+	authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+	if len(authorizationHeader) == 0 {
+		err := errors.New("authorization header is not provided")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	fields := strings.Split(authorizationHeader, " ")
+	if len(fields) < 2 {
+		err := errors.New("invalid authorization header")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		err := fmt.Errorf("invalid authorization type token needs to be %s type", authorizationTypeBearer)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	accessToken := fields[1]
+
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	if payload.TokenType != token.AccessTokenType {
+		err := errors.New("only access tokens can authorize requests")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	if payload.HasScope(utils.MFAChallengeScope) {
+		err := errors.New("mfa challenge tokens can only be exchanged at /login/mfa")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	revoked, err := revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil
+	}
+	if revoked {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return nil
+	}
+
+	return payload
+}
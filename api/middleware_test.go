@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,20 +14,34 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	accessToken, payload, err := tokenMaker.CreateToken(username, role, duration, token.WithScopes(utils.DefaultScopes...))
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	authorization := fmt.Sprintf("%s %s", authorizationType, accessToken)
+	request.Header.Set(authorizationHeaderKey, authorization)
+}
+
 func TestAuthMiddleware(t *testing.T) {
 
 	testCases := []struct {
 		name          string
-		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				token, err := tokenMaker.CreateToken(utils.RandomOwner(), time.Minute)
-				require.NoError(t, err)
-				authorization := fmt.Sprintf("%s %s", authorizationTypeBearer, token)
-				request.Header.Set(authorizationHeaderKey, authorization)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
@@ -34,9 +49,21 @@ func TestAuthMiddleware(t *testing.T) {
 		},
 		{
 			name: "ExpiredToken",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				token, err := tokenMaker.CreateToken(utils.RandomOwner(), -time.Minute)
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusUnauthorized)
+			},
+		},
+		{
+			name: "RevokedToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
+				token, payload, err := tokenMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
 				require.NoError(t, err)
+				err = revocations.Revoke(context.Background(), payload)
+				require.NoError(t, err)
+
 				authorization := fmt.Sprintf("%s %s", authorizationTypeBearer, token)
 				request.Header.Set(authorizationHeaderKey, authorization)
 			},
@@ -44,9 +71,28 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, recorder.Code, http.StatusUnauthorized)
 			},
 		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
+				refreshToken, _, err := tokenMaker.CreateToken(
+					utils.RandomOwner(),
+					utils.DepositorRole,
+					time.Hour,
+					token.WithScopes(utils.DefaultScopes...),
+					token.WithTokenType(token.RefreshTokenType),
+				)
+				require.NoError(t, err)
+
+				authorization := fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken)
+				request.Header.Set(authorizationHeaderKey, authorization)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Code, http.StatusUnauthorized)
+			},
+		},
 		{
 			name: "UnsetToken",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
 
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -55,7 +101,7 @@ func TestAuthMiddleware(t *testing.T) {
 		},
 		{
 			name: "InvalidToken",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, revocations token.RevocationStore) {
 				authorization := fmt.Sprintf("%s %s", authorizationTypeBearer, "sdaskdasdsalkd")
 				request.Header.Set(authorizationHeaderKey, authorization)
 			},
@@ -68,7 +114,7 @@ func TestAuthMiddleware(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			server := NewTestServer(t, nil)
-			server.router.GET("/auth", authMiddleware(server.tokenMaker), func(ctx *gin.Context) {
+			server.router.GET("/auth", authMiddleware(server.tokenMaker, server.revocations, server.store), func(ctx *gin.Context) {
 				ctx.JSON(http.StatusOK, gin.H{})
 			})
 
@@ -76,10 +122,61 @@ func TestAuthMiddleware(t *testing.T) {
 			recorder := httptest.NewRecorder()
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
-			tc.setupAuth(t, request, server.tokenMaker)
+			tc.setupAuth(t, request, server.tokenMaker, server.revocations)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
 	}
 
 }
+
+func TestScopedAuthMiddleware(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		scopes        []string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			scopes: []string{utils.AccountsReadScope, utils.TransfersWriteScope},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			scopes: []string{utils.AccountsReadScope},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "NoScopes",
+			scopes: nil,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewTestServer(t, nil)
+			server.router.GET("/auth", scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.AccountsReadScope, utils.TransfersWriteScope), func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/auth", nil)
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute, token.WithScopes(tc.scopes...))
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
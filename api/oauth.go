@@ -0,0 +1,482 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	oauthClientIDTag       = "sbc_"
+	oauthClientIDBytes     = 12
+	oauthClientSecretBytes = 32
+	oauthCodeBytes         = 32
+
+	oauthGrantAuthorizationCode = "authorization_code"
+	oauthGrantClientCredentials = "client_credentials"
+)
+
+// error codes of RFC 6749 returned by the authorization and token endpoints
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidGrant         = "invalid_grant"
+	oauthErrInvalidScope         = "invalid_scope"
+	oauthErrUnauthorizedClient   = "unauthorized_client"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrAccessDenied         = "access_denied"
+	oauthErrServerError          = "server_error"
+)
+
+var (
+	errInvalidOAuthClient = errors.New("unknown client or bad client credentials")
+	errInvalidOAuthGrant  = errors.New("authorization code is invalid, expired or was issued to another client")
+)
+
+// oauthErrorResponse is the error body RFC 6749 mandates for the token endpoint
+func oauthErrorResponse(code string, err error) gin.H {
+	return gin.H{"error": code, "error_description": err.Error()}
+}
+
+// oauthScopes resolves the space separated scope parameter of a request against
+// the scopes allowed, an empty parameter asks for all of them
+func oauthScopes(scope string, allowed []string) ([]string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return append([]string{}, allowed...), nil
+	}
+
+	for _, s := range requested {
+		if !containsString(allowed, s) {
+			return nil, fmt.Errorf("scope %s is not allowed", s)
+		}
+	}
+	return requested, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirect_uris" binding:"dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:read transfers:write"`
+	// Confidential clients can keep a secret, they get one and may use the client credentials grant
+	Confidential bool `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// createOAuthClient registers a third party app owned by the current user,
+// the client secret is only ever shown in this response
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req CreateOAuthClientRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Confidential && len(req.RedirectURIs) == 0 {
+		err := errors.New("public clients need at least one redirect uri")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, scope := range req.Scopes {
+		if !authPayload.HasScope(scope) {
+			err := fmt.Errorf("cannot grant the %s scope the current token doesn't have", scope)
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+	}
+
+	clientID, err := utils.GenerateSecureToken(oauthClientIDBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var secret, hashedSecret string
+	if req.Confidential {
+		secret, err = utils.GenerateSecureToken(oauthClientSecretBytes)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		hashedSecret = utils.HashSecureToken(secret)
+	}
+
+	client, err := server.store.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		ID:             oauthClientIDTag + clientID,
+		Owner:          authPayload.Username,
+		Name:           req.Name,
+		HashedSecret:   hashedSecret,
+		IsConfidential: req.Confidential,
+		RedirectUris:   append([]string{}, req.RedirectURIs...),
+		Scopes:         req.Scopes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, OAuthClientResponse{
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.IsConfidential,
+		CreatedAt:    client.CreatedAt,
+	})
+}
+
+// OAuthAuthorizeRequest carries the parameters of an authorization request,
+// PKCE with the S256 method is required from every client
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required,eq=code"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required,min=43,max=128"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type OAuthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	State       string   `json:"state,omitempty"`
+}
+
+// checkAuthorizeRequest resolves the client and the scopes of an authorization request,
+// it answers 400 and returns false when the request can't be honoured
+func (server *Server) checkAuthorizeRequest(ctx *gin.Context, req OAuthAuthorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := server.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidClient, errInvalidOAuthClient))
+			return db.OauthClient{}, nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return db.OauthClient{}, nil, false
+	}
+
+	// never redirect to an uri the client didn't register, the code would leak
+	if !containsString(client.RedirectUris, req.RedirectURI) {
+		err := errors.New("redirect_uri is not registered for this client")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return db.OauthClient{}, nil, false
+	}
+
+	scopes, err := oauthScopes(req.Scope, client.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidScope, err))
+		return db.OauthClient{}, nil, false
+	}
+
+	// users can only delegate what they hold themselves
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, scope := range scopes {
+		if !authPayload.HasScope(scope) {
+			err := fmt.Errorf("cannot grant the %s scope the current token doesn't have", scope)
+			ctx.JSON(http.StatusForbidden, oauthErrorResponse(oauthErrInvalidScope, err))
+			return db.OauthClient{}, nil, false
+		}
+	}
+
+	return client, scopes, true
+}
+
+// getOAuthConsent validates an authorization request and describes what the
+// client asks for, so the user can be shown a consent screen
+func (server *Server) getOAuthConsent(ctx *gin.Context) {
+	var req OAuthAuthorizeRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, OAuthConsentResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: req.RedirectURI,
+		Scopes:      scopes,
+		State:       req.State,
+	})
+}
+
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthRedirectResponse tells the user agent where to send the user back to the client
+type OAuthRedirectResponse struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// oauthRedirect appends params to the query of the client redirect uri
+func oauthRedirect(redirectURI string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// submitOAuthConsent records the user's answer to the consent screen and hands back
+// the redirect carrying either an authorization code or an access_denied error
+func (server *Server) submitOAuthConsent(ctx *gin.Context) {
+	var req OAuthConsentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req.OAuthAuthorizeRequest)
+	if !ok {
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", oauthErrAccessDenied)
+		redirectURI, err := oauthRedirect(req.RedirectURI, params)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+			return
+		}
+		ctx.JSON(http.StatusOK, OAuthRedirectResponse{RedirectURI: redirectURI})
+		return
+	}
+
+	code, err := utils.GenerateSecureToken(oauthCodeBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = server.store.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:            utils.HashSecureToken(code),
+		ClientID:            client.ID,
+		Username:            authPayload.Username,
+		RedirectUri:         req.RedirectURI,
+		Scopes:              scopes,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(server.config.OAuthCodeDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return
+	}
+
+	params.Set("code", code)
+	redirectURI, err := oauthRedirect(req.RedirectURI, params)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return
+	}
+	ctx.JSON(http.StatusOK, OAuthRedirectResponse{RedirectURI: redirectURI})
+}
+
+// OAuthTokenRequest is the form posted to the token endpoint, clients authenticate
+// with HTTP basic auth or with the client_id and client_secret fields
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// issueOAuthToken exchanges a grant for an access token
+func (server *Server) issueOAuthToken(ctx *gin.Context) {
+	var req OAuthTokenRequest
+	err := ctx.ShouldBindWith(&req, binding.Form)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return
+	}
+
+	if req.GrantType != oauthGrantAuthorizationCode && req.GrantType != oauthGrantClientCredentials {
+		err := fmt.Errorf("grant type %s is not supported", req.GrantType)
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrUnsupportedGrantType, err))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req)
+	if !ok {
+		return
+	}
+
+	var username string
+	var scopes []string
+	switch req.GrantType {
+	case oauthGrantAuthorizationCode:
+		username, scopes, ok = server.redeemAuthorizationCode(ctx, client, req)
+	case oauthGrantClientCredentials:
+		username, scopes, ok = server.grantClientCredentials(ctx, client, req)
+	}
+	if !ok {
+		return
+	}
+
+	// delegated tokens act with the rights of a depositor whatever the role of the user,
+	// third party apps never get back-office access
+	accessToken, _, err := server.tokenMaker.CreateToken(
+		username,
+		utils.DepositorRole,
+		server.config.TokenDuration,
+		token.WithScopes(scopes...),
+		token.WithClientID(client.ID),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(server.config.TokenDuration.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// authenticateOAuthClient identifies the client calling the token endpoint,
+// confidential clients must prove they hold their secret
+func (server *Server) authenticateOAuthClient(ctx *gin.Context, req OAuthTokenRequest) (db.OauthClient, bool) {
+	clientID, clientSecret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = req.ClientID, req.ClientSecret
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrInvalidClient, errInvalidOAuthClient))
+			return db.OauthClient{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return db.OauthClient{}, false
+	}
+
+	if client.IsConfidential {
+		hashedSecret := utils.HashSecureToken(clientSecret)
+		if subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(client.HashedSecret)) != 1 {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrInvalidClient, errInvalidOAuthClient))
+			return db.OauthClient{}, false
+		}
+	}
+
+	return client, true
+}
+
+// redeemAuthorizationCode burns the code whatever happens next, then checks it was
+// issued to client for the same redirect uri and that the PKCE verifier matches
+func (server *Server) redeemAuthorizationCode(ctx *gin.Context, client db.OauthClient, req OAuthTokenRequest) (string, []string, bool) {
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		err := errors.New("code, redirect_uri and code_verifier are required")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidRequest, err))
+		return "", nil, false
+	}
+
+	code, err := server.store.UseOAuthAuthorizationCode(ctx, utils.HashSecureToken(req.Code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidGrant, errInvalidOAuthGrant))
+			return "", nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrServerError, err))
+		return "", nil, false
+	}
+
+	if code.ClientID != client.ID ||
+		code.RedirectUri != req.RedirectURI ||
+		time.Now().After(code.ExpiresAt) ||
+		code.CodeChallengeMethod != utils.PKCEMethodS256 ||
+		!utils.VerifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidGrant, errInvalidOAuthGrant))
+		return "", nil, false
+	}
+
+	return code.Username, code.Scopes, true
+}
+
+// grantClientCredentials lets a confidential client act on behalf of the user who registered it
+func (server *Server) grantClientCredentials(ctx *gin.Context, client db.OauthClient, req OAuthTokenRequest) (string, []string, bool) {
+	if !client.IsConfidential {
+		err := errors.New("public clients cannot use the client credentials grant")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrUnauthorizedClient, err))
+		return "", nil, false
+	}
+
+	scopes, err := oauthScopes(req.Scope, client.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrInvalidScope, err))
+		return "", nil, false
+	}
+
+	return client.Owner, scopes, true
+}
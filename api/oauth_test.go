@@ -0,0 +1,814 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://fintech.example.com/callback"
+
+// randomOAuthClient returns a registered client of owner along with its clear secret,
+// public clients get no secret
+func randomOAuthClient(t *testing.T, owner db.User, confidential bool) (db.OauthClient, string) {
+	client := db.OauthClient{
+		ID:             oauthClientIDTag + utils.RandomString(16),
+		Owner:          owner.Username,
+		Name:           utils.RandomOwner(),
+		IsConfidential: confidential,
+		RedirectUris:   []string{testRedirectURI},
+		Scopes:         []string{utils.AccountsReadScope, utils.TransfersReadScope},
+		CreatedAt:      time.Now(),
+	}
+
+	var secret string
+	if confidential {
+		secret = utils.RandomString(43)
+		client.HashedSecret = utils.HashSecureToken(secret)
+	}
+	return client, secret
+}
+
+func TestCreateOAuthClientAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Confidential",
+			body: gin.H{
+				"name":          "budgeting app",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{utils.AccountsReadScope},
+				"confidential":  true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.True(t, strings.HasPrefix(arg.ID, oauthClientIDTag))
+						require.NotEmpty(t, arg.HashedSecret)
+						return db.OauthClient{
+							ID:             arg.ID,
+							Owner:          arg.Owner,
+							Name:           arg.Name,
+							HashedSecret:   arg.HashedSecret,
+							IsConfidential: arg.IsConfidential,
+							RedirectUris:   arg.RedirectUris,
+							Scopes:         arg.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var response OAuthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.NotEmpty(t, response.ClientID)
+				require.NotEmpty(t, response.ClientSecret)
+				require.True(t, response.Confidential)
+				require.NotContains(t, recorder.Body.String(), utils.HashSecureToken(response.ClientSecret))
+			},
+		},
+		{
+			name: "Public",
+			body: gin.H{
+				"name":          "mobile app",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{utils.AccountsReadScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.Empty(t, arg.HashedSecret)
+						return db.OauthClient{ID: arg.ID, RedirectUris: arg.RedirectUris, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "client_secret")
+			},
+		},
+		{
+			name: "PublicWithoutRedirectURI",
+			body: gin.H{
+				"name":   "mobile app",
+				"scopes": []string{utils.AccountsReadScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRedirectURI",
+			body: gin.H{
+				"name":          "mobile app",
+				"redirect_uris": []string{"not a url"},
+				"scopes":        []string{utils.AccountsReadScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/clients", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, user, false)
+	challenge := utils.PKCEChallenge(utils.RandomString(43))
+
+	authorizeQuery := func(modify func(query url.Values)) url.Values {
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {client.ID},
+			"redirect_uri":          {testRedirectURI},
+			"scope":                 {utils.AccountsReadScope},
+			"state":                 {"xyz"},
+			"code_challenge":        {challenge},
+			"code_challenge_method": {utils.PKCEMethodS256},
+		}
+		if modify != nil {
+			modify(query)
+		}
+		return query
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: authorizeQuery(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthConsentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, client.Name, response.ClientName)
+				require.Equal(t, []string{utils.AccountsReadScope}, response.Scopes)
+				require.Equal(t, "xyz", response.State)
+			},
+		},
+		{
+			name:  "DefaultScopes",
+			query: authorizeQuery(func(query url.Values) { query.Del("scope") }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthConsentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, client.Scopes, response.Scopes)
+			},
+		},
+		{
+			name:  "ScopeNotAllowedForClient",
+			query: authorizeQuery(func(query url.Values) { query.Set("scope", utils.TransfersWriteScope) }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrInvalidScope)
+			},
+		},
+		{
+			name:  "UnregisteredRedirectURI",
+			query: authorizeQuery(func(query url.Values) { query.Set("redirect_uri", "https://attacker.example.com/") }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "UnknownClient",
+			query: authorizeQuery(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrInvalidClient)
+			},
+		},
+		{
+			name:  "MissingPKCE",
+			query: authorizeQuery(func(query url.Values) { query.Del("code_challenge") }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "PlainPKCE",
+			query: authorizeQuery(func(query url.Values) { query.Set("code_challenge_method", "plain") }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSubmitOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, user, false)
+	challenge := utils.PKCEChallenge(utils.RandomString(43))
+
+	consent := func(approve bool) gin.H {
+		return gin.H{
+			"response_type":         "code",
+			"client_id":             client.ID,
+			"redirect_uri":          testRedirectURI,
+			"scope":                 utils.AccountsReadScope,
+			"state":                 "xyz",
+			"code_challenge":        challenge,
+			"code_challenge_method": utils.PKCEMethodS256,
+			"approve":               approve,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Approved",
+			body: consent(true),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+						require.Equal(t, client.ID, arg.ClientID)
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, testRedirectURI, arg.RedirectUri)
+						require.Equal(t, []string{utils.AccountsReadScope}, arg.Scopes)
+						require.Equal(t, challenge, arg.CodeChallenge)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return db.OauthAuthorizationCode{CodeHash: arg.CodeHash}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthRedirectResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(response.RedirectURI)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(response.RedirectURI, testRedirectURI))
+				require.NotEmpty(t, redirect.Query().Get("code"))
+				require.Equal(t, "xyz", redirect.Query().Get("state"))
+			},
+		},
+		{
+			name: "Denied",
+			body: consent(false),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthRedirectResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(response.RedirectURI)
+				require.NoError(t, err)
+				require.Equal(t, oauthErrAccessDenied, redirect.Query().Get("error"))
+				require.Empty(t, redirect.Query().Get("code"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIssueOAuthTokenAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	user, _ := randomUser(t)
+	publicClient, _ := randomOAuthClient(t, owner, false)
+	confidentialClient, secret := randomOAuthClient(t, owner, true)
+
+	verifier := utils.RandomString(64)
+	code := utils.RandomString(43)
+	authorizationCode := db.OauthAuthorizationCode{
+		CodeHash:            utils.HashSecureToken(code),
+		ClientID:            publicClient.ID,
+		Username:            user.Username,
+		RedirectUri:         testRedirectURI,
+		Scopes:              []string{utils.AccountsReadScope},
+		CodeChallenge:       utils.PKCEChallenge(verifier),
+		CodeChallengeMethod: utils.PKCEMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+
+	codeForm := func(modify func(form url.Values)) url.Values {
+		form := url.Values{
+			"grant_type":    {oauthGrantAuthorizationCode},
+			"client_id":     {publicClient.ID},
+			"code":          {code},
+			"redirect_uri":  {testRedirectURI},
+			"code_verifier": {verifier},
+		}
+		if modify != nil {
+			modify(form)
+		}
+		return form
+	}
+
+	expectInvalidGrant := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Contains(t, recorder.Body.String(), oauthErrInvalidGrant)
+	}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     []string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AuthorizationCode",
+			form: codeForm(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(publicClient.ID)).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Eq(authorizationCode.CodeHash)).
+					Times(1).
+					Return(authorizationCode, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var response OAuthTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, "Bearer", response.TokenType)
+				require.Equal(t, int64(60), response.ExpiresIn)
+				require.Equal(t, utils.AccountsReadScope, response.Scope)
+
+				// the token acts for the user who consented, as a plain depositor
+				payload, err := server.tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, utils.DepositorRole, payload.Role)
+				require.Equal(t, []string{utils.AccountsReadScope}, payload.Scopes)
+				require.Equal(t, publicClient.ID, payload.ClientID)
+			},
+		},
+		{
+			name: "WrongCodeVerifier",
+			form: codeForm(func(form url.Values) { form.Set("code_verifier", utils.RandomString(64)) }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(authorizationCode, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				expectInvalidGrant(t, recorder)
+			},
+		},
+		{
+			name: "WrongRedirectURI",
+			form: codeForm(func(form url.Values) { form.Set("redirect_uri", "https://fintech.example.com/other") }),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(authorizationCode, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				expectInvalidGrant(t, recorder)
+			},
+		},
+		{
+			name:      "CodeOfAnotherClient",
+			form:      codeForm(func(form url.Values) { form.Del("client_id") }),
+			basicAuth: []string{confidentialClient.ID, secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(confidentialClient.ID)).
+					Times(1).
+					Return(confidentialClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(authorizationCode, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				expectInvalidGrant(t, recorder)
+			},
+		},
+		{
+			name: "ExpiredCode",
+			form: codeForm(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := authorizationCode
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expired, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				expectInvalidGrant(t, recorder)
+			},
+		},
+		{
+			name: "UsedCode",
+			form: codeForm(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				expectInvalidGrant(t, recorder)
+			},
+		},
+		{
+			name:      "ClientCredentials",
+			form:      url.Values{"grant_type": {oauthGrantClientCredentials}, "scope": {utils.TransfersReadScope}},
+			basicAuth: []string{confidentialClient.ID, secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(confidentialClient.ID)).
+					Times(1).
+					Return(confidentialClient, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+
+				// the client acts for the user who registered it
+				payload, err := server.tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, owner.Username, payload.Username)
+				require.Equal(t, []string{utils.TransfersReadScope}, payload.Scopes)
+			},
+		},
+		{
+			name: "ClientCredentialsInForm",
+			form: url.Values{
+				"grant_type":    {oauthGrantClientCredentials},
+				"client_id":     {confidentialClient.ID},
+				"client_secret": {secret},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(confidentialClient.ID)).
+					Times(1).
+					Return(confidentialClient, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response OAuthTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, strings.Join(confidentialClient.Scopes, " "), response.Scope)
+			},
+		},
+		{
+			name:      "BadClientSecret",
+			form:      url.Values{"grant_type": {oauthGrantClientCredentials}},
+			basicAuth: []string{confidentialClient.ID, utils.RandomString(43)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(confidentialClient.ID)).
+					Times(1).
+					Return(confidentialClient, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrInvalidClient)
+			},
+		},
+		{
+			name: "ClientCredentialsForPublicClient",
+			form: url.Values{"grant_type": {oauthGrantClientCredentials}, "client_id": {publicClient.ID}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(publicClient.ID)).
+					Times(1).
+					Return(publicClient, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrUnauthorizedClient)
+			},
+		},
+		{
+			name:      "ScopeNotAllowedForClient",
+			form:      url.Values{"grant_type": {oauthGrantClientCredentials}, "scope": {utils.TransfersWriteScope}},
+			basicAuth: []string{confidentialClient.ID, secret},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(confidentialClient, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrInvalidScope)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: url.Values{"grant_type": {"password"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthErrUnsupportedGrantType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth != nil {
+				request.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestOAuthTokenAccessesScopedRoutes(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount()
+	account.ID = utils.RandomInt(1, 10000)
+	account.Owner = user.Username
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := NewTestServer(t, store)
+
+	// a token limited to accounts:read can read accounts but not move money
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, utils.DepositorRole, time.Minute, token.WithScopes(utils.AccountsReadScope))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/account/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+accessToken)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+accessToken)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestOAuthTokenDeniedUserSessionRoutes(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+	}{
+		{name: "EnrollTOTP", method: http.MethodPost, url: "/users/mfa/totp"},
+		{name: "VerifyTOTP", method: http.MethodPost, url: "/users/mfa/totp/verify"},
+		{name: "CreateAPIKey", method: http.MethodPost, url: "/api_keys"},
+		{name: "CreateOAuthClient", method: http.MethodPost, url: "/oauth/clients"},
+		{name: "RevokeAllSessions", method: http.MethodPost, url: "/sessions/revoke_all"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the store is never reached
+			store := mockdb.NewMockStore(ctrl)
+			server := NewTestServer(t, store)
+
+			accessToken, _, err := server.tokenMaker.CreateToken(
+				user.Username,
+				utils.DepositorRole,
+				time.Minute,
+				token.WithScopes(utils.DefaultScopes...),
+				token.WithClientID(utils.RandomString(16)),
+			)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader([]byte("{}")))
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "Bearer "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/mail"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const resetTokenSize = 32
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a single use reset link to the owner of the address,
+// it answers the same way whether the address is known or not
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resetToken, err := utils.GenerateSecureToken(resetTokenSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: utils.HashSecureToken(resetToken),
+		ExpiresAt: time.Now().Add(server.config.PasswordResetTokenDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your SimpleBank password",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the link below to choose a new password, it expires in %s:\n\n%s?token=%s\n\nIf you didn't ask for a reset you can ignore this email.\n",
+			user.FullName, server.config.PasswordResetTokenDuration, server.config.PasswordResetURL, resetToken,
+		),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password out of a reset token, every session and
+// token issued before the change stops working
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      utils.HashSecureToken(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if err == db.ErrInvalidResetToken {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
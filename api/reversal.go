@@ -0,0 +1,87 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type ReverseTransferRequest struct {
+	// Amount defaults to whatever wasn't refunded yet
+	Amount int64 `json:"amount" binding:"omitempty,min=1"`
+}
+
+// reverseTransfer refunds all or part of a transfer to its sender,
+// only the owner of the receiving account can give the money back
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri TransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var request ReverseTransferRequest
+	err = ctx.ShouldBindJSON(&request)
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		err := errors.New("only the receiving account can reverse a transfer")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	idempotency, valid := idempotencyParams(ctx, authPayload.Username, request)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID:  transfer.ID,
+		Amount:      request.Amount,
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyReused) ||
+			errors.Is(err, db.ErrTransferNotReversible) ||
+			errors.Is(err, db.ErrTransferAlreadyReversed) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrReversalExceedsTransfer) ||
+			errors.Is(err, db.ErrInsufficientFunds) ||
+			errors.Is(err, db.ErrAmountTooSmall) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	markReplayed(ctx, result.Replayed)
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/schedule"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+var errScheduleNeverRuns = errors.New("schedule has no run between start_at and end_at")
+
+type ScheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Currency      string     `json:"currency"`
+	Schedule      string     `json:"schedule"`
+	StartAt       time.Time  `json:"start_at"`
+	EndAt         *time.Time `json:"end_at,omitempty"`
+	MaxRuns       *int64     `json:"max_runs,omitempty"`
+	RunCount      int64      `json:"run_count"`
+	Status        string     `json:"status"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	// RetryAt is set while a run short of funds waits to be tried again
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newScheduledTransferResponse(scheduledTransfer db.ScheduledTransfer) ScheduledTransferResponse {
+	resp := ScheduledTransferResponse{
+		ID:            scheduledTransfer.ID,
+		FromAccountID: scheduledTransfer.FromAccountID,
+		ToAccountID:   scheduledTransfer.ToAccountID,
+		Amount:        scheduledTransfer.Amount,
+		Currency:      scheduledTransfer.Currency,
+		Schedule:      scheduledTransfer.Schedule,
+		StartAt:       scheduledTransfer.StartAt,
+		RunCount:      scheduledTransfer.RunCount,
+		Status:        scheduledTransfer.Status,
+		CreatedAt:     scheduledTransfer.CreatedAt,
+	}
+	if scheduledTransfer.EndAt.Valid {
+		resp.EndAt = &scheduledTransfer.EndAt.Time
+	}
+	if scheduledTransfer.MaxRuns.Valid {
+		resp.MaxRuns = &scheduledTransfer.MaxRuns.Int64
+	}
+	if scheduledTransfer.NextRunAt.Valid {
+		resp.NextRunAt = &scheduledTransfer.NextRunAt.Time
+	}
+	if scheduledTransfer.RetryAt.Valid {
+		resp.RetryAt = &scheduledTransfer.RetryAt.Time
+	}
+	return resp
+}
+
+type CreateScheduledTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=1"`
+	Currency      string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	// Schedule is a cron expression evaluated in UTC, a shorthand like @monthly or "@every <duration>"
+	Schedule string `json:"schedule" binding:"required"`
+	// StartAt defaults to now
+	StartAt *time.Time `json:"start_at"`
+	EndAt   *time.Time `json:"end_at"`
+	MaxRuns int64      `json:"max_runs" binding:"omitempty,min=1"`
+}
+
+// createScheduledTransfer sets up a standing order moving money on a schedule until its end date or maximum number of runs
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req CreateScheduledTransferRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	s, err := schedule.Parse(req.Schedule)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	var endAt sql.NullTime
+	if req.EndAt != nil {
+		if !req.EndAt.After(startAt) {
+			err := errors.New("end_at must be after start_at")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		endAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+
+	nextRunAt, valid := firstRun(ctx, s, startAt, endAt)
+	if !valid {
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	var maxRuns sql.NullInt64
+	if req.MaxRuns > 0 {
+		maxRuns = sql.NullInt64{Int64: req.MaxRuns, Valid: true}
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Schedule:      req.Schedule,
+		StartAt:       startAt,
+		EndAt:         endAt,
+		MaxRuns:       maxRuns,
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newScheduledTransferResponse(scheduledTransfer))
+}
+
+// firstRun works out the first run of s from startAt, or from now for a start in the past,
+// answering 400 when there's none before endAt
+func firstRun(ctx *gin.Context, s schedule.Schedule, startAt time.Time, endAt sql.NullTime) (sql.NullTime, bool) {
+	if now := time.Now(); startAt.Before(now) {
+		startAt = now
+	}
+
+	first := s.First(startAt)
+	if first.IsZero() || (endAt.Valid && first.After(endAt.Time)) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errScheduleNeverRuns))
+		return sql.NullTime{}, false
+	}
+	return sql.NullTime{Time: first, Valid: true}, true
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req ListPageRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]ScheduledTransferResponse, 0, len(scheduledTransfers))
+	for _, scheduledTransfer := range scheduledTransfers {
+		resp = append(resp, newScheduledTransferResponse(scheduledTransfer))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type ScheduledTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri ScheduledTransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, valid := server.ownedScheduledTransfer(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+type UpdateScheduledTransferRequest struct {
+	Amount   *int64     `json:"amount" binding:"omitempty,gt=1"`
+	Schedule *string    `json:"schedule"`
+	EndAt    *time.Time `json:"end_at"`
+	MaxRuns  *int64     `json:"max_runs" binding:"omitempty,min=1"`
+}
+
+// updateScheduledTransfer changes the amount or timing of an active scheduled transfer,
+// a new schedule starts over from now and a run waiting for a retry is tried again right away
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri ScheduledTransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req UpdateScheduledTransferRequest
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, valid := server.ownedScheduledTransfer(ctx, uri.ID)
+	if !valid {
+		return
+	}
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive {
+		err := errors.New("scheduled transfer is no longer active")
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID:        scheduledTransfer.ID,
+		Amount:    scheduledTransfer.Amount,
+		Schedule:  scheduledTransfer.Schedule,
+		EndAt:     scheduledTransfer.EndAt,
+		MaxRuns:   scheduledTransfer.MaxRuns,
+		Status:    scheduledTransfer.Status,
+		NextRunAt: scheduledTransfer.NextRunAt,
+	}
+	if req.Amount != nil {
+		arg.Amount = *req.Amount
+	}
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+	if req.MaxRuns != nil {
+		if *req.MaxRuns <= scheduledTransfer.RunCount {
+			err := errors.New("max_runs must be more than the runs already made")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.MaxRuns = sql.NullInt64{Int64: *req.MaxRuns, Valid: true}
+	}
+	if req.Schedule != nil {
+		s, err := schedule.Parse(*req.Schedule)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Schedule = *req.Schedule
+		arg.NextRunAt, valid = firstRun(ctx, s, scheduledTransfer.StartAt, arg.EndAt)
+		if !valid {
+			return
+		}
+	} else if arg.EndAt.Valid && arg.NextRunAt.Time.After(arg.EndAt.Time) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errScheduleNeverRuns))
+		return
+	}
+
+	scheduledTransfer, err = server.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+// deleteScheduledTransfer cancels a scheduled transfer along with its run history,
+// transfers already made are left alone
+func (server *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	var uri ScheduledTransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	deleted, err := server.store.DeleteScheduledTransfer(ctx, db.DeleteScheduledTransferParams{
+		ID:    uri.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		err := errors.New("scheduled transfer not found")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type ScheduledTransferRunResponse struct {
+	ID           int64     `json:"id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Attempt      int64     `json:"attempt"`
+	Status       string    `json:"status"`
+	TransferID   *int64    `json:"transfer_id,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) ScheduledTransferRunResponse {
+	resp := ScheduledTransferRunResponse{
+		ID:           run.ID,
+		ScheduledFor: run.ScheduledFor,
+		Attempt:      run.Attempt,
+		Status:       run.Status,
+		Error:        run.Error,
+		CreatedAt:    run.CreatedAt,
+	}
+	if run.TransferID.Valid {
+		resp.TransferID = &run.TransferID.Int64
+	}
+	return resp
+}
+
+// listScheduledTransferRuns returns the outcome of each run of a scheduled transfer, latest first
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri ScheduledTransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req ListPageRequest
+	err = ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, valid := server.ownedScheduledTransfer(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               req.Limit,
+		Offset:              req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]ScheduledTransferRunResponse, 0, len(runs))
+	for _, run := range runs {
+		resp = append(resp, newScheduledTransferRunResponse(run))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// ownedScheduledTransfer loads a scheduled transfer of the current user
+func (server *Server) ownedScheduledTransfer(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduledTransfer, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduledTransfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduledTransfer.Owner != authPayload.Username {
+		err := errors.New("scheduled transfer doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return scheduledTransfer, false
+	}
+	return scheduledTransfer, true
+}
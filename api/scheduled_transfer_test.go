@@ -0,0 +1,488 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomScheduledTransfer(owner string, from db.Account, to db.Account) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:            utils.RandomInt(1, 1000),
+		Owner:         owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        utils.RandomMoney(),
+		Currency:      from.Currency,
+		Schedule:      "@monthly",
+		StartAt:       time.Now(),
+		Status:        db.ScheduledTransferStatusActive,
+		NextRunAt:     sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+}
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount()
+	account1.ID = utils.RandomInt(1, 1000)
+	account1.Owner = user1.Username
+	account1.Currency = "USD"
+
+	account2 := randomAccount()
+	account2.ID = account1.ID + 1
+	account2.Owner = user2.Username
+	account2.Currency = "USD"
+
+	startAt := time.Date(2030, time.January, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule":        "0 9 1 * *",
+				"start_at":        startAt,
+				"max_runs":        12,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, user1.Username, arg.Owner)
+						require.Equal(t, int64(100), arg.Amount)
+						require.Equal(t, "USD", arg.Currency)
+						require.True(t, startAt.Equal(arg.StartAt))
+						require.False(t, arg.EndAt.Valid)
+						require.Equal(t, sql.NullInt64{Int64: 12, Valid: true}, arg.MaxRuns)
+						require.True(t, arg.NextRunAt.Valid)
+						require.Equal(t, time.Date(2030, time.February, 1, 9, 0, 0, 0, time.UTC), arg.NextRunAt.Time)
+						return db.ScheduledTransfer{
+							ID:            1,
+							Owner:         arg.Owner,
+							FromAccountID: arg.FromAccountID,
+							ToAccountID:   arg.ToAccountID,
+							Amount:        arg.Amount,
+							Currency:      arg.Currency,
+							Schedule:      arg.Schedule,
+							StartAt:       arg.StartAt,
+							MaxRuns:       arg.MaxRuns,
+							Status:        db.ScheduledTransferStatusActive,
+							NextRunAt:     arg.NextRunAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var resp ScheduledTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, int64(12), *resp.MaxRuns)
+				require.Nil(t, resp.EndAt)
+				require.Equal(t, time.Date(2030, time.February, 1, 9, 0, 0, 0, time.UTC), resp.NextRunAt.UTC())
+			},
+		},
+		{
+			name: "InvalidSchedule",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule":        "every month",
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EndBeforeStart",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule":        "@daily",
+				"start_at":        startAt,
+				"end_at":          startAt.Add(-time.Hour),
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NeverRuns",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule":        "@monthly",
+				"start_at":        startAt,
+				"end_at":          startAt.Add(24 * time.Hour),
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "USD",
+				"schedule":        "@monthly",
+			},
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          100,
+				"currency":        "EUR",
+				"schedule":        "@monthly",
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := serveAuthorizedRequest(t, store, http.MethodPost, "/scheduled_transfers", tc.body, tc.username)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount()
+	account1.ID = utils.RandomInt(1, 1000)
+	account1.Owner = user1.Username
+	account2 := randomAccount()
+	account2.ID = account1.ID + 1
+	account2.Owner = user2.Username
+
+	scheduledTransfer := randomScheduledTransfer(user1.Username, account1, account2)
+	scheduledTransfer.RunCount = 3
+
+	completed := scheduledTransfer
+	completed.Status = db.ScheduledTransferStatusCompleted
+	completed.NextRunAt = sql.NullTime{}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Amount",
+			body:     gin.H{"amount": 250},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+
+				arg := db.UpdateScheduledTransferParams{
+					ID:        scheduledTransfer.ID,
+					Amount:    250,
+					Schedule:  scheduledTransfer.Schedule,
+					Status:    db.ScheduledTransferStatusActive,
+					NextRunAt: scheduledTransfer.NextRunAt,
+				}
+				updated := scheduledTransfer
+				updated.Amount = 250
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Schedule",
+			body:     gin.H{"schedule": "@every 24h"},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, "@every 24h", arg.Schedule)
+						// the start is in the past so the new schedule starts now
+						require.WithinDuration(t, time.Now(), arg.NextRunAt.Time, time.Second)
+						return scheduledTransfer, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "MaxRunsAlreadyReached",
+			body:     gin.H{"max_runs": 3},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "EndBeforeNextRun",
+			body:     gin.H{"end_at": scheduledTransfer.NextRunAt.Time.Add(-time.Minute)},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotActive",
+			body:     gin.H{"amount": 250},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(completed, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "Unauthorized",
+			body:     gin.H{"amount": 250},
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			body:     gin.H{"amount": 250},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID)
+			recorder := serveAuthorizedRequest(t, store, http.MethodPatch, url, tc.body, tc.username)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	id := utils.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name         string
+		deleted      int64
+		expectedCode int
+	}{
+		{name: "OK", deleted: 1, expectedCode: http.StatusNoContent},
+		{name: "NotFound", deleted: 0, expectedCode: http.StatusNotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			arg := db.DeleteScheduledTransferParams{ID: id, Owner: user.Username}
+			store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Eq(arg)).Times(1).Return(tc.deleted, nil)
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", id)
+			recorder := serveAuthorizedRequest(t, store, http.MethodDelete, url, nil, user.Username)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestListScheduledTransferRunsAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount()
+	account1.Owner = user1.Username
+	account2 := randomAccount()
+	account2.Owner = user2.Username
+
+	scheduledTransfer := randomScheduledTransfer(user1.Username, account1, account2)
+	runs := []db.ScheduledTransferRun{
+		{
+			ID:                  2,
+			ScheduledTransferID: scheduledTransfer.ID,
+			Attempt:             2,
+			Status:              db.ScheduledRunStatusSucceeded,
+			TransferID:          sql.NullInt64{Int64: 10, Valid: true},
+		},
+		{
+			ID:                  1,
+			ScheduledTransferID: scheduledTransfer.ID,
+			Attempt:             1,
+			Status:              db.ScheduledRunStatusRetrying,
+			Error:               db.ErrInsufficientFunds.Error(),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			query:    "?limit=10&offset=0",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+
+				arg := db.ListScheduledTransferRunsParams{ScheduledTransferID: scheduledTransfer.ID, Limit: 10, Offset: 0}
+				store.EXPECT().ListScheduledTransferRuns(gomock.Any(), gomock.Eq(arg)).Times(1).Return(runs, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []ScheduledTransferRunResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 2)
+				require.Equal(t, int64(10), *resp[0].TransferID)
+				require.Nil(t, resp[1].TransferID)
+				require.Equal(t, db.ErrInsufficientFunds.Error(), resp[1].Error)
+			},
+		},
+		{
+			name:     "Unauthorized",
+			query:    "?limit=10&offset=0",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().ListScheduledTransferRuns(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingLimit",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/scheduled_transfers/%d/runs%s", scheduledTransfer.ID, tc.query)
+			recorder := serveAuthorizedRequest(t, store, http.MethodGet, url, nil, tc.username)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"github.com/brkss/simplebank/fx"
+	"github.com/brkss/simplebank/mail"
 	"github.com/brkss/simplebank/token"
 	"github.com/brkss/simplebank/utils"
 
@@ -12,23 +14,29 @@ import (
 
 // Server serve HTTP request for our banking service
 type Server struct {
-	store      db.Store
-	router     *gin.Engine
-	tokenMaker token.Maker
-	config     utils.Config
+	store       db.Store
+	router      *gin.Engine
+	tokenMaker  token.Maker
+	revocations token.RevocationStore
+	mailer      mail.Mailer
+	rates       fx.FXRateProvider
+	config      utils.Config
 }
 
 // NewServer creaet new HTTP server and setup routes
-func NewServer(config utils.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymetricKey)
+func NewServer(config utils.Config, store db.Store, revocations token.RevocationStore, mailer mail.Mailer, rates fx.FXRateProvider) (*Server, error) {
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %v", err)
 	}
 
 	server := &Server{
-		store:      store,
-		tokenMaker: tokenMaker,
-		config:     config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		mailer:      mailer,
+		rates:       rates,
+		config:      config,
 	}
 
 	server.SetupRouter()
@@ -40,18 +48,123 @@ func (server *Server) SetupRouter() {
 
 	router.POST("/users", server.createUser)
 	router.POST("/login", server.LoginUser)
+	router.POST("/login/mfa", server.loginMFA)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/.well-known/jwks.json", server.getJWKS)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.GET("/verify_email", server.verifyEmail)
+	router.POST("/oauth/token", server.issueOAuthToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(
+		authMiddleware(server.tokenMaker, server.revocations, server.store),
+		requireUserSession(),
+	)
 
-	authRoutes.POST("/accounts", server.createAccount)
-	authRoutes.GET("/account/:id", server.getAccount)
-	authRoutes.GET("/accounts/:limit/:offset", server.listAccounts)
+	authRoutes.POST("/logout", server.logoutUser)
+	authRoutes.POST("/users/verify_email/resend", server.resendVerifyEmail)
+	authRoutes.POST("/sessions/revoke_all", server.revokeAllSessions)
+	authRoutes.POST("/users/mfa/totp", server.enrollTOTP)
+	authRoutes.POST("/users/mfa/totp/verify", server.verifyTOTP)
+	authRoutes.POST("/api_keys", server.createAPIKey)
+	authRoutes.GET("/api_keys", server.listAPIKeys)
+	authRoutes.DELETE("/api_keys/:id", server.deleteAPIKey)
+	authRoutes.POST("/oauth/clients", server.createOAuthClient)
+	authRoutes.GET("/oauth/authorize", server.getOAuthConsent)
+	authRoutes.POST("/oauth/authorize", server.submitOAuthConsent)
 
-	authRoutes.POST("/transfers", server.createTransfer)
+	accountReadRoutes := router.Group("/").Use(scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.AccountsReadScope))
+
+	accountReadRoutes.GET("/account/:id", server.getAccount)
+	accountReadRoutes.GET("/accounts/:id/:offset", server.listAccounts)
+	accountReadRoutes.GET("/accounts/:id/entries", server.listAccountEntries)
+	accountReadRoutes.GET("/accounts/:id/statement", server.exportAccountStatement)
+
+	accountWriteRoutes := router.Group("/").Use(
+		scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.AccountsWriteScope),
+		server.requireVerifiedEmail(),
+	)
+
+	accountWriteRoutes.POST("/accounts", server.createAccount)
+
+	transferReadRoutes := router.Group("/").Use(scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.TransfersReadScope))
+
+	transferReadRoutes.GET("/transfers/:id", server.getTransfer)
+	transferReadRoutes.GET("/transfers/batch/:id", server.getBatchTransfer)
+	transferReadRoutes.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	transferReadRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
+	transferReadRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	transferReadRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+
+	transferWriteRoutes := router.Group("/").Use(
+		scopedAuthMiddleware(server.tokenMaker, server.revocations, server.store, utils.TransfersWriteScope),
+		server.requireVerifiedEmail(),
+	)
+
+	transferWriteRoutes.POST("/transfers", server.createTransfer)
+	transferWriteRoutes.POST("/transfers/batch", server.createBatchTransfer)
+	transferWriteRoutes.POST("/transfers/authorize", server.authorizeTransfer)
+	transferWriteRoutes.POST("/transfers/:id/capture", server.captureTransfer)
+	transferWriteRoutes.POST("/transfers/:id/void", server.voidTransfer)
+	transferWriteRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
+	transferWriteRoutes.POST("/fx/quotes", server.createFXQuote)
+	transferWriteRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	transferWriteRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	transferWriteRoutes.DELETE("/scheduled_transfers/:id", server.deleteScheduledTransfer)
+
+	approverRoutes := router.Group("/transfer_approvals").Use(
+		authMiddleware(server.tokenMaker, server.revocations, server.store),
+		requireUserSession(),
+		requireRoles(utils.BankerRole, utils.AdminRole),
+	)
+
+	approverRoutes.GET("", server.listPendingApprovals)
+	approverRoutes.POST("/:id/approve", server.approveTransfer)
+	approverRoutes.POST("/:id/reject", server.rejectTransfer)
+
+	adminRoutes := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.revocations, server.store),
+		requireUserSession(),
+		requireRoles(utils.AdminRole),
+	)
+
+	adminRoutes.GET("/users", server.listUsers)
+	adminRoutes.PATCH("/users/:username/role", server.updateUserRole)
+	adminRoutes.POST("/users/:username/unlock", server.unlockUser)
+	adminRoutes.GET("/accounts", server.listAllAccounts)
+	adminRoutes.GET("/transfer_limits", server.listTransferLimits)
+	adminRoutes.GET("/transfer_limits/:scope/:subject", server.getTransferLimit)
+	adminRoutes.PUT("/transfer_limits/:scope/:subject", server.setTransferLimit)
+	adminRoutes.DELETE("/transfer_limits/:scope/:subject", server.deleteTransferLimit)
 
 	server.router = router
 }
 
+// newTokenMaker picks the token maker out of the config, a keyring file
+// takes precedence over the single key settings
+func newTokenMaker(config utils.Config) (token.Maker, error) {
+	opts := []token.MakerOption{
+		token.WithExpectedIssuer(config.TokenIssuer),
+		token.WithExpectedAudience(config.TokenAudience),
+	}
+
+	if config.TokenKeyRingFile != "" {
+		keys, err := token.LoadKeyRingFile(config.TokenKeyRingFile)
+		if err != nil {
+			return nil, err
+		}
+		return token.NewKeyRingMaker(config.TokenType, keys, opts...)
+	}
+
+	return token.NewMaker(
+		config.TokenType,
+		config.TokenSymetricKey,
+		config.TokenPrivateKeyFile,
+		config.TokenPublicKeyFile,
+		opts...,
+	)
+}
+
 // Start new HTTP request and listen for requests !
 func (server *Server) Start(address string) error {
 	return server.router.Run(address)
@@ -60,3 +173,8 @@ func (server *Server) Start(address string) error {
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// codedErrorResponse adds a stable code clients can match on to the error body
+func codedErrorResponse(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
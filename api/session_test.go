@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutAPI(t *testing.T) {
+	username := utils.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		refreshOwner  string
+		buildStubs    func(store *mockdb.MockStore, refreshPayload *token.Payload)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, accessPayload, refreshPayload *token.Payload)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, accessPayload, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				requireRevoked(t, server, accessPayload, true)
+			},
+		},
+		{
+			name:         "WithRefreshToken",
+			refreshOwner: username,
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Eq(db.BlockSessionParams{
+						ID:       refreshPayload.ID,
+						Username: refreshPayload.Username,
+					})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, accessPayload, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				requireRevoked(t, server, accessPayload, true)
+				requireRevoked(t, server, refreshPayload, true)
+			},
+		},
+		{
+			name:         "RefreshTokenOfAnotherUser",
+			refreshOwner: utils.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, accessPayload, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireRevoked(t, server, accessPayload, false)
+				requireRevoked(t, server, refreshPayload, false)
+			},
+		},
+		{
+			name:         "InternalError",
+			refreshOwner: username,
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, accessPayload, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireRevoked(t, server, accessPayload, false)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := NewTestServer(t, store)
+
+			accessToken, accessPayload, err := server.tokenMaker.CreateToken(username, utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			body := gin.H{}
+			var refreshPayload *token.Payload
+			if tc.refreshOwner != "" {
+				var refreshToken string
+				refreshToken, refreshPayload, err = server.tokenMaker.CreateToken(tc.refreshOwner, utils.DepositorRole, time.Hour)
+				require.NoError(t, err)
+				body["refresh_token"] = refreshToken
+			}
+			tc.buildStubs(store, refreshPayload)
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/logout", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder, accessPayload, refreshPayload)
+		})
+	}
+}
+
+func TestRevokeAllSessionsAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := utils.RandomOwner()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		BlockUserSessions(gomock.Any(), gomock.Eq(username)).
+		Times(1).
+		Return(nil)
+
+	server := NewTestServer(t, store)
+
+	_, otherPayload, err := server.tokenMaker.CreateToken(username, utils.DepositorRole, time.Hour)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/sessions/revoke_all", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	requireRevoked(t, server, otherPayload, true)
+
+	// the token used for the request is dead as well
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func requireRevoked(t *testing.T, server *Server, payload *token.Payload, expected bool) {
+	if payload == nil {
+		return
+	}
+	revoked, err := server.revocations.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.Equal(t, expected, revoked)
+}
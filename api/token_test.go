@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/mail"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := utils.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		buildSession  func(refreshToken string, payload *token.Payload) db.Session
+		sessionErr    error
+		userErr       error
+		invalidToken  bool
+		tokenType     string
+		checkResponse func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response RenewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.NotEmpty(t, response.AccessToken)
+				require.WithinDuration(t, time.Now().Add(time.Minute), response.AccessTokenExpiresAt, time.Second)
+
+				// the refresh token was issued to a banker since demoted to depositor
+				payload, err := tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, utils.DepositorRole, payload.Role)
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			userErr: sql.ErrNoRows,
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			invalidToken: true,
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			invalidToken: true,
+			tokenType:    token.AccessTokenType,
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			sessionErr: sql.ErrNoRows,
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			sessionErr: sql.ErrConnDone,
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.IsBlocked = true
+				return session
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.Username = utils.RandomOwner()
+				return session
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedSessionToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.RefreshToken = utils.RandomString(32)
+				return session
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return session
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := NewTestServer(t, store)
+
+			tokenType := tc.tokenType
+			if tokenType == "" {
+				tokenType = token.RefreshTokenType
+			}
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, utils.BankerRole, time.Hour, token.WithTokenType(tokenType))
+			require.NoError(t, err)
+
+			session := tc.buildSession(refreshToken, payload)
+			if tc.invalidToken {
+				if tc.tokenType == "" {
+					refreshToken = utils.RandomString(32)
+				}
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			} else {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, tc.sessionErr)
+			}
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(username)).
+				AnyTimes().
+				Return(db.User{Username: username, Role: utils.DepositorRole}, tc.userErr)
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server.tokenMaker, recorder)
+		})
+	}
+}
+
+func randomSession(refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:           payload.ID,
+		Username:     payload.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    "test-agent",
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    payload.ExpiredAt,
+		CreatedAt:    payload.IssuedAt,
+	}
+}
+
+func TestGetJWKSAPI(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	privateKeyFile := filepath.Join(t.TempDir(), "private.pem")
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		config        utils.Config
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			config: utils.Config{
+				TokenType:           token.TypeJWTEdDSA,
+				TokenPrivateKeyFile: privateKeyFile,
+				TokenDuration:       time.Minute,
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var jwks token.JWKS
+				err := json.Unmarshal(recorder.Body.Bytes(), &jwks)
+				require.NoError(t, err)
+				require.Len(t, jwks.Keys, 1)
+				require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+				require.Equal(t, base64.RawURLEncoding.EncodeToString(publicKey), jwks.Keys[0].X)
+			},
+		},
+		{
+			name: "SymetricKey",
+			config: utils.Config{
+				TokenType:        token.TypePaseto,
+				TokenSymetricKey: utils.RandomString(32),
+				TokenDuration:    time.Minute,
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := NewServer(tc.config, nil, token.NewMemoryRevocationStore(), mail.NewMemoryMailer(), nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -2,18 +2,24 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type CreateTransferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
 	Amount        int64  `json:"amount" binding:"required,gt=1"`
 	Currency      string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	// QuoteID is required when the destination account holds another currency,
+	// Amount and Currency are then those debited from the source account
+	QuoteID string `json:"quote_id" binding:"omitempty,uuid"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -24,44 +30,100 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	if !server.validAccount(ctx, request.FromAccountID, request.Currency) {
+	fromAccount, valid := server.validAccount(ctx, request.FromAccountID, request.Currency)
+	if !valid {
 		return
 	}
 
-	if !server.validAccount(ctx, request.ToAccountID, request.Currency) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// with a quote the destination currency is the one of the quote, checked when the transfer is made
+	var fxQuoteID uuid.NullUUID
+	if request.QuoteID == "" {
+		_, valid = server.validAccount(ctx, request.ToAccountID, request.Currency)
+	} else {
+		fxQuoteID = uuid.NullUUID{UUID: uuid.MustParse(request.QuoteID), Valid: true}
+		_, valid = server.findAccount(ctx, request.ToAccountID)
+	}
+	if !valid {
+		return
+	}
+
+	idempotency, valid := idempotencyParams(ctx, authPayload.Username, request)
+	if !valid {
 		return
 	}
 
 	arg := db.TransferTxParams{
-		FromAccountId: request.FromAccountID,
-		ToAccountId:   request.ToAccountID,
-		Amount:        request.Amount,
+		FromAccountId:   request.FromAccountID,
+		ToAccountId:     request.ToAccountID,
+		Amount:          request.Amount,
+		FXQuoteID:       fxQuoteID,
+		RequireApproval: server.requiresApproval(request.Amount),
+		Idempotency:     idempotency,
 	}
 	results, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrCurrencyMismatch) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(limitExceededCode, err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) ||
+			errors.Is(err, db.ErrFXQuoteInvalid) ||
+			errors.Is(err, db.ErrAmountTooSmall) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	markReplayed(ctx, results.Replayed)
+	if results.Transfer.Status == db.TransferStatusPendingApproval {
+		ctx.JSON(http.StatusAccepted, results)
+		return
+	}
 	ctx.JSON(http.StatusOK, results)
 }
 
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) bool {
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 
-	account, err := server.store.GetAccount(ctx, accountID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return false
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return false
+	account, valid := server.findAccount(ctx, accountID)
+	if !valid {
+		return account, false
 	}
 
 	if account.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch %s / %s", accountID, account.Currency, currency)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return false
+		return account, false
 	}
 
-	return true
+	return account, true
+}
+
+// findAccount loads an account, answering 404 when it doesn't exist
+func (server *Server) findAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+	return account, true
 }
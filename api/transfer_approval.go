@@ -0,0 +1,134 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// approvalRequiredCode flags amounts that can only go through approval as transfers of their own
+const approvalRequiredCode = "approval_required"
+
+// requiresApproval tells whether a transfer of amount waits for a second person to approve it
+// under the threshold of the config
+func (server *Server) requiresApproval(amount int64) bool {
+	return db.RequiresApproval(server.config.TransferApprovalThreshold, amount)
+}
+
+// listPendingApprovals returns the transfers waiting for approval, oldest first
+func (server *Server) listPendingApprovals(ctx *gin.Context) {
+	var req ListPageRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfers, err := server.store.ListPendingApprovalTransfers(ctx, db.ListPendingApprovalTransfersParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+type TransferApprovalUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// approveTransfer moves the money of a transfer pending approval, the maker of a transfer can't approve it
+func (server *Server) approveTransfer(ctx *gin.Context) {
+	var uri TransferApprovalUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.ApproveTransferTx(ctx, db.ApproveTransferTxParams{
+		TransferID: uri.ID,
+		Approver:   authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrSelfApproval) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrTransferNotPendingApproval) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrLimitExceeded) {
+			ctx.JSON(http.StatusUnprocessableEntity, codedErrorResponse(limitExceededCode, err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// rejectTransfer closes a transfer pending approval without moving money
+func (server *Server) rejectTransfer(ctx *gin.Context) {
+	var uri TransferApprovalUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.findAccount(ctx, transfer.FromAccountID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner == authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(db.ErrSelfApproval))
+		return
+	}
+
+	transfer, err = server.store.RejectTransfer(ctx, db.RejectTransferParams{
+		ID:         transfer.ID,
+		ReviewedBy: sql.NullString{String: authPayload.Username, Valid: true},
+	})
+	if err != nil {
+		// the transfer was no longer pending approval
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrTransferNotPendingApproval))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
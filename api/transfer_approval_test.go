@@ -0,0 +1,315 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPendingApprovalTransfer(account db.Account) db.Transfer {
+	amount := utils.RandomInt(1001, 5000)
+	return db.Transfer{
+		ID:            utils.RandomInt(1, 1000),
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID + 1,
+		Amount:        amount,
+		Currency:      account.Currency,
+		ToAmount:      amount,
+		ToCurrency:    account.Currency,
+		ExchangeRate:  "1",
+		Status:        db.TransferStatusPendingApproval,
+	}
+}
+
+func TestListPendingApprovalsAPI(t *testing.T) {
+	transfers := []db.Transfer{
+		randomPendingApprovalTransfer(randomAccount()),
+		randomPendingApprovalTransfer(randomAccount()),
+	}
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Banker",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPendingApprovalTransfers(gomock.Any(), gomock.Eq(db.ListPendingApprovalTransfersParams{Limit: 5, Offset: 0})).
+					Times(1).
+					Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotTransfers []db.Transfer
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotTransfers)
+				require.NoError(t, err)
+				require.Len(t, gotTransfers, len(transfers))
+			},
+		},
+		{
+			name: "Depositor",
+			role: utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPendingApprovalTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := serveRequestAs(t, store, http.MethodGet, "/transfer_approvals?limit=5&offset=0", nil, utils.RandomOwner(), tc.role)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestApproveTransferAPI(t *testing.T) {
+	approver := utils.RandomOwner()
+	transfer := randomPendingApprovalTransfer(randomAccount())
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				posted := transfer
+				posted.Status = db.TransferStatusPosted
+				posted.ReviewedBy = sql.NullString{String: approver, Valid: true}
+
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(db.ApproveTransferTxParams{
+						TransferID: transfer.ID,
+						Approver:   approver,
+					})).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: posted}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.TransferTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &result)
+				require.NoError(t, err)
+				require.Equal(t, db.TransferStatusPosted, result.Transfer.Status)
+				require.Equal(t, approver, result.Transfer.ReviewedBy.String)
+			},
+		},
+		{
+			name: "Depositor",
+			role: utils.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ApproveTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "SelfApproval",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrSelfApproval)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotPendingApproval",
+			role: utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrTransferNotPendingApproval)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "LimitExceeded",
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: single transfer max is 1000", db.ErrLimitExceeded))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), limitExceededCode)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/transfer_approvals/%d/approve", transfer.ID)
+			recorder := serveRequestAs(t, store, http.MethodPost, url, nil, approver, tc.role)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRejectTransferAPI(t *testing.T) {
+	reviewer := utils.RandomOwner()
+	account := randomAccount()
+	transfer := randomPendingApprovalTransfer(account)
+
+	testCases := []struct {
+		name          string
+		reviewer      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			reviewer: reviewer,
+			buildStubs: func(store *mockdb.MockStore) {
+				rejected := transfer
+				rejected.Status = db.TransferStatusRejected
+				rejected.ReviewedBy = sql.NullString{String: reviewer, Valid: true}
+
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					RejectTransfer(gomock.Any(), gomock.Eq(db.RejectTransferParams{
+						ID:         transfer.ID,
+						ReviewedBy: sql.NullString{String: reviewer, Valid: true},
+					})).
+					Times(1).
+					Return(rejected, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotTransfer db.Transfer
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotTransfer)
+				require.NoError(t, err)
+				require.Equal(t, db.TransferStatusRejected, gotTransfer.Status)
+			},
+		},
+		{
+			name:     "SelfReview",
+			reviewer: account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().RejectTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "NotPendingApproval",
+			reviewer: reviewer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					RejectTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			reviewer: reviewer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().RejectTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			url := fmt.Sprintf("/transfer_approvals/%d/reject", transfer.ID)
+			recorder := serveRequestAs(t, store, http.MethodPost, url, nil, tc.reviewer, utils.BankerRole)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// getTransfer returns a transfer to the owners of either of its accounts
+func (server *Server) getTransfer(ctx *gin.Context) {
+	var uri TransferUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, valid := server.transferParty(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// ListAccountTransfersRequest filters the transfers of an account, MinAmount is compared to the amount
+// in the currency of the account, the one debited going out and the one credited coming in
+type ListAccountTransfersRequest struct {
+	ListPageRequest
+	Direction string    `form:"direction" binding:"omitempty,oneof=in out all"`
+	From      time.Time `form:"from"`
+	To        time.Time `form:"to"`
+	MinAmount int64     `form:"min_amount" binding:"min=0"`
+}
+
+// listAccountTransfers returns the transfers sent or received by an account of the current user, newest first
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri AccountEntriesUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req ListAccountTransfersRequest
+	err = ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.To.After(req.From) {
+		err := errors.New("to must come after from")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.findAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the current user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	from := sql.NullTime{Time: req.From, Valid: !req.From.IsZero()}
+	to := sql.NullTime{Time: req.To, Valid: !req.To.IsZero()}
+
+	// each direction has a query of its own so that it can use the index of its side
+	var transfers []db.Transfer
+	switch req.Direction {
+	case "in":
+		transfers, err = server.store.ListIncomingTransfers(ctx, db.ListIncomingTransfersParams{
+			AccountID: account.ID,
+			FromTime:  from,
+			ToTime:    to,
+			MinAmount: req.MinAmount,
+			RowLimit:  req.Limit,
+			RowOffset: req.Offset,
+		})
+	case "out":
+		transfers, err = server.store.ListOutgoingTransfers(ctx, db.ListOutgoingTransfersParams{
+			AccountID: account.ID,
+			FromTime:  from,
+			ToTime:    to,
+			MinAmount: req.MinAmount,
+			RowLimit:  req.Limit,
+			RowOffset: req.Offset,
+		})
+	default:
+		transfers, err = server.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+			AccountID: account.ID,
+			FromTime:  from,
+			ToTime:    to,
+			MinAmount: req.MinAmount,
+			RowLimit:  req.Limit,
+			RowOffset: req.Offset,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
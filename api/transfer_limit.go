@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// limitExceededCode is the error code of the transfers rejected by a transfer limit
+const limitExceededCode = "limit_exceeded"
+
+type TransferLimitResponse struct {
+	Scope         string    `json:"scope"`
+	Subject       string    `json:"subject"`
+	MaxAmount     *int64    `json:"max_amount,omitempty"`
+	DailyAmount   *int64    `json:"daily_amount,omitempty"`
+	MonthlyAmount *int64    `json:"monthly_amount,omitempty"`
+	HourlyCount   *int64    `json:"hourly_count,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func newTransferLimitResponse(limit db.TransferLimit) TransferLimitResponse {
+	resp := TransferLimitResponse{
+		Scope:     limit.Scope,
+		Subject:   limit.Subject,
+		UpdatedAt: limit.UpdatedAt,
+	}
+	if limit.MaxAmount.Valid {
+		resp.MaxAmount = &limit.MaxAmount.Int64
+	}
+	if limit.DailyAmount.Valid {
+		resp.DailyAmount = &limit.DailyAmount.Int64
+	}
+	if limit.MonthlyAmount.Valid {
+		resp.MonthlyAmount = &limit.MonthlyAmount.Int64
+	}
+	if limit.HourlyCount.Valid {
+		resp.HourlyCount = &limit.HourlyCount.Int64
+	}
+	return resp
+}
+
+// TransferLimitUri names a limit, the subject is an account id, a username or a currency depending on the scope
+type TransferLimitUri struct {
+	Scope   string `uri:"scope" binding:"required,oneof=account user currency"`
+	Subject string `uri:"subject" binding:"required"`
+}
+
+// SetTransferLimitRequest replaces the limit of a subject, a field left out falls back on the broader scopes
+type SetTransferLimitRequest struct {
+	MaxAmount     int64 `json:"max_amount" binding:"omitempty,min=1"`
+	DailyAmount   int64 `json:"daily_amount" binding:"omitempty,min=1"`
+	MonthlyAmount int64 `json:"monthly_amount" binding:"omitempty,min=1"`
+	HourlyCount   int64 `json:"hourly_count" binding:"omitempty,min=1"`
+}
+
+func (server *Server) listTransferLimits(ctx *gin.Context) {
+	var req ListPageRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	limits, err := server.store.ListTransferLimits(ctx, db.ListTransferLimitsParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]TransferLimitResponse, 0, len(limits))
+	for _, limit := range limits {
+		resp = append(resp, newTransferLimitResponse(limit))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) getTransferLimit(ctx *gin.Context) {
+	var uri TransferLimitUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	limit, err := server.store.GetTransferLimit(ctx, db.GetTransferLimitParams{
+		Scope:   uri.Scope,
+		Subject: uri.Subject,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferLimitResponse(limit))
+}
+
+// setTransferLimit creates or replaces the limit of an account, a user or a currency
+func (server *Server) setTransferLimit(ctx *gin.Context) {
+	var uri TransferLimitUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req SetTransferLimitRequest
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.validLimitSubject(ctx, uri) {
+		return
+	}
+
+	limit, err := server.store.UpsertTransferLimit(ctx, db.UpsertTransferLimitParams{
+		Scope:         uri.Scope,
+		Subject:       uri.Subject,
+		MaxAmount:     optionalLimit(req.MaxAmount),
+		DailyAmount:   optionalLimit(req.DailyAmount),
+		MonthlyAmount: optionalLimit(req.MonthlyAmount),
+		HourlyCount:   optionalLimit(req.HourlyCount),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferLimitResponse(limit))
+}
+
+func (server *Server) deleteTransferLimit(ctx *gin.Context) {
+	var uri TransferLimitUri
+	err := ctx.ShouldBindUri(&uri)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	deleted, err := server.store.DeleteTransferLimit(ctx, db.DeleteTransferLimitParams{
+		Scope:   uri.Scope,
+		Subject: uri.Subject,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// validLimitSubject checks the account or user a limit is set on exists and the currency is supported
+func (server *Server) validLimitSubject(ctx *gin.Context, uri TransferLimitUri) bool {
+	switch uri.Scope {
+	case db.TransferLimitScopeAccount:
+		accountID, err := strconv.ParseInt(uri.Subject, 10, 64)
+		if err != nil || accountID <= 0 {
+			err := fmt.Errorf("invalid account id %q", uri.Subject)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return false
+		}
+		_, valid := server.findAccount(ctx, accountID)
+		return valid
+	case db.TransferLimitScopeUser:
+		_, err := server.store.GetUser(ctx, uri.Subject)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return false
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		return true
+	default:
+		switch uri.Subject {
+		case "USD", "EUR", "CAD":
+			return true
+		}
+		err := errors.New("unsupported currency")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+}
+
+func optionalLimit(value int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: value, Valid: value > 0}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTransferLimitAPI(t *testing.T) {
+	account := randomAccount()
+	user, _ := randomUser(t)
+	accountSubject := fmt.Sprint(account.ID)
+
+	testCases := []struct {
+		name          string
+		url           string
+		role          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Account",
+			url:  "/admin/transfer_limits/account/" + accountSubject,
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500, "daily_amount": 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.UpsertTransferLimitParams{
+					Scope:       db.TransferLimitScopeAccount,
+					Subject:     accountSubject,
+					MaxAmount:   sql.NullInt64{Int64: 500, Valid: true},
+					DailyAmount: sql.NullInt64{Int64: 1000, Valid: true},
+				}
+				store.EXPECT().
+					UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferLimit{
+						Scope:       arg.Scope,
+						Subject:     arg.Subject,
+						MaxAmount:   arg.MaxAmount,
+						DailyAmount: arg.DailyAmount,
+						UpdatedAt:   time.Now(),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var limit TransferLimitResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &limit)
+				require.NoError(t, err)
+				require.Equal(t, accountSubject, limit.Subject)
+				require.Equal(t, int64(500), *limit.MaxAmount)
+				require.Equal(t, int64(1000), *limit.DailyAmount)
+				require.Nil(t, limit.MonthlyAmount)
+				require.Nil(t, limit.HourlyCount)
+			},
+		},
+		{
+			name: "User",
+			url:  "/admin/transfer_limits/user/" + user.Username,
+			role: utils.AdminRole,
+			body: gin.H{"hourly_count": 10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UpsertTransferLimit(gomock.Any(), gomock.Eq(db.UpsertTransferLimitParams{
+						Scope:       db.TransferLimitScopeUser,
+						Subject:     user.Username,
+						HourlyCount: sql.NullInt64{Int64: 10, Valid: true},
+					})).
+					Times(1).
+					Return(db.TransferLimit{Scope: db.TransferLimitScopeUser, Subject: user.Username}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Currency",
+			url:  "/admin/transfer_limits/currency/USD",
+			role: utils.AdminRole,
+			body: gin.H{"monthly_amount": 100000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTransferLimit(gomock.Any(), gomock.Eq(db.UpsertTransferLimitParams{
+						Scope:         db.TransferLimitScopeCurrency,
+						Subject:       "USD",
+						MonthlyAmount: sql.NullInt64{Int64: 100000, Valid: true},
+					})).
+					Times(1).
+					Return(db.TransferLimit{Scope: db.TransferLimitScopeCurrency, Subject: "USD"}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Depositor",
+			url:  "/admin/transfer_limits/currency/USD",
+			role: utils.DepositorRole,
+			body: gin.H{"monthly_amount": 100000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			url:  "/admin/transfer_limits/country/MA",
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			url:  "/admin/transfer_limits/currency/XYZ",
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAccountID",
+			url:  "/admin/transfer_limits/account/abc",
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			url:  "/admin/transfer_limits/account/" + accountSubject,
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			url:  "/admin/transfer_limits/user/" + user.Username,
+			role: utils.AdminRole,
+			body: gin.H{"max_amount": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeLimit",
+			url:  "/admin/transfer_limits/currency/USD",
+			role: utils.AdminRole,
+			body: gin.H{"daily_amount": -1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := serveRequestAs(t, store, http.MethodPut, tc.url, tc.body, utils.RandomOwner(), tc.role)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteTransferLimitAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		deleted       int64
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			deleted: 1,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:    "NotFound",
+			deleted: 0,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				DeleteTransferLimit(gomock.Any(), gomock.Eq(db.DeleteTransferLimitParams{
+					Scope:   db.TransferLimitScopeCurrency,
+					Subject: "EUR",
+				})).
+				Times(1).
+				Return(tc.deleted, nil)
+
+			recorder := serveRequestAs(t, store, http.MethodDelete, "/admin/transfer_limits/currency/EUR", nil, utils.RandomOwner(), utils.AdminRole)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -7,14 +7,16 @@ import (
 	"time"
 
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/token"
 	"github.com/brkss/simplebank/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	pq "github.com/lib/pq"
 )
 
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	FullName string `json:"full_name" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
@@ -23,8 +25,10 @@ type CreateUserResponse struct {
 	Username        string    `json:"username"`
 	FullName        string    `json:"full_name"`
 	Email           string    `json:"email"`
+	Role            string    `json:"role"`
+	IsEmailVerified bool      `json:"is_email_verified"`
 	PasswordChanged time.Time `json:"password_changed_at"`
-	CreatedAt       time.Time `json:""created_at`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 func (server *Server) createUser(ctx *gin.Context) {
@@ -40,16 +44,29 @@ func (server *Server) createUser(ctx *gin.Context) {
 	hashedPassword, err := utils.HashPassword(request.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	secretCode, err := utils.GenerateSecureToken(verifyEmailCodeSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       request.Username,
-		FullName:       request.FullName,
-		Email:          request.Email,
-		HashedPassword: hashedPassword,
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       request.Username,
+			FullName:       request.FullName,
+			Email:          request.Email,
+			HashedPassword: hashedPassword,
+		},
+		SecretCodeHash: utils.HashSecureToken(secretCode),
+		AfterCreate: func(user db.User, verifyEmail db.VerifyEmail) error {
+			return server.sendVerifyEmail(ctx, user, verifyEmail, secretCode)
+		},
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	result, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		pqErr, ok := err.(*pq.Error)
 		if ok {
@@ -63,25 +80,34 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	resp := CreateUserResponse{
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+	return
+}
+
+func newUserResponse(user db.User) CreateUserResponse {
+	return CreateUserResponse{
 		Username:        user.Username,
 		Email:           user.Email,
 		FullName:        user.FullName,
+		Role:            user.Role,
+		IsEmailVerified: user.IsEmailVerified,
 		CreatedAt:       user.CreatedAt,
 		PasswordChanged: user.PasswordChanged,
 	}
-
-	ctx.JSON(http.StatusOK, resp)
-	return
 }
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username string   `json:"username" binding:"required"`
+	Password string   `json:"password" binding:"required"`
+	Scopes   []string `json:"scopes" binding:"omitempty,dive,oneof=accounts:read accounts:write transfers:read transfers:write"`
 }
 
 type LoginResponse struct {
-	Accesstoken string `json:"access_token"`
+	SessionID             uuid.UUID `json:"session_id"`
+	Accesstoken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 func (server *Server) LoginUser(ctx *gin.Context) {
@@ -92,10 +118,15 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !server.checkLoginLockout(ctx, req.Username) {
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			utils.VerifyPassword(req.Password, dummyPasswordHash)
+			server.rejectLogin(ctx, req.Username, errInvalidCredentials)
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -104,17 +135,87 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 
 	err = utils.VerifyPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		server.rejectLogin(ctx, req.Username, errInvalidCredentials)
+		return
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = utils.DefaultScopes
+	}
+
+	totp, err := server.store.GetTOTPSecret(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && totp.Confirmed {
+		challengeScopes := append([]string{utils.MFAChallengeScope}, scopes...)
+		mfaToken, mfaPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, mfaChallengeDuration, token.WithScopes(challengeScopes...))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, LoginMFAChallengeResponse{
+			MFARequired:       true,
+			MFAToken:          mfaToken,
+			MFATokenExpiresAt: mfaPayload.ExpiredAt,
+		})
 		return
 	}
 
-	token, err := server.tokenMaker.CreateToken(user.Username, server.config.TokenDuration)
+	err = server.clearFailedLogins(ctx, user.Username)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	response := LoginResponse{
-		Accesstoken: token,
+
+	response, err := server.startSession(ctx, user.Username, user.Role, scopes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 	ctx.JSON(http.StatusOK, response)
 }
+
+// startSession issues the access and refresh tokens of a successful login
+// and records the session the refresh token belongs to
+func (server *Server) startSession(ctx *gin.Context, username string, role string, scopes []string) (LoginResponse, error) {
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(username, role, server.config.TokenDuration, token.WithScopes(scopes...))
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
+		username,
+		role,
+		server.config.RefreshTokenDuration,
+		token.WithScopes(scopes...),
+		token.WithTokenType(token.RefreshTokenType),
+	)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		SessionID:             session.ID,
+		Accesstoken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+	}, nil
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,9 +15,11 @@ import (
 
 	mockdb "github.com/brkss/simplebank/db/mock"
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/mail"
 	"github.com/brkss/simplebank/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,7 +30,7 @@ type eqCreateUserMatcher struct {
 
 func (e eqCreateUserMatcher) Matches(x interface{}) bool {
 
-	arg, ok := x.(db.CreateUserParams)
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -37,7 +41,7 @@ func (e eqCreateUserMatcher) Matches(x interface{}) bool {
 	}
 
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, x)
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
 func (e eqCreateUserMatcher) String() string {
@@ -55,7 +59,7 @@ func TestCreateUserAPI(t *testing.T) {
 		name          string
 		body          gin.H
 		buildStabs    func(store *mockdb.MockStore)
-		checkResponse func(recorder *httptest.ResponseRecorder)
+		checkResponse func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer)
 	}{
 		{
 			name: "OK",
@@ -72,13 +76,59 @@ func TestCreateUserAPI(t *testing.T) {
 					Email:    user.Email,
 				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParam(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserParam(arg, password)).
 					Times(1).
-					Return(user, nil)
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						require.NotEmpty(t, arg.SecretCodeHash)
+						verifyEmail := db.VerifyEmail{ID: 1, Username: user.Username, Email: user.Email, SecretCodeHash: arg.SecretCodeHash}
+						err := arg.AfterCreate(user, verifyEmail)
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, err
+					})
 			},
-			checkResponse: func(recorder *httptest.ResponseRecorder) {
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 				requireBodyMatch(t, recorder.Body, user)
+
+				msg, ok := mailer.LastMessageTo(user.Email)
+				require.True(t, ok)
+				require.Contains(t, msg.Body, "/verify_email?id=1&code=")
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"username":  user.Username,
+				"email":     "not-an-email",
+				"password":  password,
+				"full_name": user.FullName,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Empty(t, mailer.Messages())
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			body: gin.H{
+				"username":  user.Username,
+				"email":     user.Email,
+				"password":  password,
+				"full_name": user.FullName,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, mailer.Messages())
 			},
 		},
 	}
@@ -102,11 +152,352 @@ func TestCreateUserAPI(t *testing.T) {
 			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			require.NoError(t, err)
 
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, server.mailer.(*mail.MemoryMailer))
+		})
+	}
+
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStabs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Eq(db.DeleteLoginThrottleParams{Kind: throttleKindUsername, Subject: user.Username})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							RefreshToken: arg.RefreshToken,
+							UserAgent:    arg.UserAgent,
+							ClientIp:     arg.ClientIp,
+							IsBlocked:    arg.IsBlocked,
+							ExpiresAt:    arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response LoginResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.NotZero(t, response.SessionID)
+				require.NotEmpty(t, response.Accesstoken)
+				require.NotEmpty(t, response.RefreshToken)
+				require.True(t, response.RefreshTokenExpiresAt.After(response.AccessTokenExpiresAt))
+			},
+		},
+		{
+			name: "MFARequired",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Confirmed: true}, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response LoginMFAChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.True(t, response.MFARequired)
+				require.NotEmpty(t, response.MFAToken)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "UnconfirmedTOTP",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, Confirmed: false}, nil)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "NarrowedScopes",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+				"scopes":   []string{utils.AccountsReadScope},
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+				"scopes":   []string{"everything"},
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockout(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				expectFailedLogin(store, user.Username, 1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": utils.RandomString(12),
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectFailedLogin(store, user.Username, 1)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// same answer as an unknown user so usernames can't be enumerated
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "ThresholdReached",
+			body: gin.H{
+				"username": user.Username,
+				"password": utils.RandomString(12),
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectFailedLogin(store, user.Username, 4)
+				store.EXPECT().
+					LockLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LockLoginParams) error {
+						// one failure past the threshold of 3 doubles the base lockout
+						require.Equal(t, throttleKindUsername, arg.Kind)
+						require.Equal(t, user.Username, arg.Subject)
+						require.WithinDuration(t, time.Now().Add(2*time.Minute), arg.LockedUntil, time.Second)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginLockout(gomock.Any(), gomock.Eq(db.GetLoginLockoutParams{Username: user.Username, ClientIp: ""})).
+					Times(1).
+					Return(time.Now().Add(90*time.Second), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "90", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "SessionError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStabs: func(store *mockdb.MockStore) {
+				expectNoLockout(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeleteLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStabs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/login"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(recorder)
 		})
 	}
+}
 
+// expectNoLockout stubs the lockout check of a login that isn't locked out
+func expectNoLockout(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetLoginLockout(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(time.Time{}, nil)
+}
+
+// expectFailedLogin stubs the failure recorded against username and the client ip,
+// username ends up with attempts failures
+func expectFailedLogin(store *mockdb.MockStore, username string, attempts int32) {
+	store.EXPECT().
+		RecordFailedLogin(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordFailedLoginParams) (db.LoginThrottle, error) {
+			throttle := db.LoginThrottle{Kind: arg.Kind, Subject: arg.Subject, FailedAttempts: 1}
+			if arg.Kind == throttleKindUsername && arg.Subject == username {
+				throttle.FailedAttempts = attempts
+			}
+			return throttle, nil
+		})
 }
 
 func randomUser(t *testing.T) (db.User, string) {
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/mail"
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const verifyEmailCodeSize = 32
+
+// sendVerifyEmail mails the link confirming the address of a new user,
+// secretCode is the code verifyEmail only keeps the hash of
+func (server *Server) sendVerifyEmail(ctx *gin.Context, user db.User, verifyEmail db.VerifyEmail, secretCode string) error {
+	return server.mailer.Send(ctx, mail.Message{
+		To:      []string{verifyEmail.Email},
+		Subject: "Welcome to SimpleBank",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nThank you for registering with us! Please confirm your email address by opening the link below:\n\n%s?id=%d&code=%s\n",
+			user.FullName, server.config.VerifyEmailURL, verifyEmail.ID, secretCode,
+		),
+	})
+}
+
+type VerifyEmailRequest struct {
+	EmailID    int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"code" binding:"required"`
+}
+
+type VerifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail handles the link sent on signup and flags the address as verified
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailId:        req.EmailID,
+		SecretCodeHash: utils.HashSecureToken(req.SecretCode),
+	})
+	if err != nil {
+		if err == db.ErrInvalidVerifyEmail {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, VerifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// resendVerifyEmail mails a fresh link to a user whose earlier one expired or got lost
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsEmailVerified {
+		err := errors.New("email address is already verified")
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	secretCode, err := utils.GenerateSecureToken(verifyEmailCodeSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: utils.HashSecureToken(secretCode),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.sendVerifyEmail(ctx, user, verifyEmail, secretCode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
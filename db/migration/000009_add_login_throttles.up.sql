@@ -0,0 +1,8 @@
+CREATE TABLE "login_throttles" (
+  "kind" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failed_attempts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("kind", "subject")
+);
@@ -0,0 +1,10 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "fx_quote_id";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_currency";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_amount";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "currency";
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "currency";
+
+DROP TABLE IF EXISTS "fx_quotes";
+DROP TABLE IF EXISTS "fx_rates";
@@ -0,0 +1,47 @@
+CREATE TABLE "fx_rates" (
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" numeric NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("from_currency", "to_currency")
+);
+
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" numeric NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "entries" ADD COLUMN "currency" varchar NOT NULL DEFAULT '';
+
+UPDATE "entries" SET "currency" = "accounts"."currency"
+FROM "accounts" WHERE "accounts"."id" = "entries"."account_id";
+
+ALTER TABLE "entries" ALTER COLUMN "currency" DROP DEFAULT;
+
+ALTER TABLE "transfers" ADD COLUMN "currency" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "transfers" ADD COLUMN "to_currency" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+ALTER TABLE "transfers" ADD COLUMN "fx_quote_id" uuid;
+
+-- transfers made so far were all between accounts of the same currency
+UPDATE "transfers" SET
+  "currency" = "accounts"."currency",
+  "to_amount" = "transfers"."amount",
+  "to_currency" = "accounts"."currency"
+FROM "accounts" WHERE "accounts"."id" = "transfers"."from_account_id";
+
+ALTER TABLE "transfers" ALTER COLUMN "currency" DROP DEFAULT;
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" DROP DEFAULT;
+ALTER TABLE "transfers" ALTER COLUMN "to_currency" DROP DEFAULT;
+ALTER TABLE "transfers" ALTER COLUMN "exchange_rate" DROP DEFAULT;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
@@ -0,0 +1,5 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "expires_at";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "authorized_amount";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "status";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "held_balance";
@@ -0,0 +1,7 @@
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'posted';
+ALTER TABLE "transfers" ADD COLUMN "authorized_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "transfers" ADD COLUMN "expires_at" timestamptz;
+
+CREATE INDEX ON "transfers" ("expires_at") WHERE "status" = 'pending';
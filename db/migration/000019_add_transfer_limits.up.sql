@@ -0,0 +1,14 @@
+CREATE TABLE "transfer_limits" (
+  "id" bigserial PRIMARY KEY,
+  "scope" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "max_amount" bigint,
+  "daily_amount" bigint,
+  "monthly_amount" bigint,
+  "hourly_count" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "transfer_limits" ("scope", "subject");
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
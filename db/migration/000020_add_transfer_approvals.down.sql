@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "transfers_id_idx";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reviewed_at";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reviewed_by";
@@ -0,0 +1,6 @@
+ALTER TABLE "transfers" ADD COLUMN "reviewed_by" varchar;
+ALTER TABLE "transfers" ADD COLUMN "reviewed_at" timestamptz;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reviewed_by") REFERENCES "users" ("username");
+
+CREATE INDEX ON "transfers" ("id") WHERE "status" = 'pending_approval';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "entries_account_id_created_at_idx";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "transfer_id";
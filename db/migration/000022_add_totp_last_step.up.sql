@@ -0,0 +1 @@
+ALTER TABLE "totp_secrets" ADD COLUMN "last_step" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE "entries" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'transfer';
+
+CREATE INDEX ON "entries" ("transfer_id");
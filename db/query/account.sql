@@ -38,4 +38,16 @@ RETURNING *;
 
 -- name: DeleteAccount :exec 
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListAllAccounts :many
+SELECT * FROM accounts
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: AddAccountHeldBalance :one
+UPDATE accounts set
+held_balance = held_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
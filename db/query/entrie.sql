@@ -1,8 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
-) VALUES ($1, $2) RETURNING *;
+    amount,
+    currency,
+    transfer_id,
+    kind
+) VALUES ($1, $2, $3, $4, $5) RETURNING *;
 
 -- name: GetEntry :one
 SELECT * FROM entries
@@ -13,6 +16,72 @@ SELECT * FROM entries
 ORDER BY id
 LIMIT $1 OFFSET $2;
 
+-- name: ListEntriesByAccount :many
+SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    e.currency,
+    e.transfer_id,
+    (sqlc.arg(opening_balance)::bigint + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS balance_after,
+    CASE
+        -- the fee entries of a transfer are paired with each other, not with the transfer legs
+        WHEN e.kind = 'fee' THEN (
+            SELECT f.account_id FROM entries f
+            WHERE f.transfer_id = e.transfer_id AND f.kind = 'fee' AND f.id <> e.id
+            LIMIT 1
+        )
+        WHEN t.from_account_id = e.account_id THEN t.to_account_id
+        ELSE t.from_account_id
+    END AS counterparty_account_id
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+WHERE e.account_id = sqlc.arg(account_id)
+AND e.created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+AND e.created_at < COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+ORDER BY e.id
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: ListAccountStatementEntries :many
+SELECT
+    e.id,
+    e.amount,
+    e.created_at,
+    e.transfer_id,
+    CASE
+        -- the fee entries of a transfer are paired with each other, not with the transfer legs
+        WHEN e.kind = 'fee' THEN (
+            SELECT f.account_id FROM entries f
+            WHERE f.transfer_id = e.transfer_id AND f.kind = 'fee' AND f.id <> e.id
+            LIMIT 1
+        )
+        WHEN t.from_account_id = e.account_id THEN t.to_account_id
+        ELSE t.from_account_id
+    END AS counterparty_account_id
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+WHERE e.account_id = sqlc.arg(account_id)
+AND e.id > sqlc.arg(after_id)
+AND e.created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+AND e.created_at < COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+ORDER BY e.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetAccountPeriodBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE e.created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+    ), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE e.created_at >= COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+    ), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id;
+
 -- name: UpdateEntry :one
 UPDATE entries set
 amount = $2
@@ -0,0 +1,19 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    username,
+    idempotency_key,
+    request_hash
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (username, idempotency_key) DO NOTHING
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 LIMIT 1;
+
+-- name: SetIdempotencyKeyResponse :exec
+UPDATE idempotency_keys SET
+response = $3
+WHERE username = $1 AND idempotency_key = $2;
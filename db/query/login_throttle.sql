@@ -0,0 +1,30 @@
+-- name: GetLoginLockout :one
+SELECT COALESCE(MAX(locked_until), '0001-01-01 00:00:00Z')::timestamptz AS locked_until
+FROM login_throttles
+WHERE (kind = 'username' AND subject = sqlc.arg(username))
+    OR (kind = 'ip' AND subject = sqlc.arg(client_ip));
+
+-- name: RecordFailedLogin :one
+INSERT INTO login_throttles (
+    kind,
+    subject,
+    failed_attempts,
+    last_failed_at
+) VALUES (
+    sqlc.arg(kind), sqlc.arg(subject), 1, now()
+) ON CONFLICT (kind, subject) DO UPDATE SET
+failed_attempts = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+    ELSE login_throttles.failed_attempts + 1
+END,
+last_failed_at = now()
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles SET
+locked_until = $3
+WHERE kind = $1 AND subject = $2;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE kind = $1 AND subject = $2;
@@ -0,0 +1,48 @@
+-- name: UpsertTOTPSecret :one
+INSERT INTO totp_secrets (
+    username,
+    secret
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE SET
+secret = EXCLUDED.secret,
+created_at = now()
+WHERE totp_secrets.confirmed = false
+RETURNING *;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmTOTPSecret :one
+UPDATE totp_secrets SET
+confirmed = true
+WHERE username = $1
+RETURNING *;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE username = $1 AND used_at IS NULL
+ORDER BY id;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET
+used_at = now()
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_secrets SET
+last_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_step < sqlc.arg(step);
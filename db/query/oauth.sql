@@ -0,0 +1,36 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+    id,
+    owner,
+    name,
+    hashed_secret,
+    is_confidential,
+    redirect_uris,
+    scopes
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+    code_hash,
+    client_id,
+    username,
+    redirect_uri,
+    scopes,
+    code_challenge,
+    code_challenge_method,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes SET
+used_at = now()
+WHERE code_hash = $1 AND used_at IS NULL
+RETURNING *;
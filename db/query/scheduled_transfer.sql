@@ -0,0 +1,85 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    schedule,
+    start_at,
+    end_at,
+    max_runs,
+    next_run_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetScheduledTransferForRun :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListDueScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND COALESCE(retry_at, next_run_at) <= now()
+ORDER BY COALESCE(retry_at, next_run_at)
+LIMIT $1;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers SET
+amount = $2,
+schedule = $3,
+end_at = $4,
+max_runs = $5,
+status = $6,
+next_run_at = $7,
+attempts = 0,
+retry_at = NULL,
+updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateScheduledTransferProgress :one
+UPDATE scheduled_transfers SET
+run_count = $2,
+status = $3,
+next_run_at = $4,
+attempts = $5,
+retry_at = $6,
+updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteScheduledTransfer :execrows
+DELETE FROM scheduled_transfers
+WHERE id = $1 AND owner = $2;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id,
+    scheduled_for,
+    attempt,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -2,8 +2,14 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
-)VALUES ( $1, $2, $3 ) RETURNING *;
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    fx_quote_id,
+    fee
+)VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers 
@@ -14,3 +20,127 @@ SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
+
+-- name: CreateTransferHold :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    status,
+    authorized_amount,
+    expires_at
+) VALUES ( $1, $2, $3, $4, $3, $4, 1, 'pending', $3, $5 ) RETURNING *;
+
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: CaptureTransfer :one
+UPDATE transfers SET
+status = 'captured',
+amount = sqlc.arg(amount),
+to_amount = sqlc.arg(amount),
+fee = sqlc.arg(fee)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetTransferStatus :one
+UPDATE transfers SET
+status = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ListExpiredTransferHolds :many
+SELECT id FROM transfers
+WHERE status = 'pending' AND expires_at <= now()
+ORDER BY expires_at
+LIMIT $1;
+
+-- name: CreateTransferReversal :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    reversal_of
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8 ) RETURNING *;
+
+-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(to_amount), 0)::bigint AS reversed_amount
+FROM transfers
+WHERE reversal_of = $1;
+
+-- name: CreatePendingTransfer :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    fx_quote_id,
+    status
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, 'pending_approval' ) RETURNING *;
+
+-- name: ListPendingApprovalTransfers :many
+SELECT * FROM transfers
+WHERE status = 'pending_approval'
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: ApproveTransfer :one
+UPDATE transfers SET
+status = 'posted',
+fee = $2,
+reviewed_by = $3,
+reviewed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RejectTransfer :one
+UPDATE transfers SET
+status = 'rejected',
+reviewed_by = $2,
+reviewed_at = now()
+WHERE id = $1 AND status = 'pending_approval'
+RETURNING *;
+
+-- name: ListOutgoingTransfers :many
+SELECT * FROM transfers
+WHERE from_account_id = sqlc.arg(account_id)
+AND created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+AND created_at < COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+AND amount >= sqlc.arg(min_amount)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: ListIncomingTransfers :many
+SELECT * FROM transfers
+WHERE to_account_id = sqlc.arg(account_id)
+AND created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+AND created_at < COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+AND to_amount >= sqlc.arg(min_amount)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: ListAccountTransfers :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+AND created_at >= COALESCE(sqlc.narg(from_time), '-infinity'::timestamptz)
+AND created_at < COALESCE(sqlc.narg(to_time), 'infinity'::timestamptz)
+AND CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.arg(min_amount)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
@@ -0,0 +1,34 @@
+-- name: CreateTransferBatch :one
+INSERT INTO transfer_batches (
+    owner,
+    from_account_id,
+    currency,
+    mode,
+    status,
+    leg_count,
+    succeeded_count
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetTransferBatch :one
+SELECT * FROM transfer_batches
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateTransferBatchItem :one
+INSERT INTO transfer_batch_items (
+    batch_id,
+    leg,
+    to_account_id,
+    amount,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListTransferBatchItems :many
+SELECT * FROM transfer_batch_items
+WHERE batch_id = $1
+ORDER BY leg;
@@ -0,0 +1,62 @@
+-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+    scope,
+    subject,
+    max_amount,
+    daily_amount,
+    monthly_amount,
+    hourly_count
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (scope, subject) DO UPDATE SET
+max_amount = EXCLUDED.max_amount,
+daily_amount = EXCLUDED.daily_amount,
+monthly_amount = EXCLUDED.monthly_amount,
+hourly_count = EXCLUDED.hourly_count,
+updated_at = now()
+RETURNING *;
+
+-- name: GetTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE scope = $1 AND subject = $2 LIMIT 1;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+ORDER BY scope, subject
+LIMIT $1
+OFFSET $2;
+
+-- name: ListApplicableTransferLimits :many
+SELECT * FROM transfer_limits
+WHERE (scope = 'account' AND subject = sqlc.arg(account_id)::text)
+OR (scope = 'user' AND subject = sqlc.arg(owner))
+OR (scope = 'currency' AND subject = sqlc.arg(currency));
+
+-- name: DeleteTransferLimit :execrows
+DELETE FROM transfer_limits
+WHERE scope = $1 AND subject = $2;
+
+-- name: GetOutgoingTransferTotals :one
+SELECT
+    COALESCE(SUM(amount) FILTER (WHERE created_at >= sqlc.arg(day_start)), 0)::bigint AS daily_amount,
+    COALESCE(SUM(amount) FILTER (WHERE created_at >= sqlc.arg(month_start)), 0)::bigint AS monthly_amount,
+    COUNT(*) FILTER (WHERE created_at >= sqlc.arg(hour_start)) AS hourly_count
+FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+AND created_at >= LEAST(sqlc.arg(day_start), sqlc.arg(month_start), sqlc.arg(hour_start))
+AND reversal_of IS NULL
+AND status NOT IN ('voided', 'expired', 'pending_approval', 'rejected');
+
+-- name: GetOwnerOutgoingTransferTotals :one
+SELECT
+    COALESCE(SUM(t.amount) FILTER (WHERE t.created_at >= sqlc.arg(day_start)), 0)::bigint AS daily_amount,
+    COALESCE(SUM(t.amount) FILTER (WHERE t.created_at >= sqlc.arg(month_start)), 0)::bigint AS monthly_amount,
+    COUNT(*) FILTER (WHERE t.created_at >= sqlc.arg(hour_start)) AS hourly_count
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+WHERE a.owner = sqlc.arg(owner)
+AND t.currency = sqlc.arg(currency)
+AND t.created_at >= LEAST(sqlc.arg(day_start), sqlc.arg(month_start), sqlc.arg(hour_start))
+AND t.reversal_of IS NULL
+AND t.status NOT IN ('voided', 'expired', 'pending_approval', 'rejected');
@@ -0,0 +1,19 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+    username,
+    email,
+    secret_code_hash
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateVerifyEmail :one
+UPDATE verify_emails SET
+is_used = true
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) ApiKey {
+	arg := CreateAPIKeyParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		Name:      utils.RandomOwner(),
+		Prefix:    utils.RandomString(12),
+		HashedKey: utils.RandomString(64),
+		Scopes:    []string{utils.AccountsReadScope, utils.TransfersReadScope},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	key, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.ID, key.ID)
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.Name, key.Name)
+	require.Equal(t, arg.Prefix, key.Prefix)
+	require.Equal(t, arg.HashedKey, key.HashedKey)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, key.ExpiresAt.Time, time.Second)
+	require.False(t, key.LastUsedAt.Valid)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	createRandomAPIKey(t, createRandomUser(t))
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	key1 := createRandomAPIKey(t, createRandomUser(t))
+
+	key2, err := testQueries.GetAPIKeyByPrefix(context.Background(), key1.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, key1.ID, key2.ID)
+	require.Equal(t, key1.HashedKey, key2.HashedKey)
+	require.Equal(t, key1.Scopes, key2.Scopes)
+
+	_, err = testQueries.GetAPIKeyByPrefix(context.Background(), utils.RandomString(12))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomAPIKey(t, user)
+	}
+	createRandomAPIKey(t, createRandomUser(t))
+
+	keys, err := testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	for _, key := range keys {
+		require.Equal(t, user.Username, key.Username)
+	}
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	key1 := createRandomAPIKey(t, createRandomUser(t))
+
+	err := testQueries.TouchAPIKey(context.Background(), key1.ID)
+	require.NoError(t, err)
+
+	key2, err := testQueries.GetAPIKeyByPrefix(context.Background(), key1.Prefix)
+	require.NoError(t, err)
+	require.True(t, key2.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), key2.LastUsedAt.Time, time.Minute)
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	key := createRandomAPIKey(t, createRandomUser(t))
+
+	// another user can't delete the key
+	deleted, err := testQueries.DeleteAPIKey(context.Background(), DeleteAPIKeyParams{
+		ID:       key.ID,
+		Username: utils.RandomOwner(),
+	})
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	deleted, err = testQueries.DeleteAPIKey(context.Background(), DeleteAPIKeyParams{
+		ID:       key.ID,
+		Username: key.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = testQueries.GetAPIKeyByPrefix(context.Background(), key.Prefix)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -7,28 +7,45 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
-) VALUES ($1, $2) RETURNING id, account_id, amount, created_at
+    amount,
+    currency,
+    transfer_id,
+    kind
+) VALUES ($1, $2, $3, $4, $5) RETURNING id, account_id, amount, created_at, currency, transfer_id, kind
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64         `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	Currency   string        `json:"currency"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Kind       string        `json:"kind"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.TransferID,
+		arg.Kind,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Currency,
+		&i.TransferID,
+		&i.Kind,
 	)
 	return i, err
 }
@@ -43,8 +60,40 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const getAccountPeriodBalances = `-- name: GetAccountPeriodBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE e.created_at >= COALESCE($1, '-infinity'::timestamptz)
+    ), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE e.created_at >= COALESCE($2, 'infinity'::timestamptz)
+    ), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $3
+GROUP BY a.id
+`
+
+type GetAccountPeriodBalancesParams struct {
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	AccountID int64        `json:"account_id"`
+}
+
+type GetAccountPeriodBalancesRow struct {
+	OpeningBalance int64 `json:"opening_balance"`
+	ClosingBalance int64 `json:"closing_balance"`
+}
+
+func (q *Queries) GetAccountPeriodBalances(ctx context.Context, arg GetAccountPeriodBalancesParams) (GetAccountPeriodBalancesRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountPeriodBalances, arg.FromTime, arg.ToTime, arg.AccountID)
+	var i GetAccountPeriodBalancesRow
+	err := row.Scan(&i.OpeningBalance, &i.ClosingBalance)
+	return i, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, currency, transfer_id, kind FROM entries
 WHERE id = $1
 `
 
@@ -56,12 +105,92 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Currency,
+		&i.TransferID,
+		&i.Kind,
 	)
 	return i, err
 }
 
+const listAccountStatementEntries = `-- name: ListAccountStatementEntries :many
+SELECT
+    e.id,
+    e.amount,
+    e.created_at,
+    e.transfer_id,
+    CASE
+        -- the fee entries of a transfer are paired with each other, not with the transfer legs
+        WHEN e.kind = 'fee' THEN (
+            SELECT f.account_id FROM entries f
+            WHERE f.transfer_id = e.transfer_id AND f.kind = 'fee' AND f.id <> e.id
+            LIMIT 1
+        )
+        WHEN t.from_account_id = e.account_id THEN t.to_account_id
+        ELSE t.from_account_id
+    END AS counterparty_account_id
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+WHERE e.account_id = $1
+AND e.id > $2
+AND e.created_at >= COALESCE($3, '-infinity'::timestamptz)
+AND e.created_at < COALESCE($4, 'infinity'::timestamptz)
+ORDER BY e.id
+LIMIT $5
+`
+
+type ListAccountStatementEntriesParams struct {
+	AccountID int64        `json:"account_id"`
+	AfterID   int64        `json:"after_id"`
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	RowLimit  int32        `json:"row_limit"`
+}
+
+type ListAccountStatementEntriesRow struct {
+	ID                    int64         `json:"id"`
+	Amount                int64         `json:"amount"`
+	CreatedAt             time.Time     `json:"created_at"`
+	TransferID            sql.NullInt64 `json:"transfer_id"`
+	CounterpartyAccountID sql.NullInt64 `json:"counterparty_account_id"`
+}
+
+func (q *Queries) ListAccountStatementEntries(ctx context.Context, arg ListAccountStatementEntriesParams) ([]ListAccountStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatementEntries,
+		arg.AccountID,
+		arg.AfterID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementEntriesRow{}
+	for rows.Next() {
+		var i ListAccountStatementEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.CounterpartyAccountID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, currency, transfer_id, kind FROM entries
 ORDER BY id
 LIMIT $1 OFFSET $2
 `
@@ -85,6 +214,97 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Currency,
+			&i.TransferID,
+			&i.Kind,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesByAccount = `-- name: ListEntriesByAccount :many
+SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    e.currency,
+    e.transfer_id,
+    ($1::bigint + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS balance_after,
+    CASE
+        -- the fee entries of a transfer are paired with each other, not with the transfer legs
+        WHEN e.kind = 'fee' THEN (
+            SELECT f.account_id FROM entries f
+            WHERE f.transfer_id = e.transfer_id AND f.kind = 'fee' AND f.id <> e.id
+            LIMIT 1
+        )
+        WHEN t.from_account_id = e.account_id THEN t.to_account_id
+        ELSE t.from_account_id
+    END AS counterparty_account_id
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+WHERE e.account_id = $2
+AND e.created_at >= COALESCE($3, '-infinity'::timestamptz)
+AND e.created_at < COALESCE($4, 'infinity'::timestamptz)
+ORDER BY e.id
+LIMIT $5
+OFFSET $6
+`
+
+type ListEntriesByAccountParams struct {
+	OpeningBalance int64        `json:"opening_balance"`
+	AccountID      int64        `json:"account_id"`
+	FromTime       sql.NullTime `json:"from_time"`
+	ToTime         sql.NullTime `json:"to_time"`
+	RowLimit       int32        `json:"row_limit"`
+	RowOffset      int32        `json:"row_offset"`
+}
+
+type ListEntriesByAccountRow struct {
+	ID                    int64         `json:"id"`
+	AccountID             int64         `json:"account_id"`
+	Amount                int64         `json:"amount"`
+	CreatedAt             time.Time     `json:"created_at"`
+	Currency              string        `json:"currency"`
+	TransferID            sql.NullInt64 `json:"transfer_id"`
+	BalanceAfter          int64         `json:"balance_after"`
+	CounterpartyAccountID sql.NullInt64 `json:"counterparty_account_id"`
+}
+
+func (q *Queries) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]ListEntriesByAccountRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesByAccount,
+		arg.OpeningBalance,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEntriesByAccountRow{}
+	for rows.Next() {
+		var i ListEntriesByAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+			&i.TransferID,
+			&i.BalanceAfter,
+			&i.CounterpartyAccountID,
 		); err != nil {
 			return nil, err
 		}
@@ -102,7 +322,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 const updateEntry = `-- name: UpdateEntry :one
 UPDATE entries set
 amount = $2
-WHERE id = $1 RETURNING id, account_id, amount, created_at
+WHERE id = $1 RETURNING id, account_id, amount, created_at, currency, transfer_id, kind
 `
 
 type UpdateEntryParams struct {
@@ -118,6 +338,9 @@ func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Currency,
+		&i.TransferID,
+		&i.Kind,
 	)
 	return i, err
 }
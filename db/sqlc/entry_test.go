@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -15,6 +17,8 @@ func createRandomEntry(t *testing.T) Entry {
 	arg := CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    utils.RandomMoney(),
+		Currency:  account.Currency,
+		Kind:      EntryKindTransfer,
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -24,6 +28,7 @@ func createRandomEntry(t *testing.T) Entry {
 
 	require.Equal(t, entry.AccountID, arg.AccountID)
 	require.Equal(t, entry.Amount, arg.Amount)
+	require.Equal(t, entry.Currency, arg.Currency)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
@@ -80,3 +85,168 @@ func TestUpdateEntry(t *testing.T) {
 	require.NotEqual(t, entry1.Amount, entry2.Amount)
 	require.Equal(t, entry1.ID, entry2.ID)
 }
+
+func TestAccountStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	transfer := func(from Account, to Account, amount int64) TransferTxResult {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountId: from.ID,
+			ToAccountId:   to.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result
+	}
+	first := transfer(account1, account2, 30)
+	transfer(account2, account1, 10)
+	last := transfer(account1, account2, 5)
+
+	statement, err := store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), statement.OpeningBalance)
+	require.Equal(t, int64(75), statement.ClosingBalance)
+	require.Len(t, statement.Entries, 3)
+
+	balances := []int64{70, 80, 75}
+	for i, entry := range statement.Entries {
+		require.Equal(t, account1.ID, entry.AccountID)
+		require.Equal(t, balances[i], entry.BalanceAfter)
+		require.Equal(t, account2.ID, entry.CounterpartyAccountID.Int64)
+	}
+	require.Equal(t, first.Transfer.ID, statement.Entries[0].TransferID.Int64)
+
+	// a later page carries on from the entries of the period it skipped
+	statement, err = store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account1.ID,
+		Limit:     1,
+		Offset:    1,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 1)
+	require.Equal(t, int64(80), statement.Entries[0].BalanceAfter)
+
+	// a period starting with the last transfer opens with the balance the first two left
+	statement, err = store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account1.ID,
+		From:      sql.NullTime{Time: last.FromEntry.CreatedAt, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(80), statement.OpeningBalance)
+	require.Equal(t, int64(75), statement.ClosingBalance)
+	require.Len(t, statement.Entries, 1)
+	require.Equal(t, last.FromEntry.ID, statement.Entries[0].ID)
+	require.Equal(t, int64(75), statement.Entries[0].BalanceAfter)
+
+	// a period ending before the first transfer has no entries
+	statement, err = store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account1.ID,
+		To:        sql.NullTime{Time: first.FromEntry.CreatedAt, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), statement.OpeningBalance)
+	require.Equal(t, int64(100), statement.ClosingBalance)
+	require.Empty(t, statement.Entries)
+}
+
+func TestAccountStatementTxFee(t *testing.T) {
+	revenueAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	store := NewStore(testDB, WithFeeSchedule(flatFeeSchedule{fee: 5, revenueAccountID: revenueAccount.ID}))
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, EntryKindTransfer, result.FromEntry.Kind)
+	require.Equal(t, EntryKindFee, result.Fee.FromEntry.Kind)
+	require.Equal(t, EntryKindFee, result.Fee.RevenueEntry.Kind)
+
+	// the fee is paid to the revenue account, not to the payee of the transfer
+	statement, err := store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 2)
+	require.Equal(t, int64(-50), statement.Entries[0].Amount)
+	require.Equal(t, account2.ID, statement.Entries[0].CounterpartyAccountID.Int64)
+	require.Equal(t, int64(-5), statement.Entries[1].Amount)
+	require.Equal(t, revenueAccount.ID, statement.Entries[1].CounterpartyAccountID.Int64)
+	require.Equal(t, int64(45), statement.Entries[1].BalanceAfter)
+
+	statement, err = store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: revenueAccount.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 1)
+	require.Equal(t, int64(5), statement.Entries[0].Amount)
+	require.Equal(t, account1.ID, statement.Entries[0].CounterpartyAccountID.Int64)
+
+	exported, err := testQueries.ListAccountStatementEntries(context.Background(), ListAccountStatementEntriesParams{
+		AccountID: account1.ID,
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	require.Equal(t, revenueAccount.ID, exported[1].CounterpartyAccountID.Int64)
+}
+
+func TestExportAccountStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	for _, amount := range []int64{30, 20, 5} {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountId: account1.ID,
+			ToAccountId:   account2.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+	}
+
+	var opening, closing int64
+	var balances []int64
+	err := store.ExportAccountStatementTx(context.Background(), ExportAccountStatementTxParams{
+		AccountID: account1.ID,
+		OnBalances: func(openingBalance int64, closingBalance int64) error {
+			opening, closing = openingBalance, closingBalance
+			return nil
+		},
+		OnEntry: func(entry ListAccountStatementEntriesRow, balanceAfter int64) error {
+			require.Equal(t, account2.ID, entry.CounterpartyAccountID.Int64)
+			balances = append(balances, balanceAfter)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), opening)
+	require.Equal(t, int64(45), closing)
+	require.Equal(t, []int64{70, 50, 45}, balances)
+
+	// an error from a callback stops the export
+	stop := errors.New("stop")
+	calls := 0
+	err = store.ExportAccountStatementTx(context.Background(), ExportAccountStatementTxParams{
+		AccountID:  account1.ID,
+		OnBalances: func(int64, int64) error { return nil },
+		OnEntry: func(ListAccountStatementEntriesRow, int64) error {
+			calls++
+			return stop
+		},
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 1, calls)
+}
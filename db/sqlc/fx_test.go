@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomFXQuote(t *testing.T, user User, fromCurrency string, toCurrency string, rate string, duration time.Duration) FxQuote {
+	arg := CreateFXQuoteParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         rate,
+		ExpiresAt:    time.Now().Add(duration),
+	}
+
+	quote, err := testQueries.CreateFXQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FromCurrency, quote.FromCurrency)
+	require.Equal(t, arg.ToCurrency, quote.ToCurrency)
+	require.False(t, quote.UsedAt.Valid)
+	require.WithinDuration(t, arg.ExpiresAt, quote.ExpiresAt, time.Second)
+	return quote
+}
+
+func TestUpsertFXRate(t *testing.T) {
+	from := utils.RandomString(3)
+	to := utils.RandomString(3)
+
+	rate, err := testQueries.UpsertFXRate(context.Background(), UpsertFXRateParams{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         "0.92",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0.92", rate.Rate)
+
+	rate, err = testQueries.UpsertFXRate(context.Background(), UpsertFXRateParams{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         "0.93",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0.93", rate.Rate)
+
+	got, err := testQueries.GetFXRate(context.Background(), GetFXRateParams{FromCurrency: from, ToCurrency: to})
+	require.NoError(t, err)
+	require.Equal(t, rate, got)
+
+	_, err = testQueries.GetFXRate(context.Background(), GetFXRateParams{FromCurrency: to, ToCurrency: from})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseFXQuote(t *testing.T) {
+	user := createRandomUser(t)
+	quote := createRandomFXQuote(t, user, "USD", "EUR", "0.92", time.Minute)
+
+	used, err := testQueries.UseFXQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	// a quote only locks its rate for one transfer
+	_, err = testQueries.UseFXQuote(context.Background(), quote.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	expired := createRandomFXQuote(t, user, "USD", "EUR", "0.92", -time.Second)
+	_, err = testQueries.UseFXQuote(context.Background(), expired.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+	quote := createRandomFXQuote(t, user, "USD", "EUR", "0.92", time.Minute)
+
+	arg := TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        100,
+		FXQuoteID:     uuid.NullUUID{UUID: quote.ID, Valid: true},
+	}
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, int64(100), transfer.Amount)
+	require.Equal(t, "USD", transfer.Currency)
+	require.Equal(t, int64(92), transfer.ToAmount)
+	require.Equal(t, "EUR", transfer.ToCurrency)
+	require.Equal(t, quote.Rate, transfer.ExchangeRate)
+	require.Equal(t, arg.FXQuoteID, transfer.FxQuoteID)
+
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, "USD", result.FromEntry.Currency)
+	require.Equal(t, int64(92), result.ToEntry.Amount)
+	require.Equal(t, "EUR", result.ToEntry.Currency)
+
+	require.Equal(t, int64(900), result.FromAccount.Balance)
+	require.Equal(t, int64(92), result.ToAccount.Balance)
+
+	// the quote is used up
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrFXQuoteInvalid)
+}
+
+func TestTransferTxCrossCurrencyRejected(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        100,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	testCases := []struct {
+		name  string
+		quote FxQuote
+		err   error
+	}{
+		{
+			name:  "OtherUser",
+			quote: createRandomFXQuote(t, createRandomUser(t), "USD", "EUR", "0.92", time.Minute),
+			err:   ErrFXQuoteInvalid,
+		},
+		{
+			name:  "OtherCurrencies",
+			quote: createRandomFXQuote(t, user, "USD", "CAD", "1.36", time.Minute),
+			err:   ErrFXQuoteInvalid,
+		},
+		{
+			name:  "Expired",
+			quote: createRandomFXQuote(t, user, "USD", "EUR", "0.92", -time.Second),
+			err:   ErrFXQuoteInvalid,
+		},
+		{
+			name:  "AmountTooSmall",
+			quote: createRandomFXQuote(t, user, "USD", "EUR", "0.001", time.Minute),
+			err:   ErrAmountTooSmall,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        100,
+				FXQuoteID:     uuid.NullUUID{UUID: tc.quote.ID, Valid: true},
+			})
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	// nothing moved and the rejected quotes weren't used up
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), updatedAccount1.Balance)
+
+	quote, err := store.GetFXQuote(context.Background(), testCases[0].quote.ID)
+	require.NoError(t, err)
+	require.False(t, quote.UsedAt.Valid)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key comes back with a different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// IdempotencyParams identifies a request that must take effect at most once,
+// RequestHash tells a retry apart from a different request reusing the key
+type IdempotencyParams struct {
+	Username    string
+	Key         string
+	RequestHash string
+}
+
+// execIdempotentTx runs fn within a database transaction that also records the idempotency key
+// and result serialized as the response. When the key was already used for the same request fn
+// doesn't run again and result is filled with the stored response instead, reported by replayed.
+// A nil idempotency runs fn like execTx
+func (store *SQLStore) execIdempotentTx(ctx context.Context, idempotency *IdempotencyParams, result interface{}, fn func(*Queries) error) (replayed bool, err error) {
+	if idempotency == nil {
+		return false, store.execTx(ctx, fn)
+	}
+
+	err = store.execTx(ctx, func(q *Queries) error {
+		// a concurrent request holding the same key makes the insert wait until it commits or rolls back
+		_, err := q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:       idempotency.Username,
+			IdempotencyKey: idempotency.Key,
+			RequestHash:    idempotency.RequestHash,
+		})
+		if err == sql.ErrNoRows {
+			stored, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+				Username:       idempotency.Username,
+				IdempotencyKey: idempotency.Key,
+			})
+			if err != nil {
+				return err
+			}
+			if stored.RequestHash != idempotency.RequestHash {
+				return ErrIdempotencyKeyReused
+			}
+			replayed = true
+			return json.Unmarshal(stored.Response, result)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(q)
+		if err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return q.SetIdempotencyKeyResponse(ctx, SetIdempotencyKeyResponseParams{
+			Username:       idempotency.Username,
+			IdempotencyKey: idempotency.Key,
+			Response:       response,
+		})
+	})
+
+	return replayed, err
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func randomIdempotency(user User) *IdempotencyParams {
+	return &IdempotencyParams{
+		Username:    user.Username,
+		Key:         utils.RandomString(32),
+		RequestHash: utils.RandomString(64),
+	}
+}
+
+func TestTransferTxIdempotent(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	arg := TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+		Idempotency:   randomIdempotency(user),
+	}
+
+	first, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, first.Replayed)
+
+	// the retry gets the first response back without moving money again
+	second, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, second.Replayed)
+	require.Equal(t, first.Transfer.ID, second.Transfer.ID)
+	require.Equal(t, first.FromAccount.Balance, second.FromAccount.Balance)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), updatedAccount1.Balance)
+
+	// the same key with another request is refused
+	arg.Amount = 20
+	arg.Idempotency = &IdempotencyParams{
+		Username:    user.Username,
+		Key:         arg.Idempotency.Key,
+		RequestHash: utils.RandomString(64),
+	}
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// keys belong to a user, another one can use the same key
+	other := createRandomUser(t)
+	arg.Idempotency = &IdempotencyParams{
+		Username:    other.Username,
+		Key:         arg.Idempotency.Key,
+		RequestHash: utils.RandomString(64),
+	}
+	third, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, third.Replayed)
+}
+
+func TestTransferTxIdempotentConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	arg := TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+		Idempotency:   randomIdempotency(user),
+	}
+
+	// retries racing each other all get the same transfer
+	n := 5
+	results := make(chan TransferTxResult)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.TransferTx(context.Background(), arg)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	replayed := 0
+	var transferID int64
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		result := <-results
+		if result.Replayed {
+			replayed++
+		}
+		if transferID == 0 {
+			transferID = result.Transfer.ID
+		}
+		require.Equal(t, transferID, result.Transfer.ID)
+	}
+	require.Equal(t, n-1, replayed)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), updatedAccount1.Balance)
+}
+
+func TestCreateAccountTxIdempotent(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  0,
+			Currency: utils.RandomCurrency(),
+		},
+		Idempotency: randomIdempotency(user),
+	}
+
+	first, err := store.CreateAccountTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, first.Replayed)
+	require.Equal(t, user.Username, first.Account.Owner)
+
+	second, err := store.CreateAccountTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, second.Replayed)
+	require.Equal(t, first.Account.ID, second.Account.ID)
+
+	// without a key every call opens an account
+	arg.Idempotency = nil
+	third, err := store.CreateAccountTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Account.ID, third.Account.ID)
+}
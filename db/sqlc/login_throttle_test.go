@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordFailedLogin(t *testing.T) {
+	username := utils.RandomOwner()
+	arg := RecordFailedLoginParams{
+		Kind:        "username",
+		Subject:     username,
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	for i := 1; i <= 3; i++ {
+		throttle, err := testQueries.RecordFailedLogin(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, arg.Kind, throttle.Kind)
+		require.Equal(t, arg.Subject, throttle.Subject)
+		require.Equal(t, int32(i), throttle.FailedAttempts)
+		require.WithinDuration(t, time.Now(), throttle.LastFailedAt, time.Second)
+	}
+
+	// failures older than the window don't count anymore
+	arg.ResetBefore = time.Now().Add(time.Minute)
+	throttle, err := testQueries.RecordFailedLogin(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+}
+
+func TestGetLoginLockout(t *testing.T) {
+	username := utils.RandomOwner()
+	clientIP := utils.RandomString(12)
+
+	lockedUntil, err := testQueries.GetLoginLockout(context.Background(), GetLoginLockoutParams{
+		Username: username,
+		ClientIp: clientIP,
+	})
+	require.NoError(t, err)
+	require.True(t, lockedUntil.Before(time.Now()))
+
+	for _, kind := range []string{"username", "ip"} {
+		subject := username
+		if kind == "ip" {
+			subject = clientIP
+		}
+		_, err := testQueries.RecordFailedLogin(context.Background(), RecordFailedLoginParams{
+			Kind:        kind,
+			Subject:     subject,
+			ResetBefore: time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	ipLock := time.Now().Add(time.Hour)
+	err = testQueries.LockLogin(context.Background(), LockLoginParams{Kind: "ip", Subject: clientIP, LockedUntil: ipLock})
+	require.NoError(t, err)
+	err = testQueries.LockLogin(context.Background(), LockLoginParams{Kind: "username", Subject: username, LockedUntil: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+
+	// the longest lock wins
+	lockedUntil, err = testQueries.GetLoginLockout(context.Background(), GetLoginLockoutParams{
+		Username: username,
+		ClientIp: clientIP,
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, ipLock, lockedUntil, time.Second)
+
+	err = testQueries.DeleteLoginThrottle(context.Background(), DeleteLoginThrottleParams{Kind: "ip", Subject: clientIP})
+	require.NoError(t, err)
+
+	lockedUntil, err = testQueries.GetLoginLockout(context.Background(), GetLoginLockoutParams{
+		Username: username,
+		ClientIp: clientIP,
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Minute), lockedUntil, time.Second)
+}
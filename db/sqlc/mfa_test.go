@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func enrollRandomTOTP(t *testing.T, store Store, user User) EnrollTOTPTxResult {
+	arg := EnrollTOTPTxParams{
+		Username:            user.Username,
+		Secret:              utils.RandomString(32),
+		HashedRecoveryCodes: []string{utils.RandomString(20), utils.RandomString(20)},
+	}
+
+	result, err := store.EnrollTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, result.TotpSecret.Username)
+	require.Equal(t, arg.Secret, result.TotpSecret.Secret)
+	require.False(t, result.TotpSecret.Confirmed)
+	require.Len(t, result.RecoveryCodes, len(arg.HashedRecoveryCodes))
+	for i, code := range result.RecoveryCodes {
+		require.Equal(t, arg.HashedRecoveryCodes[i], code.HashedCode)
+		require.False(t, code.UsedAt.Valid)
+	}
+
+	return result
+}
+
+func TestEnrollTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	first := enrollRandomTOTP(t, store, user)
+
+	// enrolling again before confirming replaces the secret and the recovery codes
+	second := enrollRandomTOTP(t, store, user)
+	require.NotEqual(t, first.TotpSecret.Secret, second.TotpSecret.Secret)
+
+	codes, err := testQueries.ListUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, codes, len(second.RecoveryCodes))
+
+	confirmed, err := testQueries.ConfirmTOTPSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.True(t, confirmed.Confirmed)
+
+	// once confirmed the secret can't be swapped
+	_, err = store.EnrollTOTPTx(context.Background(), EnrollTOTPTxParams{
+		Username: user.Username,
+		Secret:   utils.RandomString(32),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	secret, err := testQueries.GetTOTPSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, second.TotpSecret.Secret, secret.Secret)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	result := enrollRandomTOTP(t, NewStore(testDB), user)
+	code := result.RecoveryCodes[0]
+
+	rows, err := testQueries.UseRecoveryCode(context.Background(), code.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// a code can only be used once
+	rows, err = testQueries.UseRecoveryCode(context.Background(), code.ID)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	codes, err := testQueries.ListUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, codes, len(result.RecoveryCodes)-1)
+	for _, c := range codes {
+		require.NotEqual(t, code.ID, c.ID)
+	}
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	enrollRandomTOTP(t, store, user)
+
+	useStep := func(step int64) int64 {
+		rows, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+			Step:     step,
+			Username: user.Username,
+		})
+		require.NoError(t, err)
+		return rows
+	}
+
+	require.Equal(t, int64(1), useStep(100))
+
+	// the same step or an earlier one can't be used again
+	require.Zero(t, useStep(100))
+	require.Zero(t, useStep(99))
+	require.Equal(t, int64(1), useStep(101))
+
+	totp, err := testQueries.GetTOTPSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(101), totp.LastStep)
+}
@@ -5,30 +5,229 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int64     `json:"id"`
+	Owner       string    `json:"owner"`
+	Balance     int64     `json:"balance"`
+	Currency    string    `json:"currency"`
+	CreatedAt   time.Time `json:"created_at"`
+	HeldBalance int64     `json:"held_balance"`
+}
+
+type ApiKey struct {
+	ID         uuid.UUID    `json:"id"`
+	Username   string       `json:"username"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	HashedKey  string       `json:"hashed_key"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
 }
 
 type Entry struct {
-	ID        int64     `json:"id"`
-	AccountID int64     `json:"account_id"`
-	Amount    int64     `json:"amount"`
+	ID         int64         `json:"id"`
+	AccountID  int64         `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Currency   string        `json:"currency"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Kind       string        `json:"kind"`
+}
+
+type FxQuote struct {
+	ID           uuid.UUID    `json:"id"`
+	Username     string       `json:"username"`
+	FromCurrency string       `json:"from_currency"`
+	ToCurrency   string       `json:"to_currency"`
+	Rate         string       `json:"rate"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	UsedAt       sql.NullTime `json:"used_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type FxRate struct {
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type IdempotencyKey struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	RequestHash    string          `json:"request_hash"`
+	Response       json.RawMessage `json:"response"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Kind           string    `json:"kind"`
+	Subject        string    `json:"subject"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+	LastFailedAt   time.Time `json:"last_failed_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash            string       `json:"code_hash"`
+	ClientID            string       `json:"client_id"`
+	Username            string       `json:"username"`
+	RedirectUri         string       `json:"redirect_uri"`
+	Scopes              []string     `json:"scopes"`
+	CodeChallenge       string       `json:"code_challenge"`
+	CodeChallengeMethod string       `json:"code_challenge_method"`
+	ExpiresAt           time.Time    `json:"expires_at"`
+	UsedAt              sql.NullTime `json:"used_at"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID             string    `json:"id"`
+	Owner          string    `json:"owner"`
+	Name           string    `json:"name"`
+	HashedSecret   string    `json:"hashed_secret"`
+	IsConfidential bool      `json:"is_confidential"`
+	RedirectUris   []string  `json:"redirect_uris"`
+	Scopes         []string  `json:"scopes"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int64        `json:"id"`
+	Username  string       `json:"username"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64        `json:"id"`
+	Username   string       `json:"username"`
+	HashedCode string       `json:"hashed_code"`
+	UsedAt     sql.NullTime `json:"used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64         `json:"id"`
+	Owner         string        `json:"owner"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Currency      string        `json:"currency"`
+	Schedule      string        `json:"schedule"`
+	StartAt       time.Time     `json:"start_at"`
+	EndAt         sql.NullTime  `json:"end_at"`
+	MaxRuns       sql.NullInt64 `json:"max_runs"`
+	RunCount      int64         `json:"run_count"`
+	Status        string        `json:"status"`
+	NextRunAt     sql.NullTime  `json:"next_run_at"`
+	Attempts      int64         `json:"attempts"`
+	RetryAt       sql.NullTime  `json:"retry_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64         `json:"id"`
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time     `json:"scheduled_for"`
+	Attempt             int64         `json:"attempt"`
+	Status              string        `json:"status"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	Error               string        `json:"error"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type TotpSecret struct {
+	Username  string    `json:"username"`
+	Secret    string    `json:"secret"`
+	Confirmed bool      `json:"confirmed"`
 	CreatedAt time.Time `json:"created_at"`
+	LastStep  int64     `json:"last_step"`
 }
 
 type Transfer struct {
-	ID            int64     `json:"id"`
-	FromAccountID int64     `json:"from_account_id"`
-	ToAccountID   int64     `json:"to_account_id"`
-	Amount        int64     `json:"amount"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID               int64          `json:"id"`
+	FromAccountID    int64          `json:"from_account_id"`
+	ToAccountID      int64          `json:"to_account_id"`
+	Amount           int64          `json:"amount"`
+	CreatedAt        time.Time      `json:"created_at"`
+	Currency         string         `json:"currency"`
+	ToAmount         int64          `json:"to_amount"`
+	ToCurrency       string         `json:"to_currency"`
+	ExchangeRate     string         `json:"exchange_rate"`
+	FxQuoteID        uuid.NullUUID  `json:"fx_quote_id"`
+	Status           string         `json:"status"`
+	AuthorizedAmount int64          `json:"authorized_amount"`
+	ExpiresAt        sql.NullTime   `json:"expires_at"`
+	ReversalOf       sql.NullInt64  `json:"reversal_of"`
+	Fee              int64          `json:"fee"`
+	ReviewedBy       sql.NullString `json:"reviewed_by"`
+	ReviewedAt       sql.NullTime   `json:"reviewed_at"`
+}
+
+type TransferBatch struct {
+	ID             int64     `json:"id"`
+	Owner          string    `json:"owner"`
+	FromAccountID  int64     `json:"from_account_id"`
+	Currency       string    `json:"currency"`
+	Mode           string    `json:"mode"`
+	Status         string    `json:"status"`
+	LegCount       int64     `json:"leg_count"`
+	SucceededCount int64     `json:"succeeded_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type TransferBatchItem struct {
+	ID          int64         `json:"id"`
+	BatchID     int64         `json:"batch_id"`
+	Leg         int32         `json:"leg"`
+	ToAccountID int64         `json:"to_account_id"`
+	Amount      int64         `json:"amount"`
+	Status      string        `json:"status"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	Error       string        `json:"error"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type TransferLimit struct {
+	ID            int64         `json:"id"`
+	Scope         string        `json:"scope"`
+	Subject       string        `json:"subject"`
+	MaxAmount     sql.NullInt64 `json:"max_amount"`
+	DailyAmount   sql.NullInt64 `json:"daily_amount"`
+	MonthlyAmount sql.NullInt64 `json:"monthly_amount"`
+	HourlyCount   sql.NullInt64 `json:"hourly_count"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 type User struct {
@@ -38,4 +237,21 @@ type User struct {
 	Email           string    `json:"email"`
 	PasswordChanged time.Time `json:"password_changed"`
 	CreatedAt       time.Time `json:"created_at"`
+	Role            string    `json:"role"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+}
+
+type UserTokenRevocation struct {
+	Username      string    `json:"username"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+type VerifyEmail struct {
+	ID             int64     `json:"id"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	SecretCodeHash string    `json:"secret_code_hash"`
+	IsUsed         bool      `json:"is_used"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiredAt      time.Time `json:"expired_at"`
 }
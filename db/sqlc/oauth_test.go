@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, owner User) OauthClient {
+	arg := CreateOAuthClientParams{
+		ID:             utils.RandomString(16),
+		Owner:          owner.Username,
+		Name:           utils.RandomOwner(),
+		HashedSecret:   utils.RandomString(64),
+		IsConfidential: true,
+		RedirectUris:   []string{"https://example.com/callback"},
+		Scopes:         []string{utils.AccountsReadScope},
+	}
+
+	client, err := testQueries.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.Owner, client.Owner)
+	require.Equal(t, arg.Name, client.Name)
+	require.Equal(t, arg.HashedSecret, client.HashedSecret)
+	require.Equal(t, arg.IsConfidential, client.IsConfidential)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.NotZero(t, client.CreatedAt)
+
+	return client
+}
+
+func createRandomOAuthAuthorizationCode(t *testing.T, client OauthClient, user User) OauthAuthorizationCode {
+	arg := CreateOAuthAuthorizationCodeParams{
+		CodeHash:            utils.RandomString(64),
+		ClientID:            client.ID,
+		Username:            user.Username,
+		RedirectUri:         client.RedirectUris[0],
+		Scopes:              client.Scopes,
+		CodeChallenge:       utils.RandomString(43),
+		CodeChallengeMethod: "S256",
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+
+	code, err := testQueries.CreateOAuthAuthorizationCode(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.CodeHash, code.CodeHash)
+	require.Equal(t, arg.ClientID, code.ClientID)
+	require.Equal(t, arg.Username, code.Username)
+	require.Equal(t, arg.RedirectUri, code.RedirectUri)
+	require.Equal(t, arg.Scopes, code.Scopes)
+	require.Equal(t, arg.CodeChallenge, code.CodeChallenge)
+	require.Equal(t, arg.CodeChallengeMethod, code.CodeChallengeMethod)
+	require.WithinDuration(t, arg.ExpiresAt, code.ExpiresAt, time.Second)
+	require.False(t, code.UsedAt.Valid)
+
+	return code
+}
+
+func TestGetOAuthClient(t *testing.T) {
+	client1 := createRandomOAuthClient(t, createRandomUser(t))
+
+	client2, err := testQueries.GetOAuthClient(context.Background(), client1.ID)
+	require.NoError(t, err)
+	require.Equal(t, client1.ID, client2.ID)
+	require.Equal(t, client1.RedirectUris, client2.RedirectUris)
+	require.WithinDuration(t, client1.CreatedAt, client2.CreatedAt, time.Second)
+
+	_, err = testQueries.GetOAuthClient(context.Background(), utils.RandomString(16))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseOAuthAuthorizationCode(t *testing.T) {
+	user := createRandomUser(t)
+	code1 := createRandomOAuthAuthorizationCode(t, createRandomOAuthClient(t, user), user)
+
+	code2, err := testQueries.UseOAuthAuthorizationCode(context.Background(), code1.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, code1.CodeHash, code2.CodeHash)
+	require.True(t, code2.UsedAt.Valid)
+
+	// codes are single use
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), code1.CodeHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
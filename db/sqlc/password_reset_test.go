@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordResetToken(t *testing.T, user User, expiresAt time.Time) (string, PasswordResetToken) {
+	token, err := utils.GenerateSecureToken(32)
+	require.NoError(t, err)
+
+	arg := CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: utils.HashSecureToken(token),
+		ExpiresAt: expiresAt,
+	}
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, resetToken.Username)
+	require.Equal(t, arg.TokenHash, resetToken.TokenHash)
+	require.WithinDuration(t, arg.ExpiresAt, resetToken.ExpiresAt, time.Second)
+	require.False(t, resetToken.UsedAt.Valid)
+	require.NotZero(t, resetToken.CreatedAt)
+
+	return token, resetToken
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	issuedAt := time.Now()
+	token, _ := createRandomPasswordResetToken(t, user, time.Now().Add(time.Hour))
+	otherToken, _ := createRandomPasswordResetToken(t, user, time.Now().Add(time.Hour))
+
+	hashedPassword, err := utils.HashPassword(utils.RandomString(8))
+	require.NoError(t, err)
+
+	result, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      utils.HashSecureToken(token),
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.True(t, result.User.PasswordChanged.After(user.PasswordChanged))
+
+	// sessions and tokens issued before the reset stop working with it
+	updatedSession, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, updatedSession.IsBlocked)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// the token can't be used twice, and the other pending tokens are burnt as well
+	for _, used := range []string{token, otherToken} {
+		_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      utils.HashSecureToken(used),
+			HashedPassword: hashedPassword,
+		})
+		require.ErrorIs(t, err, ErrInvalidResetToken)
+	}
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	expired, _ := createRandomPasswordResetToken(t, user, time.Now().Add(-time.Minute))
+
+	for _, token := range []string{expired, utils.RandomString(43)} {
+		_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      utils.HashSecureToken(token),
+			HashedPassword: utils.RandomString(60),
+		})
+		require.ErrorIs(t, err, ErrInvalidResetToken)
+	}
+
+	stored, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, stored.HashedPassword)
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/brkss/simplebank/token"
+)
+
+// SQLRevocationStore is a token.RevocationStore persisted in postgres
+type SQLRevocationStore struct {
+	q Querier
+}
+
+func NewRevocationStore(q Querier) token.RevocationStore {
+	return &SQLRevocationStore{q: q}
+}
+
+func (s *SQLRevocationStore) Revoke(ctx context.Context, payload *token.Payload) error {
+	return s.q.RevokeToken(ctx, RevokeTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+}
+
+func (s *SQLRevocationStore) RevokeAll(ctx context.Context, username string, before time.Time) error {
+	return s.q.RevokeUserTokens(ctx, RevokeUserTokensParams{
+		Username:      username,
+		RevokedBefore: before,
+	})
+}
+
+func (s *SQLRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return s.q.IsTokenRevoked(ctx, IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+}
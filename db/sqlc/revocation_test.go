@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/token"
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationStore(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewRevocationStore(testQueries)
+
+	payload1, err := token.NewPayload(user.Username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := token.NewPayload(user.Username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.Revoke(context.Background(), payload1)
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.RevokeAll(context.Background(), user.Username, time.Now())
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	payload3, err := token.NewPayload(user.Username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload3.IssuedAt = time.Now().Add(time.Second)
+
+	revoked, err = store.IsRevoked(context.Background(), payload3)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+
+	err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+
+	got1, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.True(t, got1.IsBlocked)
+
+	got2, err := testQueries.GetSession(context.Background(), session2.ID)
+	require.NoError(t, err)
+	require.False(t, got2.IsBlocked)
+
+	err = testQueries.BlockUserSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	got2, err = testQueries.GetSession(context.Background(), session2.ID)
+	require.NoError(t, err)
+	require.True(t, got2.IsBlocked)
+
+	_, err = testQueries.GetSession(context.Background(), uuid.New())
+	require.Error(t, err)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	user := createRandomUser(t)
+
+	expired := uuid.New()
+	err := testQueries.RevokeToken(context.Background(), RevokeTokenParams{
+		ID:        expired,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	active := uuid.New()
+	err = testQueries.RevokeToken(context.Background(), RevokeTokenParams{
+		ID:        active,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	deleted, err := testQueries.DeleteExpiredRevokedTokens(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       expired,
+		Username: user.Username,
+		IssuedAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       active,
+		Username: user.Username,
+		IssuedAt: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
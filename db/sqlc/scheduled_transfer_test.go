@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, nextRunAt time.Time) ScheduledTransfer {
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 1000)
+	toAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	arg := CreateScheduledTransferParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        utils.RandomInt(1, 100),
+		Currency:      "USD",
+		Schedule:      "@monthly",
+		StartAt:       nextRunAt,
+		MaxRuns:       sql.NullInt64{Int64: 12, Valid: true},
+		NextRunAt:     sql.NullTime{Time: nextRunAt, Valid: true},
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduledTransfer.ID)
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.Schedule, scheduledTransfer.Schedule)
+	require.Equal(t, arg.MaxRuns, scheduledTransfer.MaxRuns)
+	require.False(t, scheduledTransfer.EndAt.Valid)
+	require.Equal(t, ScheduledTransferStatusActive, scheduledTransfer.Status)
+	require.Zero(t, scheduledTransfer.RunCount)
+	require.WithinDuration(t, nextRunAt, scheduledTransfer.NextRunAt.Time, time.Second)
+
+	return scheduledTransfer
+}
+
+func TestListDueScheduledTransfers(t *testing.T) {
+	due := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	later := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	scheduledTransfers, err := testQueries.ListDueScheduledTransfers(context.Background(), 1000)
+	require.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, scheduledTransfer := range scheduledTransfers {
+		ids[scheduledTransfer.ID] = true
+	}
+	require.True(t, ids[due.ID])
+	require.False(t, ids[later.ID])
+}
+
+// scheduledTransferRun runs the next attempt of scheduledTransfer as listed due, recording the outcome
+// of its transfer as a retry when it fails and as moving the schedule on to next otherwise
+func scheduledTransferRun(scheduledTransfer ScheduledTransfer, next time.Time) RunScheduledTransferTxParams {
+	return RunScheduledTransferTxParams{
+		ScheduledTransfer: scheduledTransfer,
+		Transfer: TransferTxParams{
+			FromAccountId: scheduledTransfer.FromAccountID,
+			ToAccountId:   scheduledTransfer.ToAccountID,
+			Amount:        scheduledTransfer.Amount,
+		},
+		Outcome: func(result TransferTxResult, err error) (ScheduledTransferRunOutcome, error) {
+			outcome := ScheduledTransferRunOutcome{
+				Run: CreateScheduledTransferRunParams{
+					ScheduledTransferID: scheduledTransfer.ID,
+					ScheduledFor:        scheduledTransfer.NextRunAt.Time,
+					Attempt:             scheduledTransfer.Attempts + 1,
+					Status:              ScheduledRunStatusSucceeded,
+					TransferID:          sql.NullInt64{Int64: result.Transfer.ID, Valid: err == nil},
+				},
+				Progress: UpdateScheduledTransferProgressParams{
+					ID:        scheduledTransfer.ID,
+					RunCount:  scheduledTransfer.RunCount + 1,
+					Status:    ScheduledTransferStatusActive,
+					NextRunAt: sql.NullTime{Time: next, Valid: true},
+				},
+			}
+			if err != nil {
+				outcome.Run.Status = ScheduledRunStatusRetrying
+				outcome.Run.Error = err.Error()
+				outcome.Progress.RunCount = scheduledTransfer.RunCount
+				outcome.Progress.NextRunAt = scheduledTransfer.NextRunAt
+				outcome.Progress.Attempts = scheduledTransfer.Attempts + 1
+				outcome.Progress.RetryAt = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+			}
+			return outcome, nil
+		},
+	}
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	next := time.Now().Add(time.Hour)
+
+	arg := scheduledTransferRun(scheduledTransfer, next)
+	result, err := store.RunScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledRunStatusSucceeded, result.Run.Status)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Run.TransferID.Int64)
+	require.Equal(t, 1000-scheduledTransfer.Amount, result.Transfer.FromAccount.Balance)
+	require.Equal(t, int64(1), result.ScheduledTransfer.RunCount)
+	require.WithinDuration(t, next, result.ScheduledTransfer.NextRunAt.Time, time.Second)
+
+	// the same attempt can't be run twice
+	_, err = store.RunScheduledTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrScheduledRunStale)
+
+	fromAccount, err := testQueries.GetAccount(context.Background(), scheduledTransfer.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, 1000-scheduledTransfer.Amount, fromAccount.Balance)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, result.Run.ID, runs[0].ID)
+}
+
+func TestRunScheduledTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	arg := scheduledTransferRun(scheduledTransfer, time.Now().Add(time.Hour))
+
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.RunScheduledTransferTx(context.Background(), arg)
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrScheduledRunStale)
+	}
+	require.Equal(t, 1, succeeded)
+
+	// the attempt is paid once whichever run claimed it
+	fromAccount, err := testQueries.GetAccount(context.Background(), scheduledTransfer.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, 1000-scheduledTransfer.Amount, fromAccount.Balance)
+}
+
+func TestRunScheduledTransferTxFailedTransfer(t *testing.T) {
+	store := NewStore(testDB)
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+
+	arg := scheduledTransferRun(scheduledTransfer, time.Now().Add(time.Hour))
+	arg.Transfer.Amount = 2000
+
+	result, err := store.RunScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledRunStatusRetrying, result.Run.Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Equal(t, int64(1), result.ScheduledTransfer.Attempts)
+	require.True(t, result.ScheduledTransfer.RetryAt.Valid)
+
+	fromAccount, err := testQueries.GetAccount(context.Background(), scheduledTransfer.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), fromAccount.Balance)
+
+	// waiting for its retry it's no longer due
+	scheduledTransfers, err := testQueries.ListDueScheduledTransfers(context.Background(), 1000)
+	require.NoError(t, err)
+	for _, due := range scheduledTransfers {
+		require.NotEqual(t, scheduledTransfer.ID, due.ID)
+	}
+}
+
+func TestDeleteScheduledTransfer(t *testing.T) {
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	deleted, err := testQueries.DeleteScheduledTransfer(context.Background(), DeleteScheduledTransferParams{
+		ID:    scheduledTransfer.ID,
+		Owner: createRandomUser(t).Username,
+	})
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	deleted, err = testQueries.DeleteScheduledTransfer(context.Background(), DeleteScheduledTransferParams{
+		ID:    scheduledTransfer.ID,
+		Owner: scheduledTransfer.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
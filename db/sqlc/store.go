@@ -3,19 +3,69 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInsufficientFunds is returned by TransferTx when the source account balance can't cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrCurrencyMismatch is returned by TransferTx for accounts of different currencies without an fx quote
+	ErrCurrencyMismatch = errors.New("accounts hold different currencies, an fx quote is required")
+	// ErrFXQuoteInvalid is returned by TransferTx when the fx quote can't be applied to the transfer
+	ErrFXQuoteInvalid = errors.New("fx quote is expired, already used or doesn't match the transfer")
+	// ErrAmountTooSmall is returned by TransferTx when the converted amount rounds down to nothing
+	ErrAmountTooSmall = errors.New("amount is too small to be converted")
 )
 
 // Store provide all functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	EnrollTOTPTx(ctx context.Context, arg EnrollTOTPTxParams) (EnrollTOTPTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (CreateAccountTxResult, error)
+	AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (AuthorizeTransferTxResult, error)
+	CaptureTransferTx(ctx context.Context, arg CaptureTransferTxParams) (TransferTxResult, error)
+	VoidTransferTx(ctx context.Context, transferID int64) (ReleaseTransferTxResult, error)
+	ExpireTransferTx(ctx context.Context, transferID int64) (ReleaseTransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error)
+	ApproveTransferTx(ctx context.Context, arg ApproveTransferTxParams) (TransferTxResult, error)
+	AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error)
+	ExportAccountStatementTx(ctx context.Context, arg ExportAccountStatementTxParams) error
 }
 
 // SQLStore provide all functions to execute sql queries and transactions
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db   *sql.DB
+	fees FeeSchedule
+}
+
+// FeeSchedule prices transfers, Fee returns the fee charged on a transfer of amount
+// out of an account in currency and the bank account collecting it, a zero fee means a free transfer
+type FeeSchedule interface {
+	Fee(currency string, amount int64) (fee int64, revenueAccountID int64)
+}
+
+// StoreOption customizes the SQLStore built by NewStore
+type StoreOption func(*SQLStore)
+
+// WithFeeSchedule charges transfers, batch legs and captured holds the fees of schedule, transfers are free without it
+func WithFeeSchedule(schedule FeeSchedule) StoreOption {
+	return func(store *SQLStore) {
+		store.fees = schedule
+	}
 }
 
 // TransferTxParams contains all the input prams needed to create transaction !
@@ -23,6 +73,13 @@ type TransferTxParams struct {
 	FromAccountId int64 `json:"from_account_id"`
 	ToAccountId   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// FXQuoteID is the quote that locked the exchange rate of a transfer between accounts of different currencies,
+	// Amount is then debited in the source currency and credited converted at the quoted rate
+	FXQuoteID uuid.NullUUID `json:"fx_quote_id"`
+	// RequireApproval records the transfer as pending approval instead of moving money
+	RequireApproval bool `json:"require_approval"`
+	// Idempotency makes retries of the same request return the first transfer instead of moving money again
+	Idempotency *IdempotencyParams `json:"-"`
 }
 
 // TransferTxResult is the result of transfer transaction
@@ -32,18 +89,45 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// Fee is the fee charged on top of the amount, left out of free transfers
+	Fee *TransferFee `json:"fee,omitempty"`
+	// Replayed is set when the transfer was made by an earlier request with the same idempotency key
+	Replayed bool `json:"-"`
+}
+
+// TransferFee is the fee debited from the source account and credited to the bank revenue account
+type TransferFee struct {
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	RevenueAccountID int64  `json:"revenue_account_id"`
+	FromEntry        Entry  `json:"from_entry"`
+	RevenueEntry     Entry  `json:"revenue_entry"`
 }
 
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
+func NewStore(db *sql.DB, opts ...StoreOption) Store {
+	store := &SQLStore{
 		db:      db,
 		Queries: New(db),
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
 }
 
 // execTx execute a function within the datbase transaction
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+	return store.execTxWithOptions(ctx, nil, fn)
+}
+
+// execSnapshotTx runs read only queries against a single snapshot of the database,
+// so what they read stays consistent with each other under concurrent writes
+func (store *SQLStore) execSnapshotTx(ctx context.Context, fn func(*Queries) error) error {
+	return store.execTxWithOptions(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, fn)
+}
+
+func (store *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -60,49 +144,302 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 }
 
 // TransferTx performs a moneyTransaction from one account to the other
-// it create a transfer record, an account entries and update accounts balance within a single databse transaction
+// it create a transfer record, an account entries and update accounts balance within a single databse transaction,
+// the source account is locked first so concurrent transfers can't overdraw it or spend funds held for pending transfers.
+// The fee of the transfer, if any, is debited on top of the amount and credited to the revenue account in the same transaction,
+// transfers going over a limit of the source account fail with ErrLimitExceeded.
+// A transfer requiring approval is only recorded, ApproveTransferTx moves its money later
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	replayed, err := store.execIdempotentTx(ctx, arg.Idempotency, &result, func(q *Queries) error {
 		var err error
+		result, err = store.transfer(ctx, q, arg)
+		return err
+	})
+	result.Replayed = replayed
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountId,
-			ToAccountID:   arg.ToAccountId,
-			Amount:        arg.Amount,
-		})
+	return result, err
+}
+
+// transfer makes the transfer of arg within the transaction of q, leaving its idempotency to the caller
+func (store *SQLStore) transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	if arg.RequireApproval {
+		return recordPendingTransfer(ctx, q, arg)
+	}
+
+	return store.postTransfer(ctx, q, arg,
+		func(fromAccount Account, toAccount Account) (int64, string, error) {
+			return exchange(ctx, q, arg.FXQuoteID, arg.Amount, fromAccount, toAccount)
+		},
+		func(transfer CreateTransferParams) (Transfer, error) {
+			return q.CreateTransfer(ctx, transfer)
+		},
+	)
+}
+
+// postTransfer moves the money of a transfer, rateTransfer works out the amount credited and the rate
+// applied once both accounts are locked and record saves the transfer row
+func (store *SQLStore) postTransfer(
+	ctx context.Context,
+	q *Queries,
+	arg TransferTxParams,
+	rateTransfer func(fromAccount Account, toAccount Account) (int64, string, error),
+	record func(transfer CreateTransferParams) (Transfer, error),
+) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	fee, revenueAccountID, err := store.transferFee(ctx, q, arg)
+	if err != nil {
+		return result, err
+	}
+
+	accounts, err := lockAccountSet(ctx, q, arg.FromAccountId, arg.ToAccountId, revenueAccountID)
+	if err != nil {
+		return result, err
+	}
+	fromAccount, toAccount := accounts[arg.FromAccountId], accounts[arg.ToAccountId]
+	if availableBalance(fromAccount) < arg.Amount+fee {
+		return result, ErrInsufficientFunds
+	}
+	err = checkTransferLimits(ctx, q, fromAccount, arg.Amount, time.Now())
+	if err != nil {
+		return result, err
+	}
+
+	toAmount, rate, err := rateTransfer(fromAccount, toAccount)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = record(CreateTransferParams{
+		FromAccountID: arg.FromAccountId,
+		ToAccountID:   arg.ToAccountId,
+		Amount:        arg.Amount,
+		Currency:      fromAccount.Currency,
+		ToAmount:      toAmount,
+		ToCurrency:    toAccount.Currency,
+		ExchangeRate:  rate,
+		FxQuoteID:     arg.FXQuoteID,
+		Fee:           fee,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountId,
+		Amount:     -arg.Amount,
+		Currency:   fromAccount.Currency,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		Kind:       EntryKindTransfer,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountId,
+		Amount:     toAmount,
+		Currency:   toAccount.Currency,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		Kind:       EntryKindTransfer,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// accumulated so a transfer to the source account itself nets to zero
+	deltas := make(map[int64]int64)
+	deltas[arg.FromAccountId] -= arg.Amount
+	deltas[arg.ToAccountId] += toAmount
+	if fee > 0 {
+		result.Fee, err = chargeFee(ctx, q, result.Transfer.ID, fromAccount, accounts[revenueAccountID], fee)
 		if err != nil {
-			return err
+			return result, err
 		}
+		deltas[arg.FromAccountId] -= fee
+		deltas[revenueAccountID] += fee
+	}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountId,
-			Amount:    -arg.Amount,
-		})
+	accounts, err = addBalances(ctx, q, deltas)
+	if err != nil {
+		return result, err
+	}
+	result.FromAccount, result.ToAccount = accounts[arg.FromAccountId], accounts[arg.ToAccountId]
+	return result, nil
+}
+
+// transferFee prices a transfer with the fee schedule of the store, it reads the currency
+// of the source account before it gets locked as an account never changes currency
+func (store *SQLStore) transferFee(ctx context.Context, q *Queries, arg TransferTxParams) (int64, int64, error) {
+	if store.fees == nil {
+		return 0, 0, nil
+	}
+
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fee, revenueAccountID := store.fee(fromAccount.Currency, arg.Amount)
+	return fee, revenueAccountID, nil
+}
+
+// fee prices a transfer of amount out of an account in currency, it returns the fee
+// and the revenue account collecting it or zeros for a free transfer
+func (store *SQLStore) fee(currency string, amount int64) (int64, int64) {
+	if store.fees == nil {
+		return 0, 0
+	}
+
+	fee, revenueAccountID := store.fees.Fee(currency, amount)
+	if fee <= 0 {
+		return 0, 0
+	}
+	return fee, revenueAccountID
+}
+
+// Kinds of entries, the fee entries of a transfer are linked to it next to the ones of its legs
+const (
+	EntryKindTransfer = "transfer"
+	EntryKindFee      = "fee"
+)
+
+// chargeFee records the entries moving the fee of a transfer from fromAccount to the revenue account,
+// the entries are linked to the transfer, balances are updated by the caller
+func chargeFee(ctx context.Context, q *Queries, transferID int64, fromAccount Account, revenueAccount Account, fee int64) (*TransferFee, error) {
+	if revenueAccount.Currency != fromAccount.Currency {
+		return nil, fmt.Errorf("fee revenue account %d doesn't hold %s", revenueAccount.ID, fromAccount.Currency)
+	}
+
+	fromEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  fromAccount.ID,
+		Amount:     -fee,
+		Currency:   fromAccount.Currency,
+		TransferID: sql.NullInt64{Int64: transferID, Valid: true},
+		Kind:       EntryKindFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revenueEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  revenueAccount.ID,
+		Amount:     fee,
+		Currency:   revenueAccount.Currency,
+		TransferID: sql.NullInt64{Int64: transferID, Valid: true},
+		Kind:       EntryKindFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransferFee{
+		Amount:           fee,
+		Currency:         fromAccount.Currency,
+		RevenueAccountID: revenueAccount.ID,
+		FromEntry:        fromEntry,
+		RevenueEntry:     revenueEntry,
+	}, nil
+}
+
+// lockAccounts locks both rows of a transfer for the rest of the transaction and returns them,
+// rows are always locked in id order so opposite transfers between two accounts can't deadlock
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
+}
+
+// lockAccountSet locks the rows of ids in id order so transactions sharing accounts can't deadlock,
+// zero ids are skipped and accounts appearing twice are locked once
+func lockAccountSet(ctx context.Context, q *Queries, ids ...int64) (map[int64]Account, error) {
+	sorted := make([]int64, 0, len(ids))
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		if _, ok := accounts[id]; id != 0 && !ok {
+			accounts[id] = Account{}
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, id := range sorted {
+		account, err := q.GetAccountForUpdate(ctx, id)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountId,
-			Amount:    arg.Amount,
+// addBalances adds deltas to the balances of their accounts in id order and returns the updated accounts
+func addBalances(ctx context.Context, q *Queries, deltas map[int64]int64) (map[int64]Account, error) {
+	ids := make([]int64, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     id,
+			Amount: deltas[id],
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
-		// TODO: update account balance
+		accounts[id] = account
+	}
+	return accounts, nil
+}
 
-		if arg.FromAccountId < arg.ToAccountId {
-			result.FromAccount, result.ToAccount, err = AddMoney(ctx, q, arg.FromAccountId, -arg.Amount, arg.ToAccountId, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = AddMoney(ctx, q, arg.ToAccountId, arg.Amount, arg.FromAccountId, -arg.Amount)
+// exchange works out the amount credited to toAccount and the rate applied,
+// transfers between different currencies use up the quote that locked their rate
+func exchange(ctx context.Context, q *Queries, quoteID uuid.NullUUID, amount int64, fromAccount Account, toAccount Account) (int64, string, error) {
+	if !quoteID.Valid {
+		if fromAccount.Currency != toAccount.Currency {
+			return 0, "", ErrCurrencyMismatch
 		}
+		return amount, "1", nil
+	}
 
-		return nil
-	})
+	quote, err := q.UseFXQuote(ctx, quoteID.UUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", ErrFXQuoteInvalid
+		}
+		return 0, "", err
+	}
+	if quote.Username != fromAccount.Owner ||
+		quote.FromCurrency != fromAccount.Currency ||
+		quote.ToCurrency != toAccount.Currency {
+		return 0, "", ErrFXQuoteInvalid
+	}
 
-	return result, err
+	toAmount, err := utils.ConvertAmount(amount, quote.Rate)
+	if err != nil {
+		return 0, "", err
+	}
+	if toAmount <= 0 {
+		return 0, "", ErrAmountTooSmall
+	}
+	return toAmount, quote.Rate, nil
 }
 
 func AddMoney(
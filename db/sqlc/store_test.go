@@ -2,18 +2,31 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
+// createAccountWithBalance creates an account holding enough money for the transfers of a test
+func createAccountWithBalance(t *testing.T, user User, currency string, balance int64) Account {
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, account.Balance)
+	return account
+}
+
 func TestTranferTx(t *testing.T) {
 	store := NewStore(testDB)
 	user1 := createRandomUser(t)
 	user2 := createRandomUser(t)
-	account1 := CreateRandomAccount(t, user1)
-	account2 := CreateRandomAccount(t, user2)
+	account1 := createAccountWithBalance(t, user1, "USD", 1000)
+	account2 := createAccountWithBalance(t, user2, "USD", 1000)
 
 	fmt.Println(">> Before : ", account1.Balance, account2.Balance)
 
@@ -112,8 +125,8 @@ func TestTrasferTxDeadlock(t *testing.T) {
 
 	user1 := createRandomUser(t)
 	user2 := createRandomUser(t)
-	account1 := CreateRandomAccount(t, user1)
-	account2 := CreateRandomAccount(t, user2)
+	account1 := createAccountWithBalance(t, user1, "USD", 1000)
+	account2 := createAccountWithBalance(t, user2, "USD", 1000)
 
 	n := 10
 	amount := int64(10)
@@ -156,3 +169,129 @@ func TestTrasferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 50)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	// 10 concurrent transfers of 10 out of an account holding 50, only 5 can go through
+	n := 10
+	amount := int64(10)
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+	}
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), updatedAccount2.Balance)
+}
+
+func TestTransferTxSameAccount(t *testing.T) {
+	revenueAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	store := NewStore(testDB, WithFeeSchedule(flatFeeSchedule{fee: 5, revenueAccountID: revenueAccount.ID}))
+	account := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+
+	// money sent to the account it comes from only pays the fee
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account.ID,
+		ToAccountId:   account.ID,
+		Amount:        40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(95), result.FromAccount.Balance)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(95), updatedAccount.Balance)
+
+	// without fees the balance is left unchanged
+	store = NewStore(testDB)
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account.ID,
+		ToAccountId:   account.ID,
+		Amount:        40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(95), result.FromAccount.Balance)
+}
+
+// flatFeeSchedule charges the same fee on every transfer and pays it to one account
+type flatFeeSchedule struct {
+	fee              int64
+	revenueAccountID int64
+}
+
+func (schedule flatFeeSchedule) Fee(currency string, amount int64) (int64, int64) {
+	return schedule.fee, schedule.revenueAccountID
+}
+
+func TestTransferTxFee(t *testing.T) {
+	revenueAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	store := NewStore(testDB, WithFeeSchedule(flatFeeSchedule{fee: 5, revenueAccountID: revenueAccount.ID}))
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), result.Transfer.Fee)
+	require.Equal(t, int64(45), result.FromAccount.Balance)
+	require.Equal(t, int64(50), result.ToAccount.Balance)
+
+	require.NotNil(t, result.Fee)
+	require.Equal(t, int64(5), result.Fee.Amount)
+	require.Equal(t, "USD", result.Fee.Currency)
+	require.Equal(t, revenueAccount.ID, result.Fee.RevenueAccountID)
+	require.Equal(t, account1.ID, result.Fee.FromEntry.AccountID)
+	require.Equal(t, int64(-5), result.Fee.FromEntry.Amount)
+	require.Equal(t, revenueAccount.ID, result.Fee.RevenueEntry.AccountID)
+	require.Equal(t, int64(5), result.Fee.RevenueEntry.Amount)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.ID, Valid: true}, result.Fee.FromEntry.TransferID)
+	require.Equal(t, sql.NullInt64{Int64: result.Transfer.ID, Valid: true}, result.Fee.RevenueEntry.TransferID)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), updatedRevenueAccount.Balance)
+
+	// 45 left can't cover 45 and its fee
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        45,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(45), updatedAccount1.Balance)
+}
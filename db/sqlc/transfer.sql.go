@@ -7,24 +7,244 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
+const approveTransfer = `-- name: ApproveTransfer :one
+UPDATE transfers SET
+status = 'posted',
+fee = $2,
+reviewed_by = $3,
+reviewed_at = now()
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type ApproveTransferParams struct {
+	ID         int64          `json:"id"`
+	Fee        int64          `json:"fee"`
+	ReviewedBy sql.NullString `json:"reviewed_by"`
+}
+
+func (q *Queries) ApproveTransfer(ctx context.Context, arg ApproveTransferParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, approveTransfer, arg.ID, arg.Fee, arg.ReviewedBy)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const captureTransfer = `-- name: CaptureTransfer :one
+UPDATE transfers SET
+status = 'captured',
+amount = $1,
+to_amount = $1,
+fee = $2
+WHERE id = $3
+RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type CaptureTransferParams struct {
+	Amount int64 `json:"amount"`
+	Fee    int64 `json:"fee"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) CaptureTransfer(ctx context.Context, arg CaptureTransferParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, captureTransfer, arg.Amount, arg.Fee, arg.ID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const createPendingTransfer = `-- name: CreatePendingTransfer :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    fx_quote_id,
+    status
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, 'pending_approval' ) RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type CreatePendingTransferParams struct {
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Currency      string        `json:"currency"`
+	ToAmount      int64         `json:"to_amount"`
+	ToCurrency    string        `json:"to_currency"`
+	ExchangeRate  string        `json:"exchange_rate"`
+	FxQuoteID     uuid.NullUUID `json:"fx_quote_id"`
+}
+
+func (q *Queries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, createPendingTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ToAmount,
+		arg.ToCurrency,
+		arg.ExchangeRate,
+		arg.FxQuoteID,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
-)VALUES ( $1, $2, $3 ) RETURNING id, from_account_id, to_account_id, amount, created_at
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    fx_quote_id,
+    fee
+)VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9 ) RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Currency      string        `json:"currency"`
+	ToAmount      int64         `json:"to_amount"`
+	ToCurrency    string        `json:"to_currency"`
+	ExchangeRate  string        `json:"exchange_rate"`
+	FxQuoteID     uuid.NullUUID `json:"fx_quote_id"`
+	Fee           int64         `json:"fee"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ToAmount,
+		arg.ToCurrency,
+		arg.ExchangeRate,
+		arg.FxQuoteID,
+		arg.Fee,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const createTransferHold = `-- name: CreateTransferHold :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    status,
+    authorized_amount,
+    expires_at
+) VALUES ( $1, $2, $3, $4, $3, $4, 1, 'pending', $3, $5 ) RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type CreateTransferHoldParams struct {
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	ExpiresAt     sql.NullTime `json:"expires_at"`
+}
+
+func (q *Queries) CreateTransferHold(ctx context.Context, arg CreateTransferHoldParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, createTransferHold,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ExpiresAt,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +252,95 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
 	)
 	return i, err
 }
 
+const createTransferReversal = `-- name: CreateTransferReversal :one
+INSERT INTO transfers (
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    to_amount,
+    to_currency,
+    exchange_rate,
+    reversal_of
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8 ) RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type CreateTransferReversalParams struct {
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Currency      string        `json:"currency"`
+	ToAmount      int64         `json:"to_amount"`
+	ToCurrency    string        `json:"to_currency"`
+	ExchangeRate  string        `json:"exchange_rate"`
+	ReversalOf    sql.NullInt64 `json:"reversal_of"`
+}
+
+func (q *Queries) CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, createTransferReversal,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ToAmount,
+		arg.ToCurrency,
+		arg.ExchangeRate,
+		arg.ReversalOf,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const getReversedAmount = `-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(to_amount), 0)::bigint AS reversed_amount
+FROM transfers
+WHERE reversal_of = $1
+`
+
+func (q *Queries) GetReversedAmount(ctx context.Context, reversalOf sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getReversedAmount, reversalOf)
+	var reversed_amount int64
+	err := row.Scan(&reversed_amount)
+	return reversed_amount, err
+}
+
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers 
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers 
 WHERE id = $1
 `
 
@@ -50,12 +353,343 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+AND created_at >= COALESCE($2, '-infinity'::timestamptz)
+AND created_at < COALESCE($3, 'infinity'::timestamptz)
+AND CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END >= $4
+ORDER BY id DESC
+LIMIT $5
+OFFSET $6
+`
+
+type ListAccountTransfersParams struct {
+	AccountID int64        `json:"account_id"`
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	MinAmount int64        `json:"min_amount"`
+	RowLimit  int32        `json:"row_limit"`
+	RowOffset int32        `json:"row_offset"`
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+			&i.ToAmount,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.Status,
+			&i.AuthorizedAmount,
+			&i.ExpiresAt,
+			&i.ReversalOf,
+			&i.Fee,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredTransferHolds = `-- name: ListExpiredTransferHolds :many
+SELECT id FROM transfers
+WHERE status = 'pending' AND expires_at <= now()
+ORDER BY expires_at
+LIMIT $1
+`
+
+func (q *Queries) ListExpiredTransferHolds(ctx context.Context, limit int32) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listExpiredTransferHolds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIncomingTransfers = `-- name: ListIncomingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
+WHERE to_account_id = $1
+AND created_at >= COALESCE($2, '-infinity'::timestamptz)
+AND created_at < COALESCE($3, 'infinity'::timestamptz)
+AND to_amount >= $4
+ORDER BY id DESC
+LIMIT $5
+OFFSET $6
+`
+
+type ListIncomingTransfersParams struct {
+	AccountID int64        `json:"account_id"`
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	MinAmount int64        `json:"min_amount"`
+	RowLimit  int32        `json:"row_limit"`
+	RowOffset int32        `json:"row_offset"`
+}
+
+func (q *Queries) ListIncomingTransfers(ctx context.Context, arg ListIncomingTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listIncomingTransfers,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+			&i.ToAmount,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.Status,
+			&i.AuthorizedAmount,
+			&i.ExpiresAt,
+			&i.ReversalOf,
+			&i.Fee,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOutgoingTransfers = `-- name: ListOutgoingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
+WHERE from_account_id = $1
+AND created_at >= COALESCE($2, '-infinity'::timestamptz)
+AND created_at < COALESCE($3, 'infinity'::timestamptz)
+AND amount >= $4
+ORDER BY id DESC
+LIMIT $5
+OFFSET $6
+`
+
+type ListOutgoingTransfersParams struct {
+	AccountID int64        `json:"account_id"`
+	FromTime  sql.NullTime `json:"from_time"`
+	ToTime    sql.NullTime `json:"to_time"`
+	MinAmount int64        `json:"min_amount"`
+	RowLimit  int32        `json:"row_limit"`
+	RowOffset int32        `json:"row_offset"`
+}
+
+func (q *Queries) ListOutgoingTransfers(ctx context.Context, arg ListOutgoingTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listOutgoingTransfers,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+			&i.ToAmount,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.Status,
+			&i.AuthorizedAmount,
+			&i.ExpiresAt,
+			&i.ReversalOf,
+			&i.Fee,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingApprovalTransfers = `-- name: ListPendingApprovalTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
+WHERE status = 'pending_approval'
+ORDER BY id
+LIMIT $1
+OFFSET $2
+`
+
+type ListPendingApprovalTransfersParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListPendingApprovalTransfers(ctx context.Context, arg ListPendingApprovalTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingApprovalTransfers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+			&i.ToAmount,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.Status,
+			&i.AuthorizedAmount,
+			&i.ExpiresAt,
+			&i.ReversalOf,
+			&i.Fee,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -81,6 +715,18 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Currency,
+			&i.ToAmount,
+			&i.ToCurrency,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.Status,
+			&i.AuthorizedAmount,
+			&i.ExpiresAt,
+			&i.ReversalOf,
+			&i.Fee,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -94,3 +740,79 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const rejectTransfer = `-- name: RejectTransfer :one
+UPDATE transfers SET
+status = 'rejected',
+reviewed_by = $2,
+reviewed_at = now()
+WHERE id = $1 AND status = 'pending_approval'
+RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type RejectTransferParams struct {
+	ID         int64          `json:"id"`
+	ReviewedBy sql.NullString `json:"reviewed_by"`
+}
+
+func (q *Queries) RejectTransfer(ctx context.Context, arg RejectTransferParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, rejectTransfer, arg.ID, arg.ReviewedBy)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
+
+const setTransferStatus = `-- name: SetTransferStatus :one
+UPDATE transfers SET
+status = $2
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, currency, to_amount, to_currency, exchange_rate, fx_quote_id, status, authorized_amount, expires_at, reversal_of, fee, reviewed_by, reviewed_at
+`
+
+type SetTransferStatusParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) SetTransferStatus(ctx context.Context, arg SetTransferStatusParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, setTransferStatus, arg.ID, arg.Status)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Currency,
+		&i.ToAmount,
+		&i.ToCurrency,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.Status,
+		&i.AuthorizedAmount,
+		&i.ExpiresAt,
+		&i.ReversalOf,
+		&i.Fee,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setRandomAccountLimit(t *testing.T, account Account, arg UpsertTransferLimitParams) TransferLimit {
+	arg.Scope = TransferLimitScopeAccount
+	arg.Subject = fmt.Sprint(account.ID)
+
+	limit, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Scope, limit.Scope)
+	require.Equal(t, arg.Subject, limit.Subject)
+	require.Equal(t, arg.MaxAmount, limit.MaxAmount)
+	require.Equal(t, arg.DailyAmount, limit.DailyAmount)
+	require.Equal(t, arg.MonthlyAmount, limit.MonthlyAmount)
+	require.Equal(t, arg.HourlyCount, limit.HourlyCount)
+	return limit
+}
+
+func TestUpsertTransferLimit(t *testing.T) {
+	account := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	setRandomAccountLimit(t, account, UpsertTransferLimitParams{
+		MaxAmount: sql.NullInt64{Int64: 100, Valid: true},
+	})
+
+	// a second upsert replaces every field of the limit
+	limit := setRandomAccountLimit(t, account, UpsertTransferLimitParams{
+		DailyAmount: sql.NullInt64{Int64: 500, Valid: true},
+	})
+	require.False(t, limit.MaxAmount.Valid)
+
+	gotLimit, err := testQueries.GetTransferLimit(context.Background(), GetTransferLimitParams{
+		Scope:   limit.Scope,
+		Subject: limit.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, limit, gotLimit)
+
+	deleted, err := testQueries.DeleteTransferLimit(context.Background(), DeleteTransferLimitParams{
+		Scope:   limit.Scope,
+		Subject: limit.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = testQueries.GetTransferLimit(context.Background(), GetTransferLimitParams{
+		Scope:   limit.Scope,
+		Subject: limit.Subject,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	transfer := func(amount int64) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountId: account1.ID,
+			ToAccountId:   account2.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	// the account override takes precedence over the limit of its owner
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:       TransferLimitScopeUser,
+		Subject:     user.Username,
+		MaxAmount:   sql.NullInt64{Int64: 100, Valid: true},
+		HourlyCount: sql.NullInt64{Int64: 3, Valid: true},
+	})
+	require.NoError(t, err)
+	setRandomAccountLimit(t, account1, UpsertTransferLimitParams{
+		MaxAmount:   sql.NullInt64{Int64: 50, Valid: true},
+		DailyAmount: sql.NullInt64{Int64: 90, Valid: true},
+	})
+
+	require.ErrorIs(t, transfer(60), ErrLimitExceeded)
+	require.NoError(t, transfer(50))
+	require.NoError(t, transfer(40))
+	// 90 already went out today
+	require.ErrorIs(t, transfer(1), ErrLimitExceeded)
+
+	setRandomAccountLimit(t, account1, UpsertTransferLimitParams{})
+	// the user limit applies again, 2 transfers were made in the last hour
+	require.NoError(t, transfer(100))
+	err = transfer(1)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	require.Contains(t, err.Error(), "transfers per hour")
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-190, updatedAccount1.Balance)
+}
+
+func TestBatchTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 1000)
+	toAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	setRandomAccountLimit(t, fromAccount, UpsertTransferLimitParams{
+		MaxAmount:   sql.NullInt64{Int64: 150, Valid: true},
+		DailyAmount: sql.NullInt64{Int64: 250, Valid: true},
+	})
+
+	// legs are counted one after the other, the third one goes over the daily total
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		Mode:          BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: toAccount.ID, Amount: 200},
+			{ToAccountID: toAccount.ID, Amount: 100},
+			{ToAccountID: toAccount.ID, Amount: 100},
+			{ToAccountID: toAccount.ID, Amount: 100},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Batch.SucceededCount)
+	require.Equal(t, TransferBatchItemStatusFailed, result.Items[0].Status)
+	require.Contains(t, result.Items[0].Error, "single transfer max")
+	require.Equal(t, TransferBatchItemStatusSucceeded, result.Items[1].Status)
+	require.Equal(t, TransferBatchItemStatusSucceeded, result.Items[2].Status)
+	require.Equal(t, TransferBatchItemStatusFailed, result.Items[3].Status)
+	require.Contains(t, result.Items[3].Error, "daily outgoing total")
+	require.Equal(t, int64(800), result.FromAccount.Balance)
+
+	// the transfers of the batch count against the next ones
+	_, err = store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		Mode:          BatchModeAtomic,
+		Legs:          []BatchTransferLeg{{ToAccountID: toAccount.ID, Amount: 60}},
+	})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func TestAuthorizeTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	setRandomAccountLimit(t, account1, UpsertTransferLimitParams{
+		MaxAmount:   sql.NullInt64{Int64: 100, Valid: true},
+		DailyAmount: sql.NullInt64{Int64: 150, Valid: true},
+	})
+
+	authorize := func(amount int64) error {
+		_, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+			FromAccountId: account1.ID,
+			ToAccountId:   account2.ID,
+			Amount:        amount,
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+		return err
+	}
+
+	require.ErrorIs(t, authorize(101), ErrLimitExceeded)
+	require.NoError(t, authorize(100))
+
+	// a pending hold counts against the limit like a transfer
+	require.ErrorIs(t, authorize(60), ErrLimitExceeded)
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        60,
+	})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	require.NoError(t, authorize(50))
+}
+
+func TestTransferTxUserLimitAcrossAccounts(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, user, "USD", 1000)
+	euroAccount := createAccountWithBalance(t, user, "EUR", 1000)
+	otherAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	euroOtherAccount := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+
+	transfer := func(from Account, to Account, amount int64) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountId: from.ID,
+			ToAccountId:   to.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:       TransferLimitScopeUser,
+		Subject:     user.Username,
+		DailyAmount: sql.NullInt64{Int64: 100, Valid: true},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, transfer(account1, otherAccount, 60))
+	// the user limit counts what went out of the other account of the user
+	err = transfer(account2, otherAccount, 50)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	require.NoError(t, transfer(account2, otherAccount, 40))
+	require.ErrorIs(t, transfer(account1, otherAccount, 1), ErrLimitExceeded)
+
+	// transfers in another currency have totals of their own
+	require.NoError(t, transfer(euroAccount, euroOtherAccount, 100))
+
+	// an account limit only counts the transfers of its account
+	setRandomAccountLimit(t, account2, UpsertTransferLimitParams{
+		DailyAmount: sql.NullInt64{Int64: 100, Valid: true},
+	})
+	require.NoError(t, transfer(account2, otherAccount, 60))
+}
+
+func TestTransferTxUserLimitConcurrentAccounts(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, user, "USD", 1000)
+	otherAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:       TransferLimitScopeUser,
+		Subject:     user.Username,
+		DailyAmount: sql.NullInt64{Int64: 100, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// transfers out of both accounts at once can't get past the limit together
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccount := account1
+		if i%2 == 1 {
+			fromAccount = account2
+		}
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountId: fromAccount.ID,
+				ToAccountId:   otherAccount.ID,
+				Amount:        30,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	}
+	require.Equal(t, 3, succeeded)
+
+	updatedOtherAccount, err := store.GetAccount(context.Background(), otherAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), updatedOtherAccount.Balance)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// scopes of a transfer limit, the subject of a limit is the id of an account, the username of
+// a user or a currency code. Each field of the limit applied to an account is taken from the
+// account limit, then from the limit of its owner and last from the limit of its currency.
+// Totals capped by the limit of a user add up the transfers out of all the accounts of the user
+// in the currency of the source account
+const (
+	TransferLimitScopeAccount  = "account"
+	TransferLimitScopeUser     = "user"
+	TransferLimitScopeCurrency = "currency"
+)
+
+// ErrLimitExceeded is returned by TransferTx when a transfer goes over a limit of its source account
+var ErrLimitExceeded = errors.New("transfer limit exceeded")
+
+// checkTransferLimits checks a transfer of amount out of fromAccount against its limits and the
+// transfers it already made, fromAccount must be locked so concurrent transfers are counted.
+// Amounts are in the currency of the account, daily and monthly totals are reset at midnight UTC
+func checkTransferLimits(ctx context.Context, q *Queries, fromAccount Account, amount int64, now time.Time) error {
+	check, err := newTransferLimitCheck(ctx, q, fromAccount, now)
+	if err != nil {
+		return err
+	}
+	return check.add(amount)
+}
+
+// transferLimitCheck is the limit of a source account along with the transfers already counted
+// against it, so the transfers of a single transaction can be checked one after the other
+type transferLimitCheck struct {
+	limit TransferLimit
+	// totals counted against the aggregate fields of limit, they add up the transfers out of the
+	// source account, or out of every account of its owner in its currency for the fields set
+	// by the limit of the owner
+	dailyAmount   int64
+	monthlyAmount int64
+	hourlyCount   int64
+}
+
+// newTransferLimitCheck loads the limit of fromAccount and the totals of the transfers counted against it,
+// fromAccount must be locked so concurrent transfers are counted. Limits of the owner lock the owner too,
+// always after the accounts of the transfer, so that transfers out of their other accounts are counted
+func newTransferLimitCheck(ctx context.Context, q *Queries, fromAccount Account, now time.Time) (*transferLimitCheck, error) {
+	limits, err := q.ListApplicableTransferLimits(ctx, ListApplicableTransferLimitsParams{
+		AccountID: strconv.FormatInt(fromAccount.ID, 10),
+		Owner:     fromAccount.Owner,
+		Currency:  fromAccount.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	limit, scopes := effectiveTransferLimit(limits)
+	check := &transferLimitCheck{limit: limit}
+
+	if !limit.DailyAmount.Valid && !limit.MonthlyAmount.Valid && !limit.HourlyCount.Valid {
+		return check, nil
+	}
+
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hourStart := now.Add(-time.Hour)
+
+	totals, err := q.GetOutgoingTransferTotals(ctx, GetOutgoingTransferTotalsParams{
+		DayStart:      dayStart,
+		MonthStart:    monthStart,
+		HourStart:     hourStart,
+		FromAccountID: fromAccount.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	check.dailyAmount, check.monthlyAmount, check.hourlyCount = totals.DailyAmount, totals.MonthlyAmount, totals.HourlyCount
+
+	if scopes.daily != TransferLimitScopeUser && scopes.monthly != TransferLimitScopeUser && scopes.hourly != TransferLimitScopeUser {
+		return check, nil
+	}
+
+	_, err = q.GetUserForUpdate(ctx, fromAccount.Owner)
+	if err != nil {
+		return nil, err
+	}
+	ownerTotals, err := q.GetOwnerOutgoingTransferTotals(ctx, GetOwnerOutgoingTransferTotalsParams{
+		DayStart:   dayStart,
+		MonthStart: monthStart,
+		HourStart:  hourStart,
+		Owner:      fromAccount.Owner,
+		Currency:   fromAccount.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if scopes.daily == TransferLimitScopeUser {
+		check.dailyAmount = ownerTotals.DailyAmount
+	}
+	if scopes.monthly == TransferLimitScopeUser {
+		check.monthlyAmount = ownerTotals.MonthlyAmount
+	}
+	if scopes.hourly == TransferLimitScopeUser {
+		check.hourlyCount = ownerTotals.HourlyCount
+	}
+	return check, nil
+}
+
+// add checks a transfer of amount against the limit and counts it in the totals when it fits
+func (check *transferLimitCheck) add(amount int64) error {
+	limit := check.limit
+
+	if limit.MaxAmount.Valid && amount > limit.MaxAmount.Int64 {
+		return fmt.Errorf("%w: single transfer max is %d", ErrLimitExceeded, limit.MaxAmount.Int64)
+	}
+	if limit.DailyAmount.Valid && check.dailyAmount+amount > limit.DailyAmount.Int64 {
+		return fmt.Errorf("%w: daily outgoing total is %d", ErrLimitExceeded, limit.DailyAmount.Int64)
+	}
+	if limit.MonthlyAmount.Valid && check.monthlyAmount+amount > limit.MonthlyAmount.Int64 {
+		return fmt.Errorf("%w: monthly outgoing total is %d", ErrLimitExceeded, limit.MonthlyAmount.Int64)
+	}
+	if limit.HourlyCount.Valid && check.hourlyCount+1 > limit.HourlyCount.Int64 {
+		return fmt.Errorf("%w: %d transfers per hour", ErrLimitExceeded, limit.HourlyCount.Int64)
+	}
+
+	check.dailyAmount += amount
+	check.monthlyAmount += amount
+	check.hourlyCount++
+	return nil
+}
+
+// transferLimitScopes names the scope each aggregate field of an effective limit was taken from
+type transferLimitScopes struct {
+	daily   string
+	monthly string
+	hourly  string
+}
+
+// effectiveTransferLimit merges the limits of an account, its owner and its currency field by field,
+// the most specific scope setting a field wins
+func effectiveTransferLimit(limits []TransferLimit) (TransferLimit, transferLimitScopes) {
+	var effective TransferLimit
+	var scopes transferLimitScopes
+	for _, scope := range []string{TransferLimitScopeCurrency, TransferLimitScopeUser, TransferLimitScopeAccount} {
+		for _, limit := range limits {
+			if limit.Scope != scope {
+				continue
+			}
+			if limit.MaxAmount.Valid {
+				effective.MaxAmount = limit.MaxAmount
+			}
+			if limit.DailyAmount.Valid {
+				effective.DailyAmount = limit.DailyAmount
+				scopes.daily = scope
+			}
+			if limit.MonthlyAmount.Valid {
+				effective.MonthlyAmount = limit.MonthlyAmount
+				scopes.monthly = scope
+			}
+			if limit.HourlyCount.Valid {
+				effective.HourlyCount = limit.HourlyCount
+				scopes.hourly = scope
+			}
+		}
+	}
+	return effective, scopes
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountTransfers(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 1000)
+
+	transfer := func(from Account, to Account, amount int64) Transfer {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountId: from.ID,
+			ToAccountId:   to.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result.Transfer
+	}
+	out1 := transfer(account1, account2, 10)
+	in1 := transfer(account2, account1, 200)
+	out2 := transfer(account1, account2, 300)
+
+	all, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{out2.ID, in1.ID, out1.ID}, transferIDs(all))
+
+	outgoing, err := testQueries.ListOutgoingTransfers(context.Background(), ListOutgoingTransfersParams{
+		AccountID: account1.ID,
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{out2.ID, out1.ID}, transferIDs(outgoing))
+
+	incoming, err := testQueries.ListIncomingTransfers(context.Background(), ListIncomingTransfersParams{
+		AccountID: account1.ID,
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{in1.ID}, transferIDs(incoming))
+
+	large, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		MinAmount: 100,
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{out2.ID, in1.ID}, transferIDs(large))
+
+	// a period ending before the first transfer is empty
+	none, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		ToTime:    sql.NullTime{Time: out1.CreatedAt, Valid: true},
+		RowLimit:  10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, none)
+}
+
+func transferIDs(transfers []Transfer) []int64 {
+	ids := make([]int64, 0, len(transfers))
+	for _, transfer := range transfers {
+		ids = append(ids, transfer.ID)
+	}
+	return ids
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AccountStatementTxParams contains the input needed to build the statement of an account,
+// the period starts at From included and ends at To excluded, either side may be left open
+type AccountStatementTxParams struct {
+	AccountID int64        `json:"account_id"`
+	From      sql.NullTime `json:"from"`
+	To        sql.NullTime `json:"to"`
+	Limit     int32        `json:"limit"`
+	Offset    int32        `json:"offset"`
+}
+
+// AccountStatementTxResult is a page of the entries of the period with the balance
+// of the account at its start and at its end
+type AccountStatementTxResult struct {
+	OpeningBalance int64                     `json:"opening_balance"`
+	ClosingBalance int64                     `json:"closing_balance"`
+	Entries        []ListEntriesByAccountRow `json:"entries"`
+}
+
+// AccountStatementTx reads the entries of an account over a period along with their running balances,
+// balances are worked back from the current balance so accounts opened with funds add up too
+func (store *SQLStore) AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error) {
+	var result AccountStatementTxResult
+
+	err := store.execSnapshotTx(ctx, func(q *Queries) error {
+		balances, err := q.GetAccountPeriodBalances(ctx, GetAccountPeriodBalancesParams{
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+			AccountID: arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+		result.OpeningBalance = balances.OpeningBalance
+		result.ClosingBalance = balances.ClosingBalance
+
+		// running balances start from the opening balance and only sum the entries
+		// of the period up to the page, older history is never read again
+		result.Entries, err = q.ListEntriesByAccount(ctx, ListEntriesByAccountParams{
+			OpeningBalance: balances.OpeningBalance,
+			AccountID:      arg.AccountID,
+			FromTime:       arg.From,
+			ToTime:         arg.To,
+			RowLimit:       arg.Limit,
+			RowOffset:      arg.Offset,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// statementExportBatchSize is the number of entries ExportAccountStatementTx reads at a time
+const statementExportBatchSize = 500
+
+// ExportAccountStatementTxParams contains the input needed to export the statement of an account,
+// the period starts at From included and ends at To excluded, either side may be left open
+type ExportAccountStatementTxParams struct {
+	AccountID int64
+	From      sql.NullTime
+	To        sql.NullTime
+	// OnBalances runs before the first entry with the balances at the start and at the end of the period
+	OnBalances func(openingBalance int64, closingBalance int64) error
+	// OnEntry runs for every entry of the period in posting order with the balance it left,
+	// an error stops the export
+	OnEntry func(entry ListAccountStatementEntriesRow, balanceAfter int64) error
+}
+
+// ExportAccountStatementTx walks the entries of an account over a period batch by batch,
+// so a statement of any length never sits in memory as a whole
+func (store *SQLStore) ExportAccountStatementTx(ctx context.Context, arg ExportAccountStatementTxParams) error {
+	return store.execSnapshotTx(ctx, func(q *Queries) error {
+		balances, err := q.GetAccountPeriodBalances(ctx, GetAccountPeriodBalancesParams{
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+			AccountID: arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+		err = arg.OnBalances(balances.OpeningBalance, balances.ClosingBalance)
+		if err != nil {
+			return err
+		}
+
+		balance := balances.OpeningBalance
+		var afterID int64
+		for {
+			entries, err := q.ListAccountStatementEntries(ctx, ListAccountStatementEntriesParams{
+				AccountID: arg.AccountID,
+				AfterID:   afterID,
+				FromTime:  arg.From,
+				ToTime:    arg.To,
+				RowLimit:  statementExportBatchSize,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				balance += entry.Amount
+				err = arg.OnEntry(entry, balance)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(entries) < statementExportBatchSize {
+				return nil
+			}
+			afterID = entries[len(entries)-1].ID
+		}
+	})
+}
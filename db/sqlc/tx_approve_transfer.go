@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrTransferNotPendingApproval is returned when reviewing a transfer that isn't waiting for approval
+	ErrTransferNotPendingApproval = errors.New("transfer is not pending approval")
+	// ErrSelfApproval is returned when the owner of the source account reviews their own transfer
+	ErrSelfApproval = errors.New("a transfer can't be reviewed by its maker")
+	// ErrApprovalRequired is returned for batch legs and holds above the approval threshold,
+	// such amounts only go through approval as transfers of their own
+	ErrApprovalRequired = errors.New("amount is above the approval threshold, send it as a single transfer")
+)
+
+// RequiresApproval tells whether a transfer of amount waits for a second person to approve it,
+// a zero threshold turns approvals off
+func RequiresApproval(threshold int64, amount int64) bool {
+	return threshold > 0 && amount > threshold
+}
+
+// ApproveTransferTxParams contains the input needed to approve a transfer pending approval
+type ApproveTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	Approver   string `json:"approver"`
+}
+
+// ApproveTransferTx moves the money of a transfer pending approval at the rate it was recorded with,
+// fees, funds and limits are checked as for TransferTx at the time of approval. The transfer is posted
+// with the approver and the approval time, a failed approval leaves it pending
+func (store *SQLStore) ApproveTransferTx(ctx context.Context, arg ApproveTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		pending, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if pending.Status != TransferStatusPendingApproval {
+			return ErrTransferNotPendingApproval
+		}
+
+		fromAccount, err := q.GetAccount(ctx, pending.FromAccountID)
+		if err != nil {
+			return err
+		}
+		if fromAccount.Owner == arg.Approver {
+			return ErrSelfApproval
+		}
+
+		transfer := TransferTxParams{
+			FromAccountId: pending.FromAccountID,
+			ToAccountId:   pending.ToAccountID,
+			Amount:        pending.Amount,
+			FXQuoteID:     pending.FxQuoteID,
+		}
+		result, err = store.postTransfer(ctx, q, transfer,
+			func(Account, Account) (int64, string, error) {
+				return pending.ToAmount, pending.ExchangeRate, nil
+			},
+			func(params CreateTransferParams) (Transfer, error) {
+				return q.ApproveTransfer(ctx, ApproveTransferParams{
+					ID:         pending.ID,
+					Fee:        params.Fee,
+					ReviewedBy: sql.NullString{String: arg.Approver, Valid: true},
+				})
+			},
+		)
+		return err
+	})
+
+	return result, err
+}
+
+// recordPendingTransfer records a transfer waiting for approval without moving money, its fx quote
+// is used up right away so the transfer keeps the quoted rate however long the approval takes
+func recordPendingTransfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountId)
+	if err != nil {
+		return result, err
+	}
+	toAccount, err := q.GetAccount(ctx, arg.ToAccountId)
+	if err != nil {
+		return result, err
+	}
+
+	toAmount, rate, err := exchange(ctx, q, arg.FXQuoteID, arg.Amount, fromAccount, toAccount)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.CreatePendingTransfer(ctx, CreatePendingTransferParams{
+		FromAccountID: arg.FromAccountId,
+		ToAccountID:   arg.ToAccountId,
+		Amount:        arg.Amount,
+		Currency:      fromAccount.Currency,
+		ToAmount:      toAmount,
+		ToCurrency:    toAccount.Currency,
+		ExchangeRate:  rate,
+		FxQuoteID:     arg.FXQuoteID,
+	})
+	result.FromAccount, result.ToAccount = fromAccount, toAccount
+	return result, err
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApproveTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	maker := createRandomUser(t)
+	approver := createRandomUser(t)
+	account1 := createAccountWithBalance(t, maker, "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	pending, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId:   account1.ID,
+		ToAccountId:     account2.ID,
+		Amount:          600,
+		RequireApproval: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPendingApproval, pending.Transfer.Status)
+	require.False(t, pending.Transfer.ReviewedBy.Valid)
+	require.Empty(t, pending.FromEntry)
+
+	// no money moves before the approval
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	_, err = store.ApproveTransferTx(context.Background(), ApproveTransferTxParams{
+		TransferID: pending.Transfer.ID,
+		Approver:   maker.Username,
+	})
+	require.ErrorIs(t, err, ErrSelfApproval)
+
+	result, err := store.ApproveTransferTx(context.Background(), ApproveTransferTxParams{
+		TransferID: pending.Transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, pending.Transfer.ID, result.Transfer.ID)
+	require.Equal(t, TransferStatusPosted, result.Transfer.Status)
+	require.Equal(t, sql.NullString{String: approver.Username, Valid: true}, result.Transfer.ReviewedBy)
+	require.WithinDuration(t, time.Now(), result.Transfer.ReviewedAt.Time, time.Second)
+	require.Equal(t, int64(-600), result.FromEntry.Amount)
+	require.Equal(t, int64(600), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-600, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+600, result.ToAccount.Balance)
+
+	_, err = store.ApproveTransferTx(context.Background(), ApproveTransferTxParams{
+		TransferID: pending.Transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferNotPendingApproval)
+}
+
+func TestApproveTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	// funds are only checked on approval
+	pending, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId:   account1.ID,
+		ToAccountId:     account2.ID,
+		Amount:          500,
+		RequireApproval: true,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ApproveTransferTx(context.Background(), ApproveTransferTxParams{
+		TransferID: pending.Transfer.ID,
+		Approver:   createRandomUser(t).Username,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	transfer, err := store.GetTransfer(context.Background(), pending.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPendingApproval, transfer.Status)
+}
+
+func TestRejectTransfer(t *testing.T) {
+	store := NewStore(testDB)
+	reviewer := createRandomUser(t)
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	pending, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId:   account1.ID,
+		ToAccountId:     account2.ID,
+		Amount:          600,
+		RequireApproval: true,
+	})
+	require.NoError(t, err)
+
+	rejected, err := store.RejectTransfer(context.Background(), RejectTransferParams{
+		ID:         pending.Transfer.ID,
+		ReviewedBy: sql.NullString{String: reviewer.Username, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusRejected, rejected.Status)
+	require.Equal(t, reviewer.Username, rejected.ReviewedBy.String)
+
+	_, err = store.RejectTransfer(context.Background(), RejectTransferParams{
+		ID:         pending.Transfer.ID,
+		ReviewedBy: sql.NullString{String: reviewer.Username, Valid: true},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.ApproveTransferTx(context.Background(), ApproveTransferTxParams{
+		TransferID: pending.Transfer.ID,
+		Approver:   reviewer.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferNotPendingApproval)
+}
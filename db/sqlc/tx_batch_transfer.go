@@ -0,0 +1,288 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// modes of a batch transfer, an atomic batch moves money for every leg or none of them
+// while a best effort batch makes the legs it can and reports the others as failed
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// statuses of a batch and of its items
+const (
+	TransferBatchStatusSucceeded = "succeeded"
+	TransferBatchStatusPartial   = "partial"
+	TransferBatchStatusFailed    = "failed"
+
+	TransferBatchItemStatusSucceeded = "succeeded"
+	TransferBatchItemStatusFailed    = "failed"
+)
+
+// ErrDestinationNotFound is returned for a batch leg paying into an account that doesn't exist
+var ErrDestinationNotFound = errors.New("destination account not found")
+
+// BatchTransferLeg is a single payment of a batch
+type BatchTransferLeg struct {
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+}
+
+// BatchTransferTxParams contains the input needed to pay several accounts from one source account
+type BatchTransferTxParams struct {
+	Owner         string             `json:"owner"`
+	FromAccountID int64              `json:"from_account_id"`
+	Mode          string             `json:"mode"`
+	Legs          []BatchTransferLeg `json:"legs"`
+	// ApprovalThreshold fails the legs that would require approval as single transfers, zero turns the check off
+	ApprovalThreshold int64 `json:"approval_threshold"`
+	// Idempotency makes retries of the same request return the first batch instead of paying it again
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+// BatchTransferTxResult is the result of the batch transaction, Items follow the order of the legs
+type BatchTransferTxResult struct {
+	Batch       TransferBatch       `json:"batch"`
+	Items       []TransferBatchItem `json:"items"`
+	FromAccount Account             `json:"from_account"`
+	// Replayed is set when the batch was made by an earlier request with the same idempotency key
+	Replayed bool `json:"-"`
+}
+
+// BatchTransferTx makes every leg of a batch within a single database transaction.
+// All the accounts involved are locked up front in id order so batches and transfers
+// touching the same accounts can't deadlock, legs are then checked against the running
+// balance of the source account and its transfer limits. Each leg pays the fee of a transfer of its amount.
+// An atomic batch fails on its first invalid leg with an error naming it, a best effort
+// batch records the leg as failed and goes on
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	replayed, err := store.execIdempotentTx(ctx, arg.Idempotency, &result, func(q *Queries) error {
+		// legs are priced before locking as an account never changes currency
+		source, err := q.GetAccount(ctx, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+		fees := make([]int64, len(arg.Legs))
+		var revenueAccountID int64
+		for i, leg := range arg.Legs {
+			var legRevenueAccountID int64
+			fees[i], legRevenueAccountID = store.fee(source.Currency, leg.Amount)
+			if legRevenueAccountID != 0 {
+				revenueAccountID = legRevenueAccountID
+			}
+		}
+
+		accounts, err := lockBatchAccounts(ctx, q, arg, revenueAccountID)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[arg.FromAccountID]
+		limits, err := newTransferLimitCheck(ctx, q, fromAccount, time.Now())
+		if err != nil {
+			return err
+		}
+
+		// amounts moved per account, applied once all legs are known
+		deltas := make(map[int64]int64)
+		legErrs := make([]error, len(arg.Legs))
+		succeeded := 0
+		for i, leg := range arg.Legs {
+			toAccount, ok := accounts[leg.ToAccountID]
+			switch {
+			case !ok:
+				legErrs[i] = ErrDestinationNotFound
+			case toAccount.Currency != fromAccount.Currency:
+				legErrs[i] = ErrCurrencyMismatch
+			case RequiresApproval(arg.ApprovalThreshold, leg.Amount):
+				legErrs[i] = ErrApprovalRequired
+			case availableBalance(fromAccount)+deltas[fromAccount.ID] < leg.Amount+fees[i]:
+				legErrs[i] = ErrInsufficientFunds
+			default:
+				// legs count against the limits of the source account like separate transfers
+				legErrs[i] = limits.add(leg.Amount)
+			}
+			if legErrs[i] == nil {
+				deltas[fromAccount.ID] -= leg.Amount + fees[i]
+				deltas[toAccount.ID] += leg.Amount
+				if fees[i] > 0 {
+					deltas[revenueAccountID] += fees[i]
+				}
+				succeeded++
+				continue
+			}
+			if arg.Mode == BatchModeAtomic {
+				return fmt.Errorf("leg %d: %w", i, legErrs[i])
+			}
+		}
+
+		result.Batch, err = q.CreateTransferBatch(ctx, CreateTransferBatchParams{
+			Owner:          arg.Owner,
+			FromAccountID:  arg.FromAccountID,
+			Currency:       fromAccount.Currency,
+			Mode:           arg.Mode,
+			Status:         batchStatus(succeeded, len(arg.Legs)),
+			LegCount:       int64(len(arg.Legs)),
+			SucceededCount: int64(succeeded),
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Items = make([]TransferBatchItem, 0, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			item := CreateTransferBatchItemParams{
+				BatchID:     result.Batch.ID,
+				Leg:         int32(i),
+				ToAccountID: leg.ToAccountID,
+				Amount:      leg.Amount,
+				Status:      TransferBatchItemStatusSucceeded,
+			}
+			if legErrs[i] != nil {
+				item.Status = TransferBatchItemStatusFailed
+				item.Error = legErrs[i].Error()
+			} else {
+				item.TransferID, err = createBatchLegTransfer(ctx, q, fromAccount, leg, fees[i], accounts[revenueAccountID])
+				if err != nil {
+					return err
+				}
+			}
+
+			batchItem, err := q.CreateTransferBatchItem(ctx, item)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, batchItem)
+		}
+
+		ids := make([]int64, 0, len(deltas))
+		for id := range deltas {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		result.FromAccount = fromAccount
+		for _, id := range ids {
+			account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+				ID:     id,
+				Amount: deltas[id],
+			})
+			if err != nil {
+				return err
+			}
+			if id == arg.FromAccountID {
+				result.FromAccount = account
+			}
+		}
+		return nil
+	})
+	result.Replayed = replayed
+
+	return result, err
+}
+
+// lockBatchAccounts locks the source account, the fee revenue account if any and every destination
+// of a batch in id order, destinations that don't exist are left out of the returned accounts
+func lockBatchAccounts(ctx context.Context, q *Queries, arg BatchTransferTxParams, revenueAccountID int64) (map[int64]Account, error) {
+	ids := []int64{arg.FromAccountID}
+	seen := map[int64]bool{arg.FromAccountID: true}
+	if revenueAccountID != 0 && !seen[revenueAccountID] {
+		seen[revenueAccountID] = true
+		ids = append(ids, revenueAccountID)
+	}
+	for _, leg := range arg.Legs {
+		if !seen[leg.ToAccountID] {
+			seen[leg.ToAccountID] = true
+			ids = append(ids, leg.ToAccountID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows && id != arg.FromAccountID && id != revenueAccountID {
+				continue
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// createBatchLegTransfer records the transfer and entries of a leg along with its fee, if any,
+// balances are updated by the caller
+func createBatchLegTransfer(
+	ctx context.Context,
+	q *Queries,
+	fromAccount Account,
+	leg BatchTransferLeg,
+	fee int64,
+	revenueAccount Account,
+) (sql.NullInt64, error) {
+	transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   leg.ToAccountID,
+		Amount:        leg.Amount,
+		Currency:      fromAccount.Currency,
+		ToAmount:      leg.Amount,
+		ToCurrency:    fromAccount.Currency,
+		ExchangeRate:  "1",
+		Fee:           fee,
+	})
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	_, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  fromAccount.ID,
+		Amount:     -leg.Amount,
+		Currency:   fromAccount.Currency,
+		TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+		Kind:       EntryKindTransfer,
+	})
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	_, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  leg.ToAccountID,
+		Amount:     leg.Amount,
+		Currency:   fromAccount.Currency,
+		TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+		Kind:       EntryKindTransfer,
+	})
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	if fee > 0 {
+		_, err = chargeFee(ctx, q, transfer.ID, fromAccount, revenueAccount, fee)
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+	}
+
+	return sql.NullInt64{Int64: transfer.ID, Valid: true}, nil
+}
+
+func batchStatus(succeeded int, legs int) string {
+	switch succeeded {
+	case legs:
+		return TransferBatchStatusSucceeded
+	case 0:
+		return TransferBatchStatusFailed
+	default:
+		return TransferBatchStatusPartial
+	}
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferTxAtomic(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 1000)
+	toAccount1 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	toAccount2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	arg := BatchTransferTxParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		Mode:          BatchModeAtomic,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: toAccount1.ID, Amount: 300},
+			{ToAccountID: toAccount2.ID, Amount: 200},
+			{ToAccountID: toAccount1.ID, Amount: 100},
+		},
+	}
+
+	result, err := store.BatchTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchStatusSucceeded, result.Batch.Status)
+	require.Equal(t, int64(3), result.Batch.LegCount)
+	require.Equal(t, int64(3), result.Batch.SucceededCount)
+	require.Equal(t, int64(400), result.FromAccount.Balance)
+
+	require.Len(t, result.Items, 3)
+	for i, item := range result.Items {
+		require.Equal(t, int32(i), item.Leg)
+		require.Equal(t, TransferBatchItemStatusSucceeded, item.Status)
+		require.True(t, item.TransferID.Valid)
+
+		transfer, err := store.GetTransfer(context.Background(), item.TransferID.Int64)
+		require.NoError(t, err)
+		require.Equal(t, arg.Legs[i].ToAccountID, transfer.ToAccountID)
+		require.Equal(t, arg.Legs[i].Amount, transfer.Amount)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), toAccount1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(400), updatedAccount1.Balance)
+
+	items, err := store.ListTransferBatchItems(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Items, items)
+
+	// more than what's left fails the batch as a whole
+	arg.Legs = []BatchTransferLeg{
+		{ToAccountID: toAccount1.ID, Amount: 300},
+		{ToAccountID: toAccount2.ID, Amount: 300},
+	}
+	_, err = store.BatchTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	require.Contains(t, err.Error(), "leg 1")
+
+	updatedFromAccount, err := store.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(400), updatedFromAccount.Balance)
+}
+
+func TestBatchTransferTxBestEffort(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 500)
+	toAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	euroAccount := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		Mode:          BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: toAccount.ID, Amount: 400},
+			{ToAccountID: toAccount.ID, Amount: 200},
+			{ToAccountID: euroAccount.ID, Amount: 50},
+			{ToAccountID: -1, Amount: 50},
+			{ToAccountID: toAccount.ID, Amount: 100},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchStatusPartial, result.Batch.Status)
+	require.Equal(t, int64(2), result.Batch.SucceededCount)
+	require.Zero(t, result.FromAccount.Balance)
+
+	expected := []string{"", ErrInsufficientFunds.Error(), ErrCurrencyMismatch.Error(), ErrDestinationNotFound.Error(), ""}
+	for i, item := range result.Items {
+		require.Equal(t, expected[i], item.Error)
+		require.Equal(t, expected[i] == "", item.TransferID.Valid)
+	}
+
+	updatedAccount, err := store.GetAccount(context.Background(), toAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(500), updatedAccount.Balance)
+}
+
+func TestBatchTransferTxFee(t *testing.T) {
+	revenueAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	store := NewStore(testDB, WithFeeSchedule(flatFeeSchedule{fee: 5, revenueAccountID: revenueAccount.ID}))
+
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 215)
+	toAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	// the second leg can't cover its fee once the first one is paid
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:         user.Username,
+		FromAccountID: fromAccount.ID,
+		Mode:          BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: toAccount.ID, Amount: 100},
+			{ToAccountID: toAccount.ID, Amount: 110},
+			{ToAccountID: toAccount.ID, Amount: 100},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchStatusPartial, result.Batch.Status)
+	require.Equal(t, TransferBatchItemStatusSucceeded, result.Items[0].Status)
+	require.Equal(t, TransferBatchItemStatusFailed, result.Items[1].Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Items[1].Error)
+	require.Equal(t, TransferBatchItemStatusSucceeded, result.Items[2].Status)
+	require.Equal(t, int64(5), result.FromAccount.Balance)
+
+	transfer, err := store.GetTransfer(context.Background(), result.Items[0].TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), transfer.Fee)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), updatedRevenueAccount.Balance)
+}
+
+func TestBatchTransferTxApprovalThreshold(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	fromAccount := createAccountWithBalance(t, user, "USD", 1000)
+	toAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:             user.Username,
+		FromAccountID:     fromAccount.ID,
+		Mode:              BatchModeBestEffort,
+		ApprovalThreshold: 150,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: toAccount.ID, Amount: 200},
+			{ToAccountID: toAccount.ID, Amount: 150},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchItemStatusFailed, result.Items[0].Status)
+	require.Equal(t, ErrApprovalRequired.Error(), result.Items[0].Error)
+	require.Equal(t, TransferBatchItemStatusSucceeded, result.Items[1].Status)
+	require.Equal(t, int64(850), result.FromAccount.Balance)
+
+	_, err = store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Owner:             user.Username,
+		FromAccountID:     fromAccount.ID,
+		Mode:              BatchModeAtomic,
+		ApprovalThreshold: 150,
+		Legs:              []BatchTransferLeg{{ToAccountID: toAccount.ID, Amount: 200}},
+	})
+	require.ErrorIs(t, err, ErrApprovalRequired)
+}
+
+func TestBatchTransferTxDeadlock(t *testing.T) {
+	store := NewStore(testDB)
+
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user1, "USD", 1000)
+	account2 := createAccountWithBalance(t, user2, "USD", 1000)
+	account3 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	// batches paying into each other's source account concurrently
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		arg := BatchTransferTxParams{
+			Owner:         user1.Username,
+			FromAccountID: account1.ID,
+			Mode:          BatchModeAtomic,
+			Legs: []BatchTransferLeg{
+				{ToAccountID: account3.ID, Amount: 10},
+				{ToAccountID: account2.ID, Amount: 10},
+			},
+		}
+		if i%2 == 1 {
+			arg.Owner = user2.Username
+			arg.FromAccountID = account2.ID
+			arg.Legs[1].ToAccountID = account1.ID
+		}
+
+		go func() {
+			_, err := store.BatchTransferTx(context.Background(), arg)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount3, err := store.GetAccount(context.Background(), account3.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(950), updatedAccount1.Balance)
+	require.Equal(t, int64(100), updatedAccount3.Balance)
+}
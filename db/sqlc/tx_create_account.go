@@ -0,0 +1,31 @@
+package db
+
+import "context"
+
+// CreateAccountTxParams contains the input needed to open an account
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	// Idempotency makes retries of the same request return the account opened the first time
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+// CreateAccountTxResult is the result of the account opening transaction
+type CreateAccountTxResult struct {
+	Account Account `json:"account"`
+	// Replayed is set when the account was opened by an earlier request with the same idempotency key
+	Replayed bool `json:"-"`
+}
+
+// CreateAccountTx opens an account, at most once per idempotency key
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (CreateAccountTxResult, error) {
+	var result CreateAccountTxResult
+
+	replayed, err := store.execIdempotentTx(ctx, arg.Idempotency, &result.Account, func(q *Queries) error {
+		var err error
+		result.Account, err = q.CreateAccount(ctx, arg.CreateAccountParams)
+		return err
+	})
+	result.Replayed = replayed
+
+	return result, err
+}
@@ -0,0 +1,47 @@
+package db
+
+import "context"
+
+// CreateUserTxParams contains the input needed to sign a user up
+type CreateUserTxParams struct {
+	CreateUserParams
+	SecretCodeHash string `json:"-"`
+	// AfterCreate runs inside the transaction, an error rolls the signup back
+	AfterCreate func(user User, verifyEmail VerifyEmail) error
+}
+
+// CreateUserTxResult is the result of the signup transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user along with the code verifying their email within a single database transaction
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate != nil {
+			return arg.AfterCreate(result.User, result.VerifyEmail)
+		}
+		return nil
+	})
+
+	return result, err
+}
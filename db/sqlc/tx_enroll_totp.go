@@ -0,0 +1,54 @@
+package db
+
+import "context"
+
+// EnrollTOTPTxParams contains the input needed to (re)enroll a user in TOTP
+type EnrollTOTPTxParams struct {
+	Username            string   `json:"username"`
+	Secret              string   `json:"secret"`
+	HashedRecoveryCodes []string `json:"-"`
+}
+
+// EnrollTOTPTxResult is the result of the TOTP enrollment transaction
+type EnrollTOTPTxResult struct {
+	TotpSecret    TotpSecret     `json:"totp_secret"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// EnrollTOTPTx stores a new unconfirmed TOTP secret and replaces the user's recovery codes
+// within a single database transaction, it returns sql.ErrNoRows when TOTP is already confirmed
+func (store *SQLStore) EnrollTOTPTx(ctx context.Context, arg EnrollTOTPTxParams) (EnrollTOTPTxResult, error) {
+	var result EnrollTOTPTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.TotpSecret, err = q.UpsertTOTPSecret(ctx, UpsertTOTPSecretParams{
+			Username: arg.Username,
+			Secret:   arg.Secret,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			code, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, code)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// statuses of a transfer, immediate transfers are posted right away while holds
+// stay pending until they are captured, voided or expire. Transfers requiring approval
+// stay pending approval until they are posted or rejected
+const (
+	TransferStatusPosted          = "posted"
+	TransferStatusPending         = "pending"
+	TransferStatusCaptured        = "captured"
+	TransferStatusVoided          = "voided"
+	TransferStatusExpired         = "expired"
+	TransferStatusPendingApproval = "pending_approval"
+	TransferStatusRejected        = "rejected"
+)
+
+var (
+	// ErrTransferNotPending is returned when capturing or releasing a transfer that isn't a pending hold
+	ErrTransferNotPending = errors.New("transfer is not a pending hold")
+	// ErrHoldExpired is returned when capturing a hold past its expiry
+	ErrHoldExpired = errors.New("hold has expired")
+	// ErrCaptureExceedsHold is returned when capturing more than the amount held
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+// AuthorizeTransferTxParams contains the input needed to hold funds for a later transfer
+type AuthorizeTransferTxParams struct {
+	FromAccountId int64     `json:"from_account_id"`
+	ToAccountId   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	// Idempotency makes retries of the same request return the first hold instead of holding funds again
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+// AuthorizeTransferTxResult is the result of the hold transaction
+type AuthorizeTransferTxResult struct {
+	Transfer    Transfer `json:"transfer"`
+	FromAccount Account  `json:"from_account"`
+	// Replayed is set when the hold was made by an earlier request with the same idempotency key
+	Replayed bool `json:"-"`
+}
+
+// AuthorizeTransferTx creates a pending transfer and reserves its amount on the source account,
+// no money moves until the hold is captured. The hold is checked against the transfer limits
+// of the source account and counts against them until it is voided or expires
+func (store *SQLStore) AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (AuthorizeTransferTxResult, error) {
+	var result AuthorizeTransferTxResult
+
+	replayed, err := store.execIdempotentTx(ctx, arg.Idempotency, &result, func(q *Queries) error {
+		fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountId, arg.ToAccountId)
+		if err != nil {
+			return err
+		}
+		if fromAccount.Currency != toAccount.Currency {
+			return ErrCurrencyMismatch
+		}
+		if availableBalance(fromAccount) < arg.Amount {
+			return ErrInsufficientFunds
+		}
+		err = checkTransferLimits(ctx, q, fromAccount, arg.Amount, time.Now())
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.CreateTransferHold(ctx, CreateTransferHoldParams{
+			FromAccountID: arg.FromAccountId,
+			ToAccountID:   arg.ToAccountId,
+			Amount:        arg.Amount,
+			Currency:      fromAccount.Currency,
+			ExpiresAt:     sql.NullTime{Time: arg.ExpiresAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     arg.FromAccountId,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+	result.Replayed = replayed
+
+	return result, err
+}
+
+// CaptureTransferTxParams contains the input needed to settle a hold
+type CaptureTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is moved to the destination account, anything held above it goes back to the source account
+	Amount int64 `json:"amount"`
+}
+
+// CaptureTransferTx settles a pending transfer for at most the amount held,
+// the whole hold is released and the captured amount moves like an immediate transfer.
+// The fee of the captured amount is charged on top of it, out of the funds that weren't held
+func (store *SQLStore) CaptureTransferTx(ctx context.Context, arg CaptureTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != TransferStatusPending {
+			return ErrTransferNotPending
+		}
+		if holdExpired(transfer) {
+			return ErrHoldExpired
+		}
+		if arg.Amount > transfer.AuthorizedAmount {
+			return ErrCaptureExceedsHold
+		}
+
+		fee, revenueAccountID := store.fee(transfer.Currency, arg.Amount)
+		accounts, err := lockAccountSet(ctx, q, transfer.FromAccountID, transfer.ToAccountID, revenueAccountID)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[transfer.FromAccountID]
+		if availableBalance(fromAccount)+transfer.AuthorizedAmount < arg.Amount+fee {
+			return ErrInsufficientFunds
+		}
+
+		_, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     transfer.FromAccountID,
+			Amount: -transfer.AuthorizedAmount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.CaptureTransfer(ctx, CaptureTransferParams{
+			ID:     transfer.ID,
+			Amount: arg.Amount,
+			Fee:    fee,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  transfer.FromAccountID,
+			Amount:     -arg.Amount,
+			Currency:   transfer.Currency,
+			TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+			Kind:       EntryKindTransfer,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  transfer.ToAccountID,
+			Amount:     arg.Amount,
+			Currency:   transfer.ToCurrency,
+			TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+			Kind:       EntryKindTransfer,
+		})
+		if err != nil {
+			return err
+		}
+
+		deltas := make(map[int64]int64)
+		deltas[transfer.FromAccountID] -= arg.Amount
+		deltas[transfer.ToAccountID] += arg.Amount
+		if fee > 0 {
+			result.Fee, err = chargeFee(ctx, q, transfer.ID, fromAccount, accounts[revenueAccountID], fee)
+			if err != nil {
+				return err
+			}
+			deltas[transfer.FromAccountID] -= fee
+			deltas[revenueAccountID] += fee
+		}
+
+		accounts, err = addBalances(ctx, q, deltas)
+		if err != nil {
+			return err
+		}
+		result.FromAccount, result.ToAccount = accounts[transfer.FromAccountID], accounts[transfer.ToAccountID]
+		return nil
+	})
+
+	return result, err
+}
+
+// ReleaseTransferTxResult is the result of voiding or expiring a hold
+type ReleaseTransferTxResult struct {
+	Transfer    Transfer `json:"transfer"`
+	FromAccount Account  `json:"from_account"`
+}
+
+// VoidTransferTx cancels a pending transfer and gives the held amount back to the source account
+func (store *SQLStore) VoidTransferTx(ctx context.Context, transferID int64) (ReleaseTransferTxResult, error) {
+	var result ReleaseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, err := q.GetTransferForUpdate(ctx, transferID)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != TransferStatusPending {
+			return ErrTransferNotPending
+		}
+
+		result, err = releaseHold(ctx, q, transfer, TransferStatusVoided)
+		return err
+	})
+
+	return result, err
+}
+
+// ExpireTransferTx releases a pending transfer past its expiry,
+// it returns ErrTransferNotPending when the hold was settled or isn't due yet
+func (store *SQLStore) ExpireTransferTx(ctx context.Context, transferID int64) (ReleaseTransferTxResult, error) {
+	var result ReleaseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, err := q.GetTransferForUpdate(ctx, transferID)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != TransferStatusPending || !holdExpired(transfer) {
+			return ErrTransferNotPending
+		}
+
+		result, err = releaseHold(ctx, q, transfer, TransferStatusExpired)
+		return err
+	})
+
+	return result, err
+}
+
+func releaseHold(ctx context.Context, q *Queries, transfer Transfer, status string) (ReleaseTransferTxResult, error) {
+	var result ReleaseTransferTxResult
+	var err error
+
+	result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		ID:     transfer.FromAccountID,
+		Amount: -transfer.AuthorizedAmount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.SetTransferStatus(ctx, SetTransferStatusParams{
+		ID:     transfer.ID,
+		Status: status,
+	})
+	return result, err
+}
+
+func holdExpired(transfer Transfer) bool {
+	return transfer.ExpiresAt.Valid && !time.Now().Before(transfer.ExpiresAt.Time)
+}
+
+// availableBalance is the part of the balance that isn't held for pending transfers
+func availableBalance(account Account) int64 {
+	return account.Balance - account.HeldBalance
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func authorizeRandomHold(t *testing.T, store Store, fromAccount Account, toAccount Account, amount int64, expiresAt time.Time) Transfer {
+	result, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountId: fromAccount.ID,
+		ToAccountId:   toAccount.ID,
+		Amount:        amount,
+		ExpiresAt:     expiresAt,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, TransferStatusPending, transfer.Status)
+	require.Equal(t, amount, transfer.Amount)
+	require.Equal(t, amount, transfer.AuthorizedAmount)
+	require.True(t, transfer.ExpiresAt.Valid)
+	require.WithinDuration(t, expiresAt, transfer.ExpiresAt.Time, time.Second)
+
+	// holding funds doesn't move them
+	require.Equal(t, fromAccount.Balance, result.FromAccount.Balance)
+	return transfer
+}
+
+func TestAuthorizeTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	authorizeRandomHold(t, store, account1, account2, 70, time.Now().Add(time.Hour))
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), updatedAccount1.Balance)
+	require.Equal(t, int64(70), updatedAccount1.HeldBalance)
+
+	// held funds can be neither held again nor transferred
+	_, err = store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        40,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        40,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        30,
+	})
+	require.NoError(t, err)
+}
+
+func TestAuthorizeTransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+
+	_, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestCaptureTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	hold := authorizeRandomHold(t, store, account1, account2, 70, time.Now().Add(time.Hour))
+
+	_, err := store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 71})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// a partial capture moves what is captured and releases the rest
+	result, err := store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 40})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusCaptured, result.Transfer.Status)
+	require.Equal(t, int64(40), result.Transfer.Amount)
+	require.Equal(t, int64(40), result.Transfer.ToAmount)
+	require.Equal(t, int64(70), result.Transfer.AuthorizedAmount)
+	require.Equal(t, int64(-40), result.FromEntry.Amount)
+	require.Equal(t, int64(40), result.ToEntry.Amount)
+	require.Equal(t, int64(60), result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+	require.Equal(t, int64(40), result.ToAccount.Balance)
+
+	_, err = store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 10})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = store.VoidTransferTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestCaptureTransferTxSameAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	hold := authorizeRandomHold(t, store, account, account, 70, time.Now().Add(time.Hour))
+
+	result, err := store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 70})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+}
+
+func TestCaptureTransferTxFee(t *testing.T) {
+	revenueAccount := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	store := NewStore(testDB, WithFeeSchedule(flatFeeSchedule{fee: 5, revenueAccountID: revenueAccount.ID}))
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	hold := authorizeRandomHold(t, store, account1, account2, 70, time.Now().Add(time.Hour))
+
+	result, err := store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 70})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), result.Transfer.Fee)
+	require.Equal(t, int64(25), result.FromAccount.Balance)
+	require.Equal(t, int64(70), result.ToAccount.Balance)
+
+	require.NotNil(t, result.Fee)
+	require.Equal(t, revenueAccount.ID, result.Fee.RevenueAccountID)
+	require.Equal(t, sql.NullInt64{Int64: hold.ID, Valid: true}, result.Fee.FromEntry.TransferID)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), updatedRevenueAccount.Balance)
+
+	// the hold covers the amount, the fee needs funds of its own
+	account3 := createAccountWithBalance(t, createRandomUser(t), "USD", 70)
+	hold = authorizeRandomHold(t, store, account3, account2, 70, time.Now().Add(time.Hour))
+
+	_, err = store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 70})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestCaptureTransferTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	hold := authorizeRandomHold(t, store, account1, account2, 70, time.Now().Add(-time.Second))
+
+	_, err := store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 70})
+	require.ErrorIs(t, err, ErrHoldExpired)
+}
+
+func TestVoidTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	hold := authorizeRandomHold(t, store, account1, account2, 70, time.Now().Add(time.Hour))
+
+	result, err := store.VoidTransferTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusVoided, result.Transfer.Status)
+	require.Equal(t, int64(100), result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount2.Balance)
+
+	_, err = store.VoidTransferTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestExpireTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	current := authorizeRandomHold(t, store, account1, account2, 20, time.Now().Add(time.Hour))
+	expired := authorizeRandomHold(t, store, account1, account2, 30, time.Now().Add(-time.Second))
+
+	ids, err := store.ListExpiredTransferHolds(context.Background(), 1000)
+	require.NoError(t, err)
+	require.Contains(t, ids, expired.ID)
+	require.NotContains(t, ids, current.ID)
+
+	_, err = store.ExpireTransferTx(context.Background(), current.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	result, err := store.ExpireTransferTx(context.Background(), expired.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusExpired, result.Transfer.Status)
+	require.Equal(t, int64(20), result.FromAccount.HeldBalance)
+
+	_, err = store.ExpireTransferTx(context.Background(), expired.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired or already used
+var ErrInvalidResetToken = errors.New("password reset token is invalid or has expired")
+
+// ResetPasswordTxParams contains the input needed to reset a password
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"-"`
+	HashedPassword string `json:"-"`
+}
+
+// ResetPasswordTxResult is the result of the password reset transaction
+type ResetPasswordTxResult struct {
+	User User `json:"user"`
+}
+
+// ResetPasswordTx consumes a reset token and sets the new password of its user,
+// every outstanding reset token, session and access token of the user is burnt
+// in the same database transaction
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		resetToken, err := q.GetPasswordResetTokenForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if resetToken.UsedAt.Valid || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:       resetToken.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.UsePasswordResetTokens(ctx, resetToken.Username)
+		if err != nil {
+			return err
+		}
+
+		err = q.BlockUserSessions(ctx, resetToken.Username)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeUserTokens(ctx, RevokeUserTokensParams{
+			Username:      resetToken.Username,
+			RevokedBefore: result.User.PasswordChanged,
+		})
+	})
+
+	return result, err
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+
+	"github.com/brkss/simplebank/utils"
+)
+
+var (
+	// ErrTransferNotReversible is returned when reversing a hold that didn't settle or a reversal itself
+	ErrTransferNotReversible = errors.New("only settled transfers can be reversed")
+	// ErrTransferAlreadyReversed is returned when the whole amount of a transfer was already given back
+	ErrTransferAlreadyReversed = errors.New("transfer was already fully reversed")
+	// ErrReversalExceedsTransfer is returned when refunding more than what is left of a transfer
+	ErrReversalExceedsTransfer = errors.New("reversal amount exceeds what is left of the transfer")
+)
+
+// ReverseTransferTxParams contains the input needed to give back all or part of a transfer
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is refunded to the sender in the currency they were debited in,
+	// zero reverses whatever wasn't refunded yet
+	Amount int64 `json:"amount"`
+	// Idempotency makes retries of the same request return the first reversal instead of refunding again
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+// ReverseTransferTx moves money back from the receiver of a transfer to its sender through a new transfer
+// linked to the original one, the refunds of a transfer never add up to more than its amount.
+// Cross-currency transfers are reversed at their original rate
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	replayed, err := store.execIdempotentTx(ctx, arg.Idempotency, &result, func(q *Queries) error {
+		// concurrent reversals of a transfer queue up on its row
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if original.ReversalOf.Valid ||
+			(original.Status != TransferStatusPosted && original.Status != TransferStatusCaptured) {
+			return ErrTransferNotReversible
+		}
+
+		reversed, err := q.GetReversedAmount(ctx, sql.NullInt64{Int64: original.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount - reversed
+		if remaining <= 0 {
+			return ErrTransferAlreadyReversed
+		}
+
+		refund := arg.Amount
+		if refund == 0 {
+			refund = remaining
+		}
+		if refund > remaining {
+			return ErrReversalExceedsTransfer
+		}
+
+		// what the receiver gives back in their currency, in proportion to what they got
+		debit := new(big.Int).Mul(big.NewInt(refund), big.NewInt(original.ToAmount))
+		debit.Quo(debit, big.NewInt(original.Amount))
+		if debit.Sign() <= 0 {
+			return ErrAmountTooSmall
+		}
+
+		fromAccount, _, err := lockAccounts(ctx, q, original.ToAccountID, original.FromAccountID)
+		if err != nil {
+			return err
+		}
+		if availableBalance(fromAccount) < debit.Int64() {
+			return ErrInsufficientFunds
+		}
+
+		rate := "1"
+		if original.Currency != original.ToCurrency {
+			rate = utils.FormatRate(new(big.Rat).SetFrac(big.NewInt(refund), debit))
+		}
+
+		result.Transfer, err = q.CreateTransferReversal(ctx, CreateTransferReversalParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        debit.Int64(),
+			Currency:      original.ToCurrency,
+			ToAmount:      refund,
+			ToCurrency:    original.Currency,
+			ExchangeRate:  rate,
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  original.ToAccountID,
+			Amount:     -debit.Int64(),
+			Currency:   original.ToCurrency,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+			Kind:       EntryKindTransfer,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  original.FromAccountID,
+			Amount:     refund,
+			Currency:   original.Currency,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+			Kind:       EntryKindTransfer,
+		})
+		if err != nil {
+			return err
+		}
+
+		if original.ToAccountID < original.FromAccountID {
+			result.FromAccount, result.ToAccount, err = AddMoney(ctx, q, original.ToAccountID, -debit.Int64(), original.FromAccountID, refund)
+		} else {
+			result.ToAccount, result.FromAccount, err = AddMoney(ctx, q, original.FromAccountID, refund, original.ToAccountID, -debit.Int64())
+		}
+		return err
+	})
+	result.Replayed = replayed
+
+	return result, err
+}
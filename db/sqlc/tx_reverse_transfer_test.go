@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        60,
+	})
+	require.NoError(t, err)
+
+	// a partial refund first
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     20,
+	})
+	require.NoError(t, err)
+
+	reversal := result.Transfer
+	require.Equal(t, account2.ID, reversal.FromAccountID)
+	require.Equal(t, account1.ID, reversal.ToAccountID)
+	require.Equal(t, int64(20), reversal.Amount)
+	require.Equal(t, int64(20), reversal.ToAmount)
+	require.True(t, reversal.ReversalOf.Valid)
+	require.Equal(t, original.Transfer.ID, reversal.ReversalOf.Int64)
+
+	require.Equal(t, int64(-20), result.FromEntry.Amount)
+	require.Equal(t, account2.ID, result.FromEntry.AccountID)
+	require.Equal(t, int64(20), result.ToEntry.Amount)
+	require.Equal(t, account1.ID, result.ToEntry.AccountID)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+	require.Equal(t, int64(60), result.ToAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     41,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	// no amount reverses what's left
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.Transfer.Amount)
+	require.Equal(t, int64(100), result.ToAccount.Balance)
+	require.Zero(t, result.FromAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	// reversals are final
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+
+	// only one full reversal can go through
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: original.Transfer.ID,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+	}
+	require.Equal(t, 1, succeeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), updatedAccount1.Balance)
+}
+
+func TestReverseTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1 := createAccountWithBalance(t, user, "USD", 1000)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "EUR", 0)
+	quote := createRandomFXQuote(t, user, "USD", "EUR", "0.92", time.Minute)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        100,
+		FXQuoteID:     uuid.NullUUID{UUID: quote.ID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// the receiver gives back at the original rate
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(46), result.Transfer.Amount)
+	require.Equal(t, "EUR", result.Transfer.Currency)
+	require.Equal(t, int64(50), result.Transfer.ToAmount)
+	require.Equal(t, "USD", result.Transfer.ToCurrency)
+	require.Equal(t, int64(46), result.FromAccount.Balance)
+	require.Equal(t, int64(950), result.ToAccount.Balance)
+}
+
+func TestReverseTransferTxHold(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithBalance(t, createRandomUser(t), "USD", 100)
+	account2 := createAccountWithBalance(t, createRandomUser(t), "USD", 0)
+	hold := authorizeRandomHold(t, store, account1, account2, 50, time.Now().Add(time.Hour))
+
+	_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: hold.ID})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+
+	_, err = store.CaptureTransferTx(context.Background(), CaptureTransferTxParams{TransferID: hold.ID, Amount: 30})
+	require.NoError(t, err)
+
+	// a captured hold is reversed up to the amount captured
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), result.Transfer.Amount)
+	require.Equal(t, int64(100), result.ToAccount.Balance)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// statuses of a scheduled transfer, it stays active until its schedule runs out
+// or a run fails in a way retrying can't fix
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusFailed    = "failed"
+)
+
+// outcomes of a single run of a scheduled transfer
+const (
+	ScheduledRunStatusSucceeded = "succeeded"
+	ScheduledRunStatusRetrying  = "retrying"
+	ScheduledRunStatusFailed    = "failed"
+)
+
+// ErrScheduledRunStale is returned when running an attempt of a scheduled transfer that was changed,
+// deleted, already recorded or is being run by someone else since it was listed due
+var ErrScheduledRunStale = errors.New("scheduled transfer changed since the run started")
+
+// ScheduledTransferRunOutcome contains the outcome of a run and where the schedule goes from there
+type ScheduledTransferRunOutcome struct {
+	Run      CreateScheduledTransferRunParams      `json:"run"`
+	Progress UpdateScheduledTransferProgressParams `json:"progress"`
+}
+
+// RunScheduledTransferTxParams contains the attempt of a scheduled transfer to run
+type RunScheduledTransferTxParams struct {
+	// ScheduledTransfer is the scheduled transfer as it was listed due, its next attempt is the one run
+	ScheduledTransfer ScheduledTransfer `json:"scheduled_transfer"`
+	// Transfer is made within the run, its idempotency is ignored as the claimed attempt already covers it
+	Transfer TransferTxParams `json:"transfer"`
+	// Outcome works out what to record from the result of the transfer, an error it returns rolls the run back.
+	// Transfers fail before moving any money so a failed one can still be recorded as such
+	Outcome func(result TransferTxResult, err error) (ScheduledTransferRunOutcome, error) `json:"-"`
+}
+
+// RunScheduledTransferTxResult is the result of running a scheduled transfer
+type RunScheduledTransferTxResult struct {
+	Transfer          TransferTxResult     `json:"transfer"`
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// RunScheduledTransferTx claims the next attempt of a scheduled transfer, makes its transfer and records
+// the outcome within a single database transaction, so an attempt moves money at most once.
+// The row is locked skipping a concurrent run, which gets ErrScheduledRunStale
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		scheduledTransfer, err := q.GetScheduledTransferForRun(ctx, arg.ScheduledTransfer.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrScheduledRunStale
+			}
+			return err
+		}
+		if scheduledTransfer.Status != ScheduledTransferStatusActive ||
+			!scheduledTransfer.NextRunAt.Valid ||
+			!scheduledTransfer.NextRunAt.Time.Equal(arg.ScheduledTransfer.NextRunAt.Time) ||
+			scheduledTransfer.Attempts != arg.ScheduledTransfer.Attempts {
+			return ErrScheduledRunStale
+		}
+
+		transfer := arg.Transfer
+		transfer.Idempotency = nil
+		result.Transfer, err = store.transfer(ctx, q, transfer)
+
+		outcome, err := arg.Outcome(result.Transfer, err)
+		if err != nil {
+			return err
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, outcome.Run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.UpdateScheduledTransferProgress(ctx, outcome.Progress)
+		return err
+	})
+
+	return result, err
+}
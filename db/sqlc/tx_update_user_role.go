@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// UpdateUserRoleTxParams contains the input needed to change the role of a user
+type UpdateUserRoleTxParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// UpdateUserRoleTxResult is the result of the role update transaction
+type UpdateUserRoleTxResult struct {
+	User User `json:"user"`
+}
+
+// UpdateUserRoleTx sets the role of a user, when it changes every session and token of the user
+// is burnt in the same database transaction so nothing keeps acting with the old role
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error) {
+	var result UpdateUserRoleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		if user.Role == arg.Role {
+			result.User = user
+			return nil
+		}
+
+		result.User, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.BlockUserSessions(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeUserTokens(ctx, RevokeUserTokensParams{
+			Username:      arg.Username,
+			RevokedBefore: time.Now(),
+		})
+	})
+
+	return result, err
+}
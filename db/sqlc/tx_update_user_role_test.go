@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	issuedAt := time.Now()
+
+	isRevoked := func() bool {
+		revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+			ID:       uuid.New(),
+			Username: user.Username,
+			IssuedAt: issuedAt,
+		})
+		require.NoError(t, err)
+		return revoked
+	}
+
+	// setting the role the user already holds signs nobody out
+	result, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username: user.Username,
+		Role:     user.Role,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Role, result.User.Role)
+	require.False(t, isRevoked())
+
+	result, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username: user.Username,
+		Role:     utils.BankerRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.BankerRole, result.User.Role)
+	require.True(t, isRevoked())
+
+	updatedSession, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, updatedSession.IsBlocked)
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInvalidVerifyEmail is returned when a verification code is unknown, expired or already used
+var ErrInvalidVerifyEmail = errors.New("email verification code is invalid or has expired")
+
+// VerifyEmailTxParams contains the input needed to verify an email
+type VerifyEmailTxParams struct {
+	EmailId        int64  `json:"email_id"`
+	SecretCodeHash string `json:"-"`
+}
+
+// VerifyEmailTxResult is the result of the email verification transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx consumes a verification code and flags the email of its user as verified,
+// the code is compared in constant time so its hash can't be guessed byte by byte
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, arg.EmailId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidVerifyEmail
+			}
+			return err
+		}
+		if verifyEmail.IsUsed || time.Now().After(verifyEmail.ExpiredAt) ||
+			subtle.ConstantTimeCompare([]byte(verifyEmail.SecretCodeHash), []byte(arg.SecretCodeHash)) != 1 {
+			return ErrInvalidVerifyEmail
+		}
+
+		result.VerifyEmail, err = q.UpdateVerifyEmail(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, result.VerifyEmail.Username)
+		return err
+	})
+
+	return result, err
+}
@@ -58,3 +58,13 @@ func TestGetUser(t *testing.T){
   require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second) 
 }
 
+
+func TestGetUserByEmail(t *testing.T){
+
+  user1 := createRandomUser(t);
+
+  user2, err := testQueries.GetUserByEmail(context.Background(), user1.Email)
+  require.NoError(t, err)
+  require.Equal(t, user1.Username, user2.Username);
+  require.Equal(t, user1.Email, user2.Email);
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTx(t *testing.T, store Store) (CreateUserTxResult, string) {
+	hashedPassword, err := utils.HashPassword(utils.RandomString(6))
+	require.NoError(t, err)
+	secretCode, err := utils.GenerateSecureToken(32)
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       utils.RandomOwner(),
+			FullName:       utils.RandomOwner(),
+			HashedPassword: hashedPassword,
+			Email:          utils.RandomEmail(),
+		},
+		SecretCodeHash: utils.HashSecureToken(secretCode),
+	}
+
+	var afterCreateUser User
+	arg.AfterCreate = func(user User, verifyEmail VerifyEmail) error {
+		afterCreateUser = user
+		return nil
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.User, afterCreateUser)
+
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.SecretCodeHash, result.VerifyEmail.SecretCodeHash)
+	require.False(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.VerifyEmail.ExpiredAt.After(result.VerifyEmail.CreatedAt))
+
+	return result, secretCode
+}
+
+func TestCreateUserTx(t *testing.T) {
+	createRandomUserTx(t, NewStore(testDB))
+}
+
+func TestCreateUserTxRollback(t *testing.T) {
+	store := NewStore(testDB)
+	errSend := errors.New("cannot send email")
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       utils.RandomOwner(),
+			FullName:       utils.RandomOwner(),
+			HashedPassword: utils.RandomString(60),
+			Email:          utils.RandomEmail(),
+		},
+		SecretCodeHash: utils.HashSecureToken(utils.RandomString(32)),
+		AfterCreate: func(user User, verifyEmail VerifyEmail) error {
+			return errSend
+		},
+	}
+
+	_, err := store.CreateUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, errSend)
+
+	_, err = testQueries.GetUser(context.Background(), arg.Username)
+	require.Error(t, err)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	created, secretCode := createRandomUserTx(t, store)
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailId:        created.VerifyEmail.ID,
+		SecretCodeHash: utils.HashSecureToken(utils.RandomString(32)),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailId:        created.VerifyEmail.ID,
+		SecretCodeHash: utils.HashSecureToken(secretCode),
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	// codes are single use
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailId:        created.VerifyEmail.ID,
+		SecretCodeHash: utils.HashSecureToken(secretCode),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+}
@@ -0,0 +1,235 @@
+package fees
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+)
+
+// Wildcard is the currency of the rule applied to currencies without a rule of their own,
+// a schedule with a wildcard rule needs a revenue account for every supported currency
+const Wildcard = "*"
+
+// ScheduleFile is the layout of the file read by LoadScheduleFile, e.g.
+//
+//	{
+//	  "revenue_accounts": {"USD": 1, "EUR": 2, "CAD": 3, "MAD": 4},
+//	  "rules": {
+//	    "USD": {"flat": 25, "percent": "0.5", "max": 500},
+//	    "*": {"tiers": [{"up_to": 10000, "flat": 10}, {"percent": "0.2"}], "min": 10}
+//	  }
+//	}
+//
+// Amounts are in the smallest unit of the currency charged
+type ScheduleFile struct {
+	// RevenueAccounts maps a currency to the bank account collecting its fees,
+	// every currency priced by a rule needs one
+	RevenueAccounts map[string]int64 `json:"revenue_accounts"`
+	Rules           map[string]Rule  `json:"rules"`
+}
+
+// Rule prices the transfers of a currency, the fee of the first tier covering the amount
+// replaces Flat and Percent. The result is then kept between Min and Max, a zero Max doesn't cap it
+type Rule struct {
+	Flat    int64  `json:"flat"`
+	Percent string `json:"percent"`
+	Tiers   []Tier `json:"tiers"`
+	Min     int64  `json:"min"`
+	Max     int64  `json:"max"`
+}
+
+// Tier prices the amounts up to UpTo included, the last tier leaves UpTo out to cover the rest
+type Tier struct {
+	UpTo    int64  `json:"up_to"`
+	Flat    int64  `json:"flat"`
+	Percent string `json:"percent"`
+}
+
+type price struct {
+	upTo    int64
+	flat    int64
+	percent *big.Rat
+}
+
+type rule struct {
+	price
+	tiers []price
+	min   int64
+	max   int64
+}
+
+// Schedule works out the fee charged on a transfer out of an account of a given currency
+type Schedule struct {
+	revenueAccounts map[string]int64
+	rules           map[string]rule
+}
+
+func NewSchedule(file ScheduleFile) (*Schedule, error) {
+	schedule := &Schedule{
+		revenueAccounts: make(map[string]int64, len(file.RevenueAccounts)),
+		rules:           make(map[string]rule, len(file.Rules)),
+	}
+
+	for currency, accountID := range file.RevenueAccounts {
+		if accountID <= 0 {
+			return nil, fmt.Errorf("%s: invalid revenue account %d", currency, accountID)
+		}
+		schedule.revenueAccounts[currency] = accountID
+	}
+
+	for currency, r := range file.Rules {
+		parsed, err := parseRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", currency, err)
+		}
+		schedule.rules[currency] = parsed
+
+		// a rule nobody collects would leave its transfers free without a word
+		covered := []string{currency}
+		if currency == Wildcard {
+			covered = utils.SupportedCurrencies
+		}
+		for _, c := range covered {
+			if _, ok := schedule.revenueAccounts[c]; !ok {
+				return nil, fmt.Errorf("%s: no revenue account collects the fees of %s", currency, c)
+			}
+		}
+	}
+	return schedule, nil
+}
+
+// AccountStore is the part of the database CheckRevenueAccounts reads accounts from
+type AccountStore interface {
+	GetAccount(ctx context.Context, id int64) (db.Account, error)
+}
+
+// CheckRevenueAccounts makes sure every revenue account exists and holds the currency it collects,
+// so a misconfigured schedule is caught at startup rather than on the first transfer it prices
+func (schedule *Schedule) CheckRevenueAccounts(ctx context.Context, store AccountStore) error {
+	currencies := make([]string, 0, len(schedule.revenueAccounts))
+	for currency := range schedule.revenueAccounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		accountID := schedule.revenueAccounts[currency]
+		account, err := store.GetAccount(ctx, accountID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%s: revenue account %d doesn't exist", currency, accountID)
+			}
+			return fmt.Errorf("%s: cannot read revenue account %d: %w", currency, accountID, err)
+		}
+		if account.Currency != currency {
+			return fmt.Errorf("%s: revenue account %d holds %s", currency, accountID, account.Currency)
+		}
+	}
+	return nil
+}
+
+// LoadScheduleFile reads a Schedule out of a json ScheduleFile
+func LoadScheduleFile(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fee schedule file: %w", err)
+	}
+
+	var file ScheduleFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse fee schedule file: %w", err)
+	}
+
+	return NewSchedule(file)
+}
+
+func parseRule(r Rule) (rule, error) {
+	base, err := parsePrice(0, r.Flat, r.Percent)
+	if err != nil {
+		return rule{}, err
+	}
+	if r.Min < 0 || r.Max < 0 || (r.Max > 0 && r.Min > r.Max) {
+		return rule{}, fmt.Errorf("invalid fee bounds %d - %d", r.Min, r.Max)
+	}
+
+	parsed := rule{price: base, min: r.Min, max: r.Max}
+	for i, tier := range r.Tiers {
+		last := i == len(r.Tiers)-1
+		if last != (tier.UpTo == 0) {
+			return rule{}, fmt.Errorf("tier %d: only the last tier leaves up_to out", i)
+		}
+		if i > 0 && !last && tier.UpTo <= r.Tiers[i-1].UpTo {
+			return rule{}, fmt.Errorf("tier %d: tiers must be in increasing up_to order", i)
+		}
+
+		p, err := parsePrice(tier.UpTo, tier.Flat, tier.Percent)
+		if err != nil {
+			return rule{}, fmt.Errorf("tier %d: %w", i, err)
+		}
+		parsed.tiers = append(parsed.tiers, p)
+	}
+	return parsed, nil
+}
+
+func parsePrice(upTo int64, flat int64, percent string) (price, error) {
+	if flat < 0 {
+		return price{}, fmt.Errorf("flat fee %d can't be negative", flat)
+	}
+
+	p := price{upTo: upTo, flat: flat, percent: new(big.Rat)}
+	if percent != "" {
+		_, ok := p.percent.SetString(percent)
+		if !ok || p.percent.Sign() < 0 || p.percent.Cmp(big.NewRat(100, 1)) > 0 {
+			return price{}, fmt.Errorf("invalid fee percentage %q", percent)
+		}
+	}
+	return p, nil
+}
+
+// Fee returns the fee charged on a transfer of amount out of an account in currency
+// and the account collecting it, a zero fee means the transfer is free.
+// The percentage part is rounded down to the smallest currency unit
+func (schedule *Schedule) Fee(currency string, amount int64) (int64, int64) {
+	revenueAccountID, ok := schedule.revenueAccounts[currency]
+	if !ok {
+		return 0, 0
+	}
+	r, ok := schedule.rules[currency]
+	if !ok {
+		r, ok = schedule.rules[Wildcard]
+		if !ok {
+			return 0, 0
+		}
+	}
+
+	p := r.price
+	for _, tier := range r.tiers {
+		if tier.upTo == 0 || amount <= tier.upTo {
+			p = tier
+			break
+		}
+	}
+
+	variable := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), p.percent)
+	variable.Quo(variable, big.NewRat(100, 1))
+	fee := p.flat + new(big.Int).Quo(variable.Num(), variable.Denom()).Int64()
+
+	if fee < r.min {
+		fee = r.min
+	}
+	if r.max > 0 && fee > r.max {
+		fee = r.max
+	}
+	if fee == 0 {
+		return 0, 0
+	}
+	return fee, revenueAccountID
+}
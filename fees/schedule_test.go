@@ -0,0 +1,168 @@
+package fees
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleFee(t *testing.T) {
+	schedule, err := NewSchedule(ScheduleFile{
+		RevenueAccounts: map[string]int64{"USD": 1, "EUR": 2, "CAD": 3, "MAD": 4},
+		Rules: map[string]Rule{
+			"USD": {Flat: 25, Percent: "0.5", Min: 30, Max: 500},
+			"EUR": {
+				Tiers: []Tier{
+					{UpTo: 10000, Flat: 10},
+					{UpTo: 100000, Percent: "0.25"},
+					{Percent: "0.1", Flat: 50},
+				},
+			},
+			Wildcard: {Flat: 5},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		currency  string
+		amount    int64
+		fee       int64
+		accountID int64
+	}{
+		{name: "FlatAndPercent", currency: "USD", amount: 10000, fee: 75, accountID: 1},
+		{name: "Min", currency: "USD", amount: 100, fee: 30, accountID: 1},
+		{name: "Max", currency: "USD", amount: 1000000, fee: 500, accountID: 1},
+		{name: "RoundedDown", currency: "USD", amount: 1999, fee: 34, accountID: 1},
+		{name: "FirstTier", currency: "EUR", amount: 10000, fee: 10, accountID: 2},
+		{name: "SecondTier", currency: "EUR", amount: 10001, fee: 25, accountID: 2},
+		{name: "LastTier", currency: "EUR", amount: 200000, fee: 250, accountID: 2},
+		{name: "Wildcard", currency: "CAD", amount: 100, fee: 5, accountID: 3},
+		// accounts can't be opened in a currency the bank doesn't collect fees in
+		{name: "UnsupportedCurrency", currency: "GBP", amount: 100, fee: 0, accountID: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fee, accountID := schedule.Fee(tc.currency, tc.amount)
+			require.Equal(t, tc.fee, fee)
+			require.Equal(t, tc.accountID, accountID)
+		})
+	}
+}
+
+func TestNewScheduleInvalid(t *testing.T) {
+	rules := []Rule{
+		{Flat: -1},
+		{Percent: "half"},
+		{Percent: "101"},
+		{Min: 100, Max: 10},
+		{Tiers: []Tier{{UpTo: 100, Flat: 1}}},
+		{Tiers: []Tier{{Flat: 1}, {UpTo: 100, Flat: 2}}},
+		{Tiers: []Tier{{UpTo: 100, Flat: 1}, {UpTo: 50, Flat: 2}, {Flat: 3}}},
+	}
+	for _, rule := range rules {
+		_, err := NewSchedule(ScheduleFile{
+			RevenueAccounts: map[string]int64{"USD": 1},
+			Rules:           map[string]Rule{"USD": rule},
+		})
+		require.Error(t, err, "%+v", rule)
+	}
+
+	_, err := NewSchedule(ScheduleFile{RevenueAccounts: map[string]int64{"USD": 0}})
+	require.Error(t, err)
+
+	// rules need an account to collect their fees
+	_, err = NewSchedule(ScheduleFile{
+		RevenueAccounts: map[string]int64{"USD": 1},
+		Rules:           map[string]Rule{"EUR": {Flat: 10}},
+	})
+	require.Error(t, err)
+
+	_, err = NewSchedule(ScheduleFile{
+		RevenueAccounts: map[string]int64{"USD": 1},
+		Rules:           map[string]Rule{Wildcard: {Flat: 10}},
+	})
+	require.Error(t, err)
+}
+
+func TestLoadScheduleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	err := os.WriteFile(path, []byte(`{
+		"revenue_accounts": {"USD": 7},
+		"rules": {"USD": {"percent": "1", "min": 10}}
+	}`), 0600)
+	require.NoError(t, err)
+
+	schedule, err := LoadScheduleFile(path)
+	require.NoError(t, err)
+
+	fee, accountID := schedule.Fee("USD", 5000)
+	require.Equal(t, int64(50), fee)
+	require.Equal(t, int64(7), accountID)
+
+	_, err = LoadScheduleFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestCheckRevenueAccounts(t *testing.T) {
+	schedule, err := NewSchedule(ScheduleFile{
+		RevenueAccounts: map[string]int64{"USD": 1, "EUR": 2},
+		Rules:           map[string]Rule{"USD": {Flat: 10}, "EUR": {Flat: 10}},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    string
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.Account{ID: 1, Currency: "USD"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.Account{ID: 2, Currency: "EUR"}, nil)
+			},
+		},
+		{
+			name: "Missing",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			wantErr: "revenue account 2 doesn't exist",
+		},
+		{
+			name: "WrongCurrency",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.Account{ID: 2, Currency: "EUR"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.Account{ID: 1, Currency: "CAD"}, nil)
+			},
+			wantErr: "revenue account 1 holds CAD",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			err := schedule.CheckRevenueAccounts(context.Background(), store)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
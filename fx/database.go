@@ -0,0 +1,57 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/utils"
+)
+
+// RateStore is the part of the database the DBRateProvider reads rates from
+type RateStore interface {
+	GetFXRate(ctx context.Context, arg db.GetFXRateParams) (db.FxRate, error)
+}
+
+// DBRateProvider serves the rates kept in the fx_rates table,
+// a pair only stored the other way around is served inverted
+type DBRateProvider struct {
+	store RateStore
+}
+
+func NewDBRateProvider(store RateStore) *DBRateProvider {
+	return &DBRateProvider{store: store}
+}
+
+func (provider *DBRateProvider) Rate(ctx context.Context, fromCurrency string, toCurrency string) (*big.Rat, error) {
+	if fromCurrency == toCurrency {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, err := provider.lookup(ctx, fromCurrency, toCurrency)
+	if err != sql.ErrNoRows {
+		return rate, err
+	}
+
+	rate, err = provider.lookup(ctx, toCurrency, fromCurrency)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w from %s to %s", ErrRateUnavailable, fromCurrency, toCurrency)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rate.Inv(rate), nil
+}
+
+func (provider *DBRateProvider) lookup(ctx context.Context, fromCurrency string, toCurrency string) (*big.Rat, error) {
+	rate, err := provider.store.GetFXRate(ctx, db.GetFXRateParams{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return utils.ParseRate(rate.Rate)
+}
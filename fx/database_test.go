@@ -0,0 +1,61 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"testing"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBRateProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "EUR"})).
+		AnyTimes().
+		Return(db.FxRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: "0.8000000000"}, nil)
+	store.EXPECT().
+		GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "EUR", ToCurrency: "USD"})).
+		AnyTimes().
+		Return(db.FxRate{}, sql.ErrNoRows)
+	store.EXPECT().
+		GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "CAD"})).
+		AnyTimes().
+		Return(db.FxRate{}, sql.ErrNoRows)
+	store.EXPECT().
+		GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "CAD", ToCurrency: "USD"})).
+		AnyTimes().
+		Return(db.FxRate{}, sql.ErrNoRows)
+	store.EXPECT().
+		GetFXRate(gomock.Any(), gomock.Eq(db.GetFXRateParams{FromCurrency: "USD", ToCurrency: "MAD"})).
+		AnyTimes().
+		Return(db.FxRate{}, sql.ErrConnDone)
+
+	provider := NewDBRateProvider(store)
+
+	rate, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Zero(t, big.NewRat(4, 5).Cmp(rate))
+
+	// only USD -> EUR is stored, the other way is its inverse
+	rate, err = provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Zero(t, big.NewRat(5, 4).Cmp(rate))
+
+	rate, err = provider.Rate(context.Background(), "EUR", "EUR")
+	require.NoError(t, err)
+	require.Zero(t, big.NewRat(1, 1).Cmp(rate))
+
+	_, err = provider.Rate(context.Background(), "USD", "CAD")
+	require.ErrorIs(t, err, ErrRateUnavailable)
+
+	_, err = provider.Rate(context.Background(), "USD", "MAD")
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
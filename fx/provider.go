@@ -0,0 +1,34 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	StaticProvider   = "static"
+	DatabaseProvider = "database"
+)
+
+// ErrRateUnavailable is returned when a provider has no rate for a currency pair
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// FXRateProvider gives the current rate to convert an amount of one currency into another
+type FXRateProvider interface {
+	Rate(ctx context.Context, fromCurrency string, toCurrency string) (*big.Rat, error)
+}
+
+// NewRateProvider creates the provider of the given type, rates are read from ratesFile
+// for the static provider and from store for the database one
+func NewRateProvider(providerType string, ratesFile string, store RateStore) (FXRateProvider, error) {
+	switch providerType {
+	case StaticProvider:
+		return LoadStaticRateFile(ratesFile)
+	case DatabaseProvider:
+		return NewDBRateProvider(store), nil
+	default:
+		return nil, fmt.Errorf("unknown fx rate provider %q", providerType)
+	}
+}
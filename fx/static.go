@@ -0,0 +1,71 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/brkss/simplebank/utils"
+)
+
+// StaticRateProvider serves a fixed set of rates given against a base currency,
+// the rate between two other currencies goes through the base
+type StaticRateProvider struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+// RateFile is the layout of the file read by LoadStaticRateFile, e.g.
+// {"base": "USD", "rates": {"EUR": "0.92", "CAD": "1.36"}}
+type RateFile struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+func NewStaticRateProvider(base string, rates map[string]string) (*StaticRateProvider, error) {
+	provider := &StaticRateProvider{
+		base:  base,
+		rates: map[string]*big.Rat{base: big.NewRat(1, 1)},
+	}
+	for currency, rate := range rates {
+		r, err := utils.ParseRate(rate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", currency, err)
+		}
+		provider.rates[currency] = r
+	}
+	return provider, nil
+}
+
+// LoadStaticRateFile reads a StaticRateProvider out of a json RateFile
+func LoadStaticRateFile(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var file RateFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("rates file has no base currency")
+	}
+
+	return NewStaticRateProvider(file.Base, file.Rates)
+}
+
+func (provider *StaticRateProvider) Rate(ctx context.Context, fromCurrency string, toCurrency string) (*big.Rat, error) {
+	from, ok := provider.rates[fromCurrency]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrRateUnavailable, fromCurrency)
+	}
+	to, ok := provider.rates[toCurrency]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrRateUnavailable, toCurrency)
+	}
+	return new(big.Rat).Quo(to, from), nil
+}
@@ -0,0 +1,79 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider("USD", map[string]string{
+		"EUR": "0.8",
+		"CAD": "1.2",
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		from     string
+		to       string
+		expected *big.Rat
+	}{
+		{from: "USD", to: "EUR", expected: big.NewRat(4, 5)},
+		{from: "EUR", to: "USD", expected: big.NewRat(5, 4)},
+		{from: "EUR", to: "CAD", expected: big.NewRat(3, 2)},
+		{from: "CAD", to: "CAD", expected: big.NewRat(1, 1)},
+	}
+	for _, tc := range testCases {
+		rate, err := provider.Rate(context.Background(), tc.from, tc.to)
+		require.NoError(t, err)
+		require.Zero(t, tc.expected.Cmp(rate), "%s -> %s: %s", tc.from, tc.to, rate)
+	}
+
+	_, err = provider.Rate(context.Background(), "USD", "MAD")
+	require.ErrorIs(t, err, ErrRateUnavailable)
+
+	_, err = NewStaticRateProvider("USD", map[string]string{"EUR": "-1"})
+	require.Error(t, err)
+}
+
+func TestLoadStaticRateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base": "EUR", "rates": {"USD": "1.25"}}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := LoadStaticRateFile(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Zero(t, big.NewRat(4, 5).Cmp(rate))
+
+	err = os.WriteFile(path, []byte(`{"rates": {"USD": "1.25"}}`), 0600)
+	require.NoError(t, err)
+	_, err = LoadStaticRateFile(path)
+	require.Error(t, err)
+
+	_, err = LoadStaticRateFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestNewRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": "0.92"}}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := NewRateProvider(StaticProvider, path, nil)
+	require.NoError(t, err)
+	require.IsType(t, &StaticRateProvider{}, provider)
+
+	provider, err = NewRateProvider(DatabaseProvider, "", nil)
+	require.NoError(t, err)
+	require.IsType(t, &DBRateProvider{}, provider)
+
+	_, err = NewRateProvider("oracle", "", nil)
+	require.Error(t, err)
+}
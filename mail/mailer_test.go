@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+
+	err := mailer.Send(context.Background(), Message{To: []string{"alice@email.com"}, Subject: "first", Body: "hello"})
+	require.NoError(t, err)
+	err = mailer.Send(context.Background(), Message{To: []string{"alice@email.com"}, Subject: "second", Body: "hello again"})
+	require.NoError(t, err)
+
+	require.Len(t, mailer.Messages(), 2)
+
+	msg, ok := mailer.LastMessageTo("alice@email.com")
+	require.True(t, ok)
+	require.Equal(t, "second", msg.Subject)
+
+	_, ok = mailer.LastMessageTo("bob@email.com")
+	require.False(t, ok)
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := Message{
+		To:      []string{"alice@email.com", "bob@email.com"},
+		Subject: "Réinitialisation",
+		Body:    "line one\nline two",
+	}
+
+	data := string(buildMessage("bank@email.com", msg, time.Now()))
+	headers, body, found := strings.Cut(data, "\r\n\r\n")
+	require.True(t, found)
+
+	require.Contains(t, headers, "From: bank@email.com\r\n")
+	require.Contains(t, headers, "To: alice@email.com, bob@email.com\r\n")
+	require.Contains(t, headers, "Subject: =?utf-8?q?R=C3=A9initialisation?=\r\n")
+	require.Equal(t, "line one\r\nline two", body)
+}
+
+func TestSMTPMailerNoRecipient(t *testing.T) {
+	mailer := NewSMTPMailer("localhost", 25, "", "", "bank@email.com")
+	err := mailer.Send(context.Background(), Message{Subject: "nobody"})
+	require.Error(t, err)
+}
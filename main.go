@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
 	"github.com/brkss/simplebank/api"
 	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/fees"
+	"github.com/brkss/simplebank/fx"
+	"github.com/brkss/simplebank/mail"
 	"github.com/brkss/simplebank/utils"
+	"github.com/brkss/simplebank/worker"
 	_ "github.com/golang/mock/mockgen/model"
 	_ "github.com/lib/pq"
 )
@@ -20,12 +25,42 @@ func main() {
 	if err != nil {
 		log.Fatal("cannot connect to database : ", err)
 	}
-	store := db.NewStore(con)
-	server, err := api.NewServer(config, store)
+	var storeOpts []db.StoreOption
+	var schedule *fees.Schedule
+	if config.FeeScheduleFile != "" {
+		schedule, err = fees.LoadScheduleFile(config.FeeScheduleFile)
+		if err != nil {
+			log.Fatal("cannot load fee schedule : ", err)
+		}
+		storeOpts = append(storeOpts, db.WithFeeSchedule(schedule))
+	}
+	store := db.NewStore(con, storeOpts...)
+	if schedule != nil {
+		err = schedule.CheckRevenueAccounts(context.Background(), store)
+		if err != nil {
+			log.Fatal("invalid fee schedule : ", err)
+		}
+	}
+	mailer := mail.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.EmailSender)
+	rates, err := fx.NewRateProvider(config.FXRateProvider, config.FXRatesFile, store)
+	if err != nil {
+		log.Fatal("cannot create fx rate provider : ", err)
+	}
+	server, err := api.NewServer(config, store, db.NewRevocationStore(store), mailer, rates)
 	if err != nil {
 		log.Fatal("cannot create server : ", err)
 	}
 
+	go worker.NewHoldSweeper(store, config.HoldSweepInterval).Run(context.Background())
+	go worker.NewRevocationSweeper(store, config.RevocationSweepInterval).Run(context.Background())
+	go worker.NewTransferScheduler(
+		store,
+		config.SchedulerInterval,
+		config.ScheduledTransferMaxAttempts,
+		config.ScheduledTransferRetryDelay,
+		config.TransferApprovalThreshold,
+	).Run(context.Background())
+
 	err = server.Start(config.ServerAdress)
 
 	if err != nil {
@@ -0,0 +1,169 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds how far ahead a cron schedule looks for its next run,
+// expressions like "0 0 30 2 *" never match
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+type field struct {
+	name string
+	min  uint
+	max  uint
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	dayField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12}
+	// 7 is accepted for sunday along with 0
+	weekdayField = field{name: "day of week", min: 0, max: 7}
+)
+
+// Cron runs on the minutes matching a cron expression, each field is a bit set of the values it matches
+type Cron struct {
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+	// like cron, when both day fields are restricted a day matching either of them runs,
+	// a field starting with * like */2 counts as unrestricted
+	dayStar     bool
+	weekdayStar bool
+}
+
+func parseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidSchedule, len(fields))
+	}
+
+	var err error
+	cron := &Cron{
+		dayStar:     strings.HasPrefix(fields[2], "*"),
+		weekdayStar: strings.HasPrefix(fields[4], "*"),
+	}
+	if cron.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if cron.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if cron.day, err = dayField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if cron.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if cron.weekday, err = weekdayField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if cron.weekday&(1<<7) != 0 {
+		cron.weekday |= 1
+	}
+	return cron, nil
+}
+
+// parse reads a comma separated list of *, values and ranges, each with an optional /step
+func (f field) parse(expression string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expression, ",") {
+		rangeExpression, step := part, uint(1)
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("%w: bad step in %s field %q", ErrInvalidSchedule, f.name, part)
+			}
+			rangeExpression, step = part[:i], uint(n)
+		}
+
+		low, high := f.min, f.max
+		if rangeExpression != "*" {
+			bounds := strings.SplitN(rangeExpression, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" runs from 5 to the end of the range
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("%w: empty range in %s field %q", ErrInvalidSchedule, f.name, part)
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (uint, error) {
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(n) < f.min || uint(n) > f.max {
+		return 0, fmt.Errorf("%w: %s field must be between %d and %d, got %q", ErrInvalidSchedule, f.name, f.min, f.max, s)
+	}
+	return uint(n), nil
+}
+
+func (cron *Cron) First(start time.Time) time.Time {
+	start = start.UTC()
+	first := start.Truncate(time.Minute)
+	if first.Before(start) {
+		first = first.Add(time.Minute)
+	}
+	return cron.search(first)
+}
+
+func (cron *Cron) Next(t time.Time) time.Time {
+	return cron.search(t.UTC().Truncate(time.Minute).Add(time.Minute))
+}
+
+// search returns the first matching minute at or after t, skipping whole
+// months, days and hours that can't match
+func (cron *Cron) search(t time.Time) time.Time {
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if cron.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !cron.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if cron.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if cron.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (cron *Cron) matchDay(t time.Time) bool {
+	day := cron.day&(1<<uint(t.Day())) != 0
+	weekday := cron.weekday&(1<<uint(t.Weekday())) != 0
+	if cron.dayStar || cron.weekdayStar {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinInterval is the shortest interval accepted by an @every schedule
+const MinInterval = time.Minute
+
+// ErrInvalidSchedule is returned when a schedule spec can't be parsed
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule tells when a recurring job runs, times are evaluated in UTC.
+// A zero time means the schedule never runs again
+type Schedule interface {
+	// First returns the first run at or after start
+	First(start time.Time) time.Time
+	// Next returns the run following the one at t
+	Next(t time.Time) time.Time
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads a schedule spec, either a five field cron expression
+// (minute hour day-of-month month day-of-week), one of the @yearly, @monthly,
+// @weekly, @daily or @hourly shorthands, or "@every <duration>" for a fixed interval
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+		if interval < MinInterval {
+			return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalidSchedule, MinInterval)
+		}
+		return Every(interval), nil
+	}
+
+	if expression, ok := descriptors[spec]; ok {
+		spec = expression
+	}
+	return parseCron(spec)
+}
+
+// Every runs at a fixed interval starting from the first run
+type Every time.Duration
+
+func (every Every) First(start time.Time) time.Time {
+	return start.UTC()
+}
+
+func (every Every) Next(t time.Time) time.Time {
+	return t.UTC().Add(time.Duration(every))
+}
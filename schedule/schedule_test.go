@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronNext(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		from     string
+		expected string
+	}{
+		{spec: "0 9 1 * *", from: "2026-01-15T10:00:00Z", expected: "2026-02-01T09:00:00Z"},
+		{spec: "@monthly", from: "2026-12-01T00:00:00Z", expected: "2027-01-01T00:00:00Z"},
+		{spec: "*/15 * * * *", from: "2026-01-01T10:07:30Z", expected: "2026-01-01T10:15:00Z"},
+		{spec: "30 8 * * 1-5", from: "2026-01-02T09:00:00Z", expected: "2026-01-05T08:30:00Z"},
+		{spec: "0 0 * * 7", from: "2026-01-01T00:00:00Z", expected: "2026-01-04T00:00:00Z"},
+		{spec: "0 12 31 * *", from: "2026-01-31T12:00:00Z", expected: "2026-03-31T12:00:00Z"},
+		{spec: "0 0 29 2 *", from: "2026-01-01T00:00:00Z", expected: "2028-02-29T00:00:00Z"},
+		// either day field matches when both are restricted
+		{spec: "0 0 13 * 5", from: "2026-02-01T00:00:00Z", expected: "2026-02-06T00:00:00Z"},
+		// a stepped star still counts as a star, both day fields have to match
+		{spec: "0 9 */2 * 1", from: "2026-01-01T00:00:00Z", expected: "2026-01-05T09:00:00Z"},
+		{spec: "0 9 1 * */2", from: "2026-01-02T00:00:00Z", expected: "2026-02-01T09:00:00Z"},
+		{spec: "0 6,18 * * *", from: "2026-01-01T06:00:00Z", expected: "2026-01-01T18:00:00Z"},
+	}
+
+	for _, tc := range testCases {
+		s, err := Parse(tc.spec)
+		require.NoError(t, err, tc.spec)
+		require.Equal(t, date(tc.expected), s.Next(date(tc.from)), tc.spec)
+	}
+}
+
+func TestCronFirst(t *testing.T) {
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	require.Equal(t, date("2026-01-01T09:00:00Z"), s.First(date("2026-01-01T09:00:00Z")))
+	require.Equal(t, date("2026-01-02T09:00:00Z"), s.First(date("2026-01-01T09:00:01Z")))
+	require.Equal(t, date("2026-01-01T09:00:00Z"), s.First(date("2026-01-01T04:00:00-05:00")))
+}
+
+func TestCronNeverRuns(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, s.Next(date("2026-01-01T00:00:00Z")).IsZero())
+}
+
+func TestEvery(t *testing.T) {
+	s, err := Parse("@every 720h")
+	require.NoError(t, err)
+
+	start := date("2026-01-01T09:30:00Z")
+	require.Equal(t, start, s.First(start))
+	require.Equal(t, date("2026-01-31T09:30:00Z"), s.Next(start))
+}
+
+func TestParseInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every 30s",
+		"@every soon",
+		"@fortnightly",
+	}
+	for _, spec := range specs {
+		_, err := Parse(spec)
+		require.ErrorIs(t, err, ErrInvalidSchedule, spec)
+	}
+}
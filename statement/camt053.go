@@ -0,0 +1,165 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// camt053Writer renders an ISO 20022 bank to customer statement (camt.053.001.02),
+// the format accounting software imports
+type camt053Writer struct {
+	stream    *xmlStream
+	statement Statement
+}
+
+func newCamt053Writer(w io.Writer) *camt053Writer {
+	return &camt053Writer{stream: newXMLStream(w)}
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// amount writes the Amt element and its CdtDbtInd, ISO 20022 amounts carry no sign
+func (writer *camt053Writer) amount(amount int64) {
+	currency := writer.statement.Currency
+	indicator := "CRDT"
+	text := FormatAmount(amount, currency)
+	if amount < 0 {
+		indicator = "DBIT"
+		text = strings.TrimPrefix(text, "-")
+	}
+
+	writer.stream.text("Amt", text, xml.Attr{Name: xml.Name{Local: "Ccy"}, Value: currency})
+	writer.stream.text("CdtDbtInd", indicator)
+}
+
+func (writer *camt053Writer) balance(code string, amount int64, at time.Time) {
+	stream := writer.stream
+	stream.start("Bal")
+	stream.start("Tp")
+	stream.start("CdOrPrtry")
+	stream.text("Cd", code)
+	stream.end()
+	stream.end()
+	writer.amount(amount)
+	stream.start("Dt")
+	stream.text("DtTm", camtTime(at))
+	stream.end()
+	stream.end()
+}
+
+func (writer *camt053Writer) account(id int64) {
+	stream := writer.stream
+	stream.start("Id")
+	stream.start("Othr")
+	stream.text("Id", strconv.FormatInt(id, 10))
+	stream.end()
+	stream.end()
+}
+
+func (writer *camt053Writer) Begin(statement Statement) error {
+	writer.statement = statement
+	stream := writer.stream
+	id := fmt.Sprintf("STMT-%d-%d", statement.AccountID, statement.GeneratedAt.Unix())
+
+	stream.token(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)})
+	stream.token(xml.CharData("\n"))
+	stream.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace})
+	stream.start("BkToCstmrStmt")
+
+	stream.start("GrpHdr")
+	stream.text("MsgId", id)
+	stream.text("CreDtTm", camtTime(statement.GeneratedAt))
+	stream.end()
+
+	stream.start("Stmt")
+	stream.text("Id", id)
+	stream.text("CreDtTm", camtTime(statement.GeneratedAt))
+	stream.start("FrToDt")
+	stream.text("FrDtTm", camtTime(statement.From))
+	stream.text("ToDtTm", camtTime(statement.To))
+	stream.end()
+
+	stream.start("Acct")
+	writer.account(statement.AccountID)
+	stream.text("Ccy", statement.Currency)
+	stream.start("Ownr")
+	stream.text("Nm", statement.Owner)
+	stream.end()
+	stream.end()
+
+	writer.balance("OPBD", statement.OpeningBalance, statement.From)
+	writer.balance("CLBD", statement.ClosingBalance, statement.To)
+	return stream.err
+}
+
+func (writer *camt053Writer) Line(line Line) error {
+	stream := writer.stream
+
+	stream.start("Ntry")
+	stream.text("NtryRef", strconv.FormatInt(line.EntryID, 10))
+	writer.amount(line.Amount)
+	stream.text("Sts", "BOOK")
+	stream.start("BookgDt")
+	stream.text("DtTm", camtTime(line.BookedAt))
+	stream.end()
+	stream.start("ValDt")
+	stream.text("DtTm", camtTime(line.BookedAt))
+	stream.end()
+	stream.text("AcctSvcrRef", strconv.FormatInt(line.EntryID, 10))
+
+	stream.start("BkTxCd")
+	if line.TransferID != 0 {
+		// book transfers between accounts of the bank, issued or received
+		family := "RCDT"
+		if line.Amount < 0 {
+			family = "ICDT"
+		}
+		stream.start("Domn")
+		stream.text("Cd", "PMNT")
+		stream.start("Fmly")
+		stream.text("Cd", family)
+		stream.text("SubFmlyCd", "BOOK")
+		stream.end()
+		stream.end()
+	} else {
+		stream.start("Prtry")
+		stream.text("Cd", "ENTRY")
+		stream.end()
+	}
+	stream.end()
+
+	if line.TransferID != 0 {
+		stream.start("NtryDtls")
+		stream.start("TxDtls")
+		stream.start("Refs")
+		stream.text("EndToEndId", strconv.FormatInt(line.TransferID, 10))
+		stream.end()
+		stream.start("RltdPties")
+		if line.Amount < 0 {
+			stream.start("CdtrAcct")
+		} else {
+			stream.start("DbtrAcct")
+		}
+		writer.account(line.CounterpartyAccountID)
+		stream.end()
+		stream.end()
+		stream.end()
+		stream.end()
+	}
+
+	stream.text("AddtlNtryInf", line.Description())
+	stream.end()
+	return stream.err
+}
+
+func (writer *camt053Writer) End() error {
+	return writer.stream.flush()
+}
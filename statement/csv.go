@@ -0,0 +1,59 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{
+	"booked_at",
+	"entry_id",
+	"transfer_id",
+	"counterparty_account_id",
+	"description",
+	"amount",
+	"currency",
+	"balance_after",
+}
+
+// csvWriter renders one row per entry under a header row, ready for spreadsheets
+type csvWriter struct {
+	w        *csv.Writer
+	currency string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (writer *csvWriter) Begin(statement Statement) error {
+	writer.currency = statement.Currency
+	return writer.w.Write(csvHeader)
+}
+
+func (writer *csvWriter) Line(line Line) error {
+	return writer.w.Write([]string{
+		line.BookedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(line.EntryID, 10),
+		optionalID(line.TransferID),
+		optionalID(line.CounterpartyAccountID),
+		line.Description(),
+		FormatAmount(line.Amount, writer.currency),
+		writer.currency,
+		FormatAmount(line.BalanceAfter, writer.currency),
+	})
+}
+
+func (writer *csvWriter) End() error {
+	writer.w.Flush()
+	return writer.w.Error()
+}
+
+func optionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,103 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ofxHeader = `OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"`
+	// ofxBankID fills BANKID, OFX caps it at 9 characters
+	ofxBankID = "SIMPLEBNK"
+)
+
+// ofxWriter renders an OFX 2.2 bank statement response, the format personal finance software imports
+type ofxWriter struct {
+	stream    *xmlStream
+	statement Statement
+}
+
+func newOFXWriter(w io.Writer) *ofxWriter {
+	return &ofxWriter{stream: newXMLStream(w)}
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+func (writer *ofxWriter) status() {
+	writer.stream.start("STATUS")
+	writer.stream.text("CODE", "0")
+	writer.stream.text("SEVERITY", "INFO")
+	writer.stream.end()
+}
+
+func (writer *ofxWriter) Begin(statement Statement) error {
+	writer.statement = statement
+	stream := writer.stream
+
+	stream.token(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8" standalone="no"`)})
+	stream.token(xml.CharData("\n"))
+	stream.token(xml.ProcInst{Target: "OFX", Inst: []byte(ofxHeader)})
+	stream.token(xml.CharData("\n"))
+	stream.start("OFX")
+
+	stream.start("SIGNONMSGSRSV1")
+	stream.start("SONRS")
+	writer.status()
+	stream.text("DTSERVER", ofxTime(statement.GeneratedAt))
+	stream.text("LANGUAGE", "ENG")
+	stream.end()
+	stream.end()
+
+	stream.start("BANKMSGSRSV1")
+	stream.start("STMTTRNRS")
+	stream.text("TRNUID", "0")
+	writer.status()
+	stream.start("STMTRS")
+	stream.text("CURDEF", statement.Currency)
+	stream.start("BANKACCTFROM")
+	stream.text("BANKID", ofxBankID)
+	stream.text("ACCTID", strconv.FormatInt(statement.AccountID, 10))
+	stream.text("ACCTTYPE", "CHECKING")
+	stream.end()
+	stream.start("BANKTRANLIST")
+	stream.text("DTSTART", ofxTime(statement.From))
+	stream.text("DTEND", ofxTime(statement.To))
+	return stream.err
+}
+
+func (writer *ofxWriter) Line(line Line) error {
+	stream := writer.stream
+
+	transactionType := "CREDIT"
+	switch {
+	case line.TransferID != 0:
+		transactionType = "XFER"
+	case line.Amount < 0:
+		transactionType = "DEBIT"
+	}
+
+	stream.start("STMTTRN")
+	stream.text("TRNTYPE", transactionType)
+	stream.text("DTPOSTED", ofxTime(line.BookedAt))
+	stream.text("TRNAMT", FormatAmount(line.Amount, writer.statement.Currency))
+	stream.text("FITID", strconv.FormatInt(line.EntryID, 10))
+	stream.text("MEMO", line.Description())
+	stream.end()
+	return stream.err
+}
+
+func (writer *ofxWriter) End() error {
+	stream := writer.stream
+
+	// BANKTRANLIST
+	stream.end()
+	stream.start("LEDGERBAL")
+	stream.text("BALAMT", FormatAmount(writer.statement.ClosingBalance, writer.statement.Currency))
+	stream.text("DTASOF", ofxTime(writer.statement.To))
+	stream.end()
+	return stream.flush()
+}
@@ -0,0 +1,141 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats a statement can be exported in
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCamt053 = "camt053"
+)
+
+// Statement describes the account and the period a statement covers, amounts are in the smallest unit of Currency
+type Statement struct {
+	AccountID      int64
+	Owner          string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// Line is an entry of the statement, TransferID and CounterpartyAccountID are zero for entries outside transfers
+type Line struct {
+	EntryID               int64
+	TransferID            int64
+	CounterpartyAccountID int64
+	Amount                int64
+	BalanceAfter          int64
+	BookedAt              time.Time
+}
+
+// Description is the human readable label of the line
+func (line Line) Description() string {
+	if line.TransferID == 0 {
+		return fmt.Sprintf("Entry %d", line.EntryID)
+	}
+	if line.Amount < 0 {
+		return fmt.Sprintf("Transfer %d to account %d", line.TransferID, line.CounterpartyAccountID)
+	}
+	return fmt.Sprintf("Transfer %d from account %d", line.TransferID, line.CounterpartyAccountID)
+}
+
+// Writer renders a statement as it is read, Begin comes first, then every Line in order and End last.
+// Output may be buffered until End
+type Writer interface {
+	Begin(statement Statement) error
+	Line(line Line) error
+	End() error
+}
+
+// NewWriter returns the Writer rendering format to w
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatOFX:
+		return newOFXWriter(w), nil
+	case FormatCamt053:
+		return newCamt053Writer(w), nil
+	}
+	return nil, fmt.Errorf("unsupported statement format %q", format)
+}
+
+// ContentType is the media type of the documents rendered in format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	}
+	return "application/xml"
+}
+
+// FileExtension is the extension of the files holding documents rendered in format
+func FileExtension(format string) string {
+	switch format {
+	case FormatCSV:
+		return "csv"
+	case FormatOFX:
+		return "ofx"
+	}
+	return "xml"
+}
+
+// minorUnits lists the ISO 4217 currencies that don't split into 100 minor units
+var minorUnits = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"IQD": 3,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"UGX": 0,
+	"VND": 0,
+}
+
+// MinorUnits is the number of decimals of currency, 2 unless ISO 4217 says otherwise
+func MinorUnits(currency string) int {
+	digits, ok := minorUnits[currency]
+	if !ok {
+		return 2
+	}
+	return digits
+}
+
+// FormatAmount writes amount, counted in the smallest unit of currency, as a decimal number of currency,
+// e.g. -1050 USD is "-10.50"
+func FormatAmount(amount int64, currency string) string {
+	var sign string
+	// converting before negating keeps the smallest int64 right
+	units := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := MinorUnits(currency)
+	if digits == 0 {
+		return sign + strconv.FormatUint(units, 10)
+	}
+
+	text := strconv.FormatUint(units, 10)
+	if len(text) <= digits {
+		text = strings.Repeat("0", digits-len(text)+1) + text
+	}
+	return sign + text[:len(text)-digits] + "." + text[len(text)-digits:]
+}
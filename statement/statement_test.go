@@ -0,0 +1,167 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{amount: 1050, currency: "USD", want: "10.50"},
+		{amount: -1050, currency: "EUR", want: "-10.50"},
+		{amount: 5, currency: "USD", want: "0.05"},
+		{amount: -5, currency: "USD", want: "-0.05"},
+		{amount: 0, currency: "CAD", want: "0.00"},
+		{amount: 1050, currency: "JPY", want: "1050"},
+		{amount: -1050, currency: "KWD", want: "-1.050"},
+		{amount: 7, currency: "BHD", want: "0.007"},
+		{amount: math.MinInt64, currency: "USD", want: "-92233720368547758.08"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, FormatAmount(tc.amount, tc.currency), "%d %s", tc.amount, tc.currency)
+	}
+}
+
+func testStatement() (Statement, []Line) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	statement := Statement{
+		AccountID:      42,
+		Owner:          "alice & co",
+		Currency:       "USD",
+		From:           from,
+		To:             from.AddDate(0, 1, 0),
+		OpeningBalance: 10000,
+		ClosingBalance: 8450,
+		GeneratedAt:    from.AddDate(0, 1, 1),
+	}
+	lines := []Line{
+		{
+			EntryID:               7,
+			TransferID:            3,
+			CounterpartyAccountID: 43,
+			Amount:                -1500,
+			BalanceAfter:          8500,
+			BookedAt:              from.Add(time.Hour),
+		},
+		{
+			EntryID:      8,
+			Amount:       -50,
+			BalanceAfter: 8450,
+			BookedAt:     from.Add(2 * time.Hour),
+		},
+	}
+	return statement, lines
+}
+
+func render(t *testing.T, format string) string {
+	statement, lines := testStatement()
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Begin(statement))
+	for _, line := range lines {
+		require.NoError(t, writer.Line(line))
+	}
+	require.NoError(t, writer.End())
+	return buf.String()
+}
+
+// requireWellFormed walks the whole document, failing on any XML syntax error
+func requireWellFormed(t *testing.T, document string) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	want := "booked_at,entry_id,transfer_id,counterparty_account_id,description,amount,currency,balance_after\n" +
+		"2024-01-01T01:00:00Z,7,3,43,Transfer 3 to account 43,-15.00,USD,85.00\n" +
+		"2024-01-01T02:00:00Z,8,,,Entry 8,-0.50,USD,84.50\n"
+	require.Equal(t, want, render(t, FormatCSV))
+}
+
+func TestOFXWriter(t *testing.T) {
+	document := render(t, FormatOFX)
+	requireWellFormed(t, document)
+
+	require.True(t, strings.HasPrefix(document, `<?xml version="1.0"`))
+	require.Contains(t, document, `<?OFX OFXHEADER="200" VERSION="220"`)
+	require.Contains(t, document, "<CURDEF>USD</CURDEF>")
+	require.Contains(t, document, "<ACCTID>42</ACCTID>")
+	require.Contains(t, document, "<DTSTART>20240101000000.000[0:GMT]</DTSTART>")
+	require.Contains(t, document, "<TRNTYPE>XFER</TRNTYPE>")
+	require.Contains(t, document, "<TRNAMT>-15.00</TRNAMT>")
+	require.Contains(t, document, "<TRNTYPE>DEBIT</TRNTYPE>")
+	require.Contains(t, document, "<TRNAMT>-0.50</TRNAMT>")
+	require.Contains(t, document, "<BALAMT>84.50</BALAMT>")
+	require.True(t, strings.HasSuffix(strings.TrimSpace(document), "</OFX>"))
+}
+
+func TestCamt053Writer(t *testing.T) {
+	document := render(t, FormatCamt053)
+	requireWellFormed(t, document)
+
+	var parsed struct {
+		Statement struct {
+			Owner    string `xml:"Acct>Ownr>Nm"`
+			Balances []struct {
+				Code      string `xml:"Tp>CdOrPrtry>Cd"`
+				Amount    string `xml:"Amt"`
+				Indicator string `xml:"CdtDbtInd"`
+			} `xml:"Bal"`
+			Entries []struct {
+				Amount struct {
+					Value    string `xml:",chardata"`
+					Currency string `xml:"Ccy,attr"`
+				} `xml:"Amt"`
+				Indicator    string `xml:"CdtDbtInd"`
+				Family       string `xml:"BkTxCd>Domn>Fmly>Cd"`
+				EndToEndID   string `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+				Counterparty string `xml:"NtryDtls>TxDtls>RltdPties>CdtrAcct>Id>Othr>Id"`
+			} `xml:"Ntry"`
+		} `xml:"BkToCstmrStmt>Stmt"`
+	}
+	err := xml.Unmarshal([]byte(document), &parsed)
+	require.NoError(t, err)
+
+	stmt := parsed.Statement
+	require.Equal(t, "alice & co", stmt.Owner)
+	require.Len(t, stmt.Balances, 2)
+	require.Equal(t, "OPBD", stmt.Balances[0].Code)
+	require.Equal(t, "100.00", stmt.Balances[0].Amount)
+	require.Equal(t, "CLBD", stmt.Balances[1].Code)
+	require.Equal(t, "84.50", stmt.Balances[1].Amount)
+
+	require.Len(t, stmt.Entries, 2)
+	require.Equal(t, "15.00", stmt.Entries[0].Amount.Value)
+	require.Equal(t, "USD", stmt.Entries[0].Amount.Currency)
+	require.Equal(t, "DBIT", stmt.Entries[0].Indicator)
+	require.Equal(t, "ICDT", stmt.Entries[0].Family)
+	require.Equal(t, "3", stmt.Entries[0].EndToEndID)
+	require.Equal(t, "43", stmt.Entries[0].Counterparty)
+	require.Equal(t, "0.50", stmt.Entries[1].Amount.Value)
+	require.Empty(t, stmt.Entries[1].EndToEndID)
+}
+
+func TestNewWriterUnsupportedFormat(t *testing.T) {
+	_, err := NewWriter("pdf", io.Discard)
+	require.Error(t, err)
+}
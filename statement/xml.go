@@ -0,0 +1,57 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// xmlStream writes an XML document element by element, the first error sticks and skips the writes after it
+type xmlStream struct {
+	encoder *xml.Encoder
+	open    []xml.StartElement
+	err     error
+}
+
+func newXMLStream(w io.Writer) *xmlStream {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return &xmlStream{encoder: encoder}
+}
+
+func (stream *xmlStream) token(token xml.Token) {
+	if stream.err == nil {
+		stream.err = stream.encoder.EncodeToken(token)
+	}
+}
+
+// start opens the element name, left open until the matching end
+func (stream *xmlStream) start(name string, attrs ...xml.Attr) {
+	element := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	stream.open = append(stream.open, element)
+	stream.token(element)
+}
+
+// end closes the last element opened by start
+func (stream *xmlStream) end() {
+	element := stream.open[len(stream.open)-1]
+	stream.open = stream.open[:len(stream.open)-1]
+	stream.token(element.End())
+}
+
+// text writes the element name holding value
+func (stream *xmlStream) text(name string, value string, attrs ...xml.Attr) {
+	stream.start(name, attrs...)
+	stream.token(xml.CharData(value))
+	stream.end()
+}
+
+// flush closes the elements left open and writes out what the encoder holds
+func (stream *xmlStream) flush() error {
+	for len(stream.open) > 0 {
+		stream.end()
+	}
+	if stream.err == nil {
+		stream.err = stream.encoder.Flush()
+	}
+	return stream.err
+}
@@ -0,0 +1,119 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const MIN_RSA_KEY_SIZE = 2048
+
+// JWTAsymmetricMaker signs JWTs with EdDSA (Ed25519) or RS256,
+// anyone holding the public key can verify them without being able to mint new ones
+type JWTAsymmetricMaker struct {
+	method jwt.SigningMethod
+	keys   *KeyRing
+	claims claimSet
+}
+
+// NewJWTEdDSAMaker creates an EdDSA JWT maker, privateKey may be nil for a verify only maker
+func NewJWTEdDSAMaker(privateKey crypto.PrivateKey, publicKey crypto.PublicKey, opts ...MakerOption) (Maker, error) {
+	keys, err := singleKeyRing(nil, privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newJWTAsymmetricMaker(jwt.SigningMethodEdDSA, keys, opts...)
+}
+
+// NewJWTRS256Maker creates an RS256 JWT maker, privateKey may be nil for a verify only maker
+func NewJWTRS256Maker(privateKey crypto.PrivateKey, publicKey crypto.PublicKey, opts ...MakerOption) (Maker, error) {
+	keys, err := singleKeyRing(nil, privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newJWTAsymmetricMaker(jwt.SigningMethodRS256, keys, opts...)
+}
+
+func newJWTAsymmetricMaker(method jwt.SigningMethod, keys *KeyRing, opts ...MakerOption) (Maker, error) {
+	for _, key := range keys.keys {
+		switch method {
+		case jwt.SigningMethodEdDSA:
+			if _, ok := key.PrivateKey.(ed25519.PrivateKey); key.PrivateKey != nil && !ok {
+				return nil, fmt.Errorf("invalid private key: EdDSA needs an ed25519 key, got %T", key.PrivateKey)
+			}
+			if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+				return nil, fmt.Errorf("invalid public key: EdDSA needs an ed25519 key, got %T", key.PublicKey)
+			}
+		case jwt.SigningMethodRS256:
+			if _, ok := key.PrivateKey.(*rsa.PrivateKey); key.PrivateKey != nil && !ok {
+				return nil, fmt.Errorf("invalid private key: RS256 needs an rsa key, got %T", key.PrivateKey)
+			}
+			pub, ok := key.PublicKey.(*rsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("invalid public key: RS256 needs an rsa key, got %T", key.PublicKey)
+			}
+			if pub.N.BitLen() < MIN_RSA_KEY_SIZE {
+				return nil, fmt.Errorf("invalid public key: rsa keys must have at least %d bits", MIN_RSA_KEY_SIZE)
+			}
+		}
+	}
+
+	return &JWTAsymmetricMaker{
+		method: method,
+		keys:   keys,
+		claims: newClaimSet(opts),
+	}, nil
+}
+
+func (j *JWTAsymmetricMaker) CreateToken(username string, role string, duration time.Duration, opts ...TokenOption) (string, *Payload, error) {
+	key := j.keys.Current()
+	if key.PrivateKey == nil {
+		return "", nil, ErrVerifyOnlyMaker
+	}
+
+	payload, err := j.claims.newPayload(username, role, duration, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwtToken := jwt.NewWithClaims(j.method, payload)
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+func (j *JWTAsymmetricMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != j.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		key, err := j.keys.Lookup(jwtKeyID(token))
+		if err != nil {
+			return nil, err
+		}
+		return key.PublicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, j.claims.emptyPayload(), keyFunc)
+	if err != nil {
+		return nil, jwtError(err)
+	}
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+// JWKS exposes the public keys tokens can be verified with
+func (j *JWTAsymmetricMaker) JWKS() JWKS {
+	return j.keys.jwks(j.method.Alg())
+}
@@ -0,0 +1,96 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAsymmetricMaker(t *testing.T) {
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		signer   func() (Maker, error)
+		verifier func() (Maker, error)
+	}{
+		{
+			name:     "EdDSA",
+			signer:   func() (Maker, error) { return NewJWTEdDSAMaker(edPrivateKey, nil) },
+			verifier: func() (Maker, error) { return NewJWTEdDSAMaker(nil, edPublicKey) },
+		},
+		{
+			name:     "RS256",
+			signer:   func() (Maker, error) { return NewJWTRS256Maker(rsaPrivateKey, nil) },
+			verifier: func() (Maker, error) { return NewJWTRS256Maker(nil, &rsaPrivateKey.PublicKey) },
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := tc.signer()
+			require.NoError(t, err)
+			verifier, err := tc.verifier()
+			require.NoError(t, err)
+
+			username := utils.RandomOwner()
+			token, payload, err := signer.CreateToken(username, utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			require.NotEmpty(t, token)
+			require.NotEmpty(t, payload)
+
+			payload, err = verifier.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+			require.WithinDuration(t, time.Now().Add(time.Minute), payload.ExpiredAt, time.Second)
+
+			_, _, err = verifier.CreateToken(username, utils.DepositorRole, time.Minute)
+			require.EqualError(t, err, ErrVerifyOnlyMaker.Error())
+
+			token, _, err = signer.CreateToken(username, utils.DepositorRole, -time.Minute)
+			require.NoError(t, err)
+			payload, err = verifier.VerifyToken(token)
+			require.EqualError(t, err, ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestJWTAsymmetricAlgorithmConfusion(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTEdDSAMaker(nil, publicKey)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// an HMAC token keyed with the public key must never be accepted
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token, err := jwtToken.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestJWTRS256RejectsSmallKeys(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	maker, err := NewJWTRS256Maker(privateKey, nil)
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
@@ -11,32 +11,45 @@ import (
 const MIN_SECRET_KEY_SIZE = 32
 
 type JWTMaker struct {
-	secretKey string
+	keys   *KeyRing
+	claims claimSet
 }
 
-func NewJWTMaker(secretKey string) (Maker, error) {
+func NewJWTMaker(secretKey string, opts ...MakerOption) (Maker, error) {
+	keys, err := singleKeyRing([]byte(secretKey), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTKeyRingMaker(keys, opts...)
+}
 
-	if len(secretKey) < MIN_SECRET_KEY_SIZE {
-		return nil, fmt.Errorf("invalid secret: must have atleast %d characters !", MIN_SECRET_KEY_SIZE);
+// NewJWTKeyRingMaker creates an HS256 maker signing with the current key of the ring
+func NewJWTKeyRingMaker(keys *KeyRing, opts ...MakerOption) (Maker, error) {
+	for _, key := range keys.keys {
+		if len(key.Secret) < MIN_SECRET_KEY_SIZE {
+			return nil, fmt.Errorf("invalid secret: must have atleast %d characters !", MIN_SECRET_KEY_SIZE);
+		}
 	}
 	
-	return &JWTMaker{secretKey: secretKey}, nil;
+	return &JWTMaker{keys: keys, claims: newClaimSet(opts)}, nil;
 }
 
-func (j *JWTMaker)CreateToken(username string, duration time.Duration)(string, error){
+func (j *JWTMaker)CreateToken(username string, role string, duration time.Duration, opts ...TokenOption)(string, *Payload, error){
 
-	payload, err := NewPayload(username, duration)
+	payload, err := j.claims.newPayload(username, role, duration, opts)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
+	key := j.keys.Current()
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload) 
-	token, err := jwtToken.SignedString([]byte(j.secretKey));
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.Secret);
 	
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return token, nil
+	return token, payload, nil
 }
 
 func (j *JWTMaker)VerifyToken(token string)(*Payload, error){
@@ -45,16 +58,16 @@ func (j *JWTMaker)VerifyToken(token string)(*Payload, error){
 		if !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(j.secretKey), nil
+		key, err := j.keys.Lookup(jwtKeyID(token))
+		if err != nil {
+			return nil, err
+		}
+		return key.Secret, nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	jwtToken, err := jwt.ParseWithClaims(token, j.claims.emptyPayload(), keyFunc)
 	if err != nil {
-		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, ErrExpiredToken){
-			return nil, ErrExpiredToken
-		}
-		return nil, ErrInvalidToken
+		return nil, jwtError(err)
 	}
 	payload, ok := jwtToken.Claims.(*Payload)
 	if !ok {
@@ -63,3 +76,23 @@ func (j *JWTMaker)VerifyToken(token string)(*Payload, error){
 
 	return payload, nil
 }
+
+// jwtError maps a parse error to the payload validation error behind it,
+// anything else is reported as an invalid token
+func jwtError(err error) error {
+	verr, ok := err.(*jwt.ValidationError)
+	if ok {
+		for _, known := range []error{ErrExpiredToken, ErrInvalidIssuer, ErrInvalidAudience} {
+			if errors.Is(verr.Inner, known) {
+				return known
+			}
+		}
+	}
+	return ErrInvalidToken
+}
+
+// jwtKeyID returns the kid header of a JWT, empty when missing
+func jwtKeyID(token *jwt.Token) string {
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
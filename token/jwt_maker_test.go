@@ -22,16 +22,18 @@ func TestJWTMaked(t *testing.T){
 	issueAt := time.Now()
 	expireAt := time.Now().Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, utils.DepositorRole, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, utils.DepositorRole)
 	require.WithinDuration(t, payload.IssuedAt, issueAt, time.Second)
 	require.WithinDuration(t, payload.ExpiredAt, expireAt, time.Second) 
 }
@@ -43,18 +45,19 @@ func TestExpiredJWT(t *testing.T){
 	username := utils.RandomOwner() 
 	duration := time.Minute // token lifespan
 
-	token, err := maker.CreateToken(username, -duration)
+	token, payload, err := maker.CreateToken(username, utils.DepositorRole, -duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
 
 func TestInvalidJWTSignature(t *testing.T){
-	payload, err := NewPayload(utils.RandomOwner(), time.Minute)
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
 	require.NoError(t, err)
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
@@ -75,11 +78,12 @@ func TestInvalidToken(t *testing.T){
 	maker2, err := NewJWTMaker(utils.RandomString(34))
 	require.NoError(t, err)
 	
-	token, err := maker1.CreateToken(utils.RandomOwner(), time.Minute)
+	token, payload, err := maker1.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker2.VerifyToken(token)
+	payload, err = maker2.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
@@ -0,0 +1,277 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("token signed with an unknown or retired key")
+
+// Key is one of the keys of a KeyRing, symmetric makers use Secret while
+// asymmetric ones use PrivateKey (nil on verify only makers) and PublicKey
+type Key struct {
+	ID         string
+	Secret     []byte
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	// ExpiresAt is the time after which tokens signed with a retired key
+	// stop being accepted, the zero value means the key never expires
+	ExpiresAt time.Time
+}
+
+func (k Key) expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt)
+}
+
+// KeyRing holds the current signing key and the retired keys still accepted for verification
+type KeyRing struct {
+	current string
+	keys    map[string]Key
+}
+
+// NewKeyRing creates a keyring signing with current and verifying against current and retired keys
+func NewKeyRing(current Key, retired ...Key) (*KeyRing, error) {
+	ring := &KeyRing{
+		current: current.ID,
+		keys:    make(map[string]Key),
+	}
+
+	for _, key := range append([]Key{current}, retired...) {
+		if key.ID == "" {
+			return nil, errors.New("invalid keyring: every key needs an id")
+		}
+		if _, ok := ring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("invalid keyring: duplicated key id %s", key.ID)
+		}
+		if key.PublicKey == nil && key.PrivateKey != nil {
+			signer, ok := key.PrivateKey.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("invalid keyring: unsupported private key type %T", key.PrivateKey)
+			}
+			key.PublicKey = signer.Public()
+		}
+		ring.keys[key.ID] = key
+	}
+	return ring, nil
+}
+
+// Current returns the key new tokens are signed with
+func (r *KeyRing) Current() Key {
+	return r.keys[r.current]
+}
+
+// Lookup returns the key matching kid, tokens without kid are checked against the current key
+func (r *KeyRing) Lookup(kid string) (Key, error) {
+	if kid == "" {
+		kid = r.current
+	}
+	key, ok := r.keys[kid]
+	if !ok || key.expired(time.Now()) {
+		return Key{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// Keys returns every key still accepted for verification, current key first
+func (r *KeyRing) Keys() []Key {
+	now := time.Now()
+	keys := []Key{r.Current()}
+	for id, key := range r.keys {
+		if id != r.current && !key.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.SliceStable(keys[1:], func(i, j int) bool { return keys[1+i].ID < keys[1+j].ID })
+	return keys
+}
+
+// singleKeyRing builds a keyring out of one key, deriving its id from the key material
+func singleKeyRing(secret []byte, privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (*KeyRing, error) {
+	key := Key{
+		Secret:     secret,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}
+	if key.PublicKey == nil && key.PrivateKey != nil {
+		signer, ok := key.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key.PrivateKey)
+		}
+		key.PublicKey = signer.Public()
+	}
+
+	if key.PublicKey == nil {
+		key.ID = SymetricKeyID(secret)
+	} else {
+		id, err := PublicKeyID(key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		key.ID = id
+	}
+	return NewKeyRing(key)
+}
+
+// SymetricKeyID derives a stable key id from a symmetric secret
+func SymetricKeyID(secret []byte) string {
+	sum := sha256.Sum256(append([]byte("simplebank-kid:"), secret...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// PublicKeyID derives a stable key id from a public key
+func PublicKeyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// keyFooter is the PASETO footer carrying the key id
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// pasetoKeyID reads the key id out of a PASETO token footer without verifying it,
+// the footer is authenticated so tampering with it makes verification fail later on
+func pasetoKeyID(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) < 3 || len(parts) > 4 {
+		return "", ErrInvalidToken
+	}
+	if len(parts) == 3 {
+		return "", nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var footer keyFooter
+	if err := json.Unmarshal(data, &footer); err != nil {
+		// footers we don't know about are treated as the absence of kid
+		return "", nil
+	}
+	return footer.KeyID, nil
+}
+
+// KeyRingConfig is the JSON layout of the keyring file
+type KeyRingConfig struct {
+	Current string `json:"current"`
+	Keys    []struct {
+		ID             string    `json:"kid"`
+		Secret         string    `json:"secret"`
+		PrivateKeyFile string    `json:"private_key_file"`
+		PublicKeyFile  string    `json:"public_key_file"`
+		ExpiresAt      time.Time `json:"expires_at"`
+	} `json:"keys"`
+}
+
+// LoadKeyRingFile reads a keyring from a JSON file, PEM key paths are used as is
+func LoadKeyRingFile(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read keyring file: %w", err)
+	}
+
+	var config KeyRingConfig
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse keyring file: %w", err)
+	}
+
+	var current *Key
+	var retired []Key
+	for _, k := range config.Keys {
+		key := Key{
+			ID:        k.ID,
+			Secret:    []byte(k.Secret),
+			ExpiresAt: k.ExpiresAt,
+		}
+		if k.PrivateKeyFile != "" {
+			key.PrivateKey, err = LoadPrivateKeyFile(k.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if k.PublicKeyFile != "" {
+			key.PublicKey, err = LoadPublicKeyFile(k.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if k.ID == config.Current {
+			current = &key
+		} else {
+			retired = append(retired, key)
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("invalid keyring: current key %q not found", config.Current)
+	}
+	return NewKeyRing(*current, retired...)
+}
+
+// JWK is a public JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKSProvider is implemented by makers verifying with public keys
+type JWKSProvider interface {
+	JWKS() JWKS
+}
+
+func (r *KeyRing) jwks(algorithm string) JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range r.Keys() {
+		switch pub := key.PublicKey.(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: algorithm,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+	return set
+}
@@ -0,0 +1,178 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRingRotation(t *testing.T) {
+	oldKey := Key{ID: "old", Secret: []byte(utils.RandomString(32))}
+	newKey := Key{ID: "new", Secret: []byte(utils.RandomString(32))}
+
+	for _, tokenType := range []string{TypePaseto, TypeJWT} {
+		t.Run(tokenType, func(t *testing.T) {
+			oldRing, err := NewKeyRing(oldKey)
+			require.NoError(t, err)
+			oldMaker, err := NewKeyRingMaker(tokenType, oldRing)
+			require.NoError(t, err)
+
+			oldToken, _, err := oldMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			// rotate: new key signs, old key keeps verifying until it expires
+			retired := oldKey
+			retired.ExpiresAt = time.Now().Add(time.Hour)
+			ring, err := NewKeyRing(newKey, retired)
+			require.NoError(t, err)
+			maker, err := NewKeyRingMaker(tokenType, ring)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(oldToken)
+			require.NoError(t, err)
+
+			newToken, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+
+			// makers that never heard of the new key reject its tokens
+			_, err = oldMaker.VerifyToken(newToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+
+			// once the retired key expires its tokens are rejected
+			retired.ExpiresAt = time.Now().Add(-time.Second)
+			ring, err = NewKeyRing(newKey, retired)
+			require.NoError(t, err)
+			maker, err = NewKeyRingMaker(tokenType, ring)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(oldToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+		})
+	}
+}
+
+func TestKeyRingErrors(t *testing.T) {
+	_, err := NewKeyRing(Key{Secret: []byte(utils.RandomString(32))})
+	require.Error(t, err)
+
+	key := Key{ID: "key", Secret: []byte(utils.RandomString(32))}
+	_, err = NewKeyRing(key, key)
+	require.Error(t, err)
+
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+	_, err = ring.Lookup("unknown")
+	require.EqualError(t, err, ErrUnknownKey.Error())
+
+	got, err := ring.Lookup("")
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+}
+
+func TestKeyRingJWKS(t *testing.T) {
+	publicKey1, privateKey1, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicKey2, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicKey3, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing(
+		Key{ID: "current", PrivateKey: privateKey1},
+		Key{ID: "retired", PublicKey: publicKey2, ExpiresAt: time.Now().Add(time.Hour)},
+		Key{ID: "expired", PublicKey: publicKey3, ExpiresAt: time.Now().Add(-time.Hour)},
+	)
+	require.NoError(t, err)
+
+	maker, err := NewKeyRingMaker(TypeJWTEdDSA, ring)
+	require.NoError(t, err)
+
+	provider, ok := maker.(JWKSProvider)
+	require.True(t, ok)
+
+	jwks := provider.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, "current", jwks.Keys[0].KeyID)
+	require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	require.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+	require.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+	require.Equal(t, []byte(publicKey1), mustDecodeBase64URL(t, jwks.Keys[0].X))
+	require.Equal(t, "retired", jwks.Keys[1].KeyID)
+
+	symetricMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	_, ok = symetricMaker.(JWKSProvider)
+	require.False(t, ok)
+}
+
+func TestLoadKeyRingFile(t *testing.T) {
+	publicKey1, privateKey1, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateFile1, _ := writeKeyFiles(t, privateKey1, publicKey1)
+
+	publicKey2, privateKey2, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, publicFile2 := writeKeyFiles(t, privateKey2, publicKey2)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	data, err := json.Marshal(map[string]interface{}{
+		"current": "2026-10",
+		"keys": []map[string]interface{}{
+			{"kid": "2026-10", "private_key_file": privateFile1},
+			{"kid": "2026-07", "public_key_file": publicFile2, "expires_at": expiresAt},
+		},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	err = os.WriteFile(path, data, 0600)
+	require.NoError(t, err)
+
+	ring, err := LoadKeyRingFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "2026-10", ring.Current().ID)
+	require.Equal(t, publicKey1, ring.Current().PublicKey)
+
+	retired, err := ring.Lookup("2026-07")
+	require.NoError(t, err)
+	require.Nil(t, retired.PrivateKey)
+	require.Equal(t, expiresAt, retired.ExpiresAt)
+
+	// tokens of the previous signer are still accepted
+	oldMaker, err := NewKeyRingMaker(TypePasetoV4Public, mustKeyRing(t, Key{ID: "2026-07", PrivateKey: privateKey2}))
+	require.NoError(t, err)
+	oldToken, _, err := oldMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	maker, err := NewKeyRingMaker(TypePasetoV4Public, ring)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	err = os.WriteFile(path, []byte(`{"current": "missing", "keys": []}`), 0600)
+	require.NoError(t, err)
+	_, err = LoadKeyRingFile(path)
+	require.Error(t, err)
+}
+
+func mustKeyRing(t *testing.T, current Key, retired ...Key) *KeyRing {
+	ring, err := NewKeyRing(current, retired...)
+	require.NoError(t, err)
+	return ring
+}
+
+func mustDecodeBase64URL(t *testing.T, s string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,58 @@
+package token
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// LoadPrivateKeyFile reads a PEM encoded PKCS#8 (or PKCS#1 RSA) private key
+func LoadPrivateKeyFile(path string) (crypto.PrivateKey, error) {
+	block, err := readPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err == nil {
+		return key, nil
+	}
+	rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if rsaErr == nil {
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("cannot parse private key %s: %w", path, err)
+}
+
+// LoadPublicKeyFile reads a PEM encoded PKIX (or PKCS#1 RSA) public key
+func LoadPublicKeyFile(path string) (crypto.PublicKey, error) {
+	block, err := readPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err == nil {
+		return key, nil
+	}
+	rsaKey, rsaErr := x509.ParsePKCS1PublicKey(block.Bytes)
+	if rsaErr == nil {
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("cannot parse public key %s: %w", path, err)
+}
+
+func readPEMFile(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
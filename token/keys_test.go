@@ -0,0 +1,97 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFiles(t *testing.T, privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (string, string) {
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	privateFile := filepath.Join(dir, "private.pem")
+	err = os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	require.NoError(t, err)
+
+	publicFile := filepath.Join(dir, "public.pem")
+	err = os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+	require.NoError(t, err)
+
+	return privateFile, publicFile
+}
+
+func TestNewMaker(t *testing.T) {
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPrivateFile, edPublicFile := writeKeyFiles(t, edPrivateKey, edPublicKey)
+
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPrivateFile, rsaPublicFile := writeKeyFiles(t, rsaPrivateKey, &rsaPrivateKey.PublicKey)
+
+	testCases := []struct {
+		tokenType   string
+		privateFile string
+		publicFile  string
+	}{
+		{TypePaseto, "", ""},
+		{TypeJWT, "", ""},
+		{TypePasetoV2Public, edPrivateFile, edPublicFile},
+		{TypePasetoV4Public, edPrivateFile, edPublicFile},
+		{TypeJWTEdDSA, edPrivateFile, edPublicFile},
+		{TypeJWTRS256, rsaPrivateFile, rsaPublicFile},
+	}
+
+	symetricKey := utils.RandomString(32)
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.tokenType, func(t *testing.T) {
+			signer, err := NewMaker(tc.tokenType, symetricKey, tc.privateFile, "")
+			require.NoError(t, err)
+
+			token, _, err := signer.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			verifier := signer
+			if tc.publicFile != "" {
+				verifier, err = NewMaker(tc.tokenType, "", "", tc.publicFile)
+				require.NoError(t, err)
+			}
+			_, err = verifier.VerifyToken(token)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewMakerErrors(t *testing.T) {
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPrivateFile, _ := writeKeyFiles(t, edPrivateKey, edPublicKey)
+
+	_, err = NewMaker("unknown", "", edPrivateFile, "")
+	require.Error(t, err)
+
+	_, err = NewMaker(TypePasetoV4Public, "", "", "")
+	require.Error(t, err)
+
+	_, err = NewMaker(TypeJWTRS256, "", edPrivateFile, "")
+	require.Error(t, err)
+
+	_, err = NewMaker(TypeJWTEdDSA, "", filepath.Join(t.TempDir(), "missing.pem"), "")
+	require.Error(t, err)
+}
@@ -1,13 +1,90 @@
 package token
 
-import "time"
+import (
+  "crypto"
+  "errors"
+  "fmt"
+  "time"
+
+  "github.com/golang-jwt/jwt/v4"
+)
+
+// token types that can be picked through the TOKEN_TYPE config
+const (
+  TypePaseto         = "paseto"           // PASETO v2.local, symmetric
+  TypePasetoV2Public = "paseto_v2_public" // PASETO v2.public, Ed25519
+  TypePasetoV4Public = "paseto_v4_public" // PASETO v4.public, Ed25519
+  TypeJWT            = "jwt"              // JWT HS256, symmetric
+  TypeJWTEdDSA       = "jwt_eddsa"        // JWT EdDSA, Ed25519
+  TypeJWTRS256       = "jwt_rs256"        // JWT RS256
+)
+
+var ErrVerifyOnlyMaker = errors.New("token maker has no private key, it can only verify tokens")
 
 // Maker in an interface got managing tokens
 type Maker interface {
-  // CreateToken creates a new token for a specific username and duration 
-  CreateToken(username string, duration time.Duration) (string, error)
+  // CreateToken creates a new token for a specific username, role and duration,
+  // opts can grant scopes or override the issuer and audience
+  CreateToken(username string, role string, duration time.Duration, opts ...TokenOption) (string, *Payload, error)
 
   // VerifyToken checks if the token is valid or not 
   VerifyToken(token string) (*Payload, error)
 
 }
+
+// NewMaker builds the maker matching tokenType, asymmetric makers load their keys
+// from PEM files and become verify only when privateKeyFile is empty
+func NewMaker(tokenType string, symetricKey string, privateKeyFile string, publicKeyFile string, opts ...MakerOption) (Maker, error) {
+  var privateKey crypto.PrivateKey
+  var publicKey crypto.PublicKey
+  var err error
+  if privateKeyFile != "" {
+    privateKey, err = LoadPrivateKeyFile(privateKeyFile)
+    if err != nil {
+      return nil, err
+    }
+  }
+  if publicKeyFile != "" {
+    publicKey, err = LoadPublicKeyFile(publicKeyFile)
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  var secret []byte
+  switch tokenType {
+  case "", TypePaseto, TypeJWT:
+    secret, privateKey, publicKey = []byte(symetricKey), nil, nil
+  default:
+    if privateKey == nil && publicKey == nil {
+      return nil, fmt.Errorf("token type %s needs a private or a public key file", tokenType)
+    }
+  }
+
+  keys, err := singleKeyRing(secret, privateKey, publicKey)
+  if err != nil {
+    return nil, err
+  }
+  return NewKeyRingMaker(tokenType, keys, opts...)
+}
+
+// NewKeyRingMaker builds the maker matching tokenType on top of a keyring,
+// tokens are signed with the current key and verified against any active key
+func NewKeyRingMaker(tokenType string, keys *KeyRing, opts ...MakerOption) (Maker, error) {
+  switch tokenType {
+  case "", TypePaseto:
+    return NewPasetoKeyRingMaker(keys, opts...)
+  case TypeJWT:
+    return NewJWTKeyRingMaker(keys, opts...)
+  case TypePasetoV2Public:
+    return newPasetoPublicMaker("v2", keys, opts...)
+  case TypePasetoV4Public:
+    return newPasetoPublicMaker("v4", keys, opts...)
+  case TypeJWTEdDSA:
+    return newJWTAsymmetricMaker(jwt.SigningMethodEdDSA, keys, opts...)
+  case TypeJWTRS256:
+    return newJWTAsymmetricMaker(jwt.SigningMethodRS256, keys, opts...)
+  default:
+    return nil, fmt.Errorf("unknown token type %s", tokenType)
+  }
+}
@@ -0,0 +1,94 @@
+package token
+
+import "time"
+
+// TokenOption customizes the payload of a single token
+type TokenOption func(*Payload)
+
+// WithScopes grants the token the given scopes
+func WithScopes(scopes ...string) TokenOption {
+	return func(p *Payload) {
+		p.Scopes = append([]string{}, scopes...)
+	}
+}
+
+// WithTokenType sets the kind of token, tokens are access tokens unless told otherwise
+func WithTokenType(tokenType string) TokenOption {
+	return func(p *Payload) {
+		p.TokenType = tokenType
+	}
+}
+
+// WithClientID marks the token as delegated to an OAuth client
+func WithClientID(clientID string) TokenOption {
+	return func(p *Payload) {
+		p.ClientID = clientID
+	}
+}
+
+// WithIssuer overrides the issuer the maker stamps on the token
+func WithIssuer(issuer string) TokenOption {
+	return func(p *Payload) {
+		p.Issuer = issuer
+	}
+}
+
+// WithAudience overrides the audience the maker stamps on the token
+func WithAudience(audience string) TokenOption {
+	return func(p *Payload) {
+		p.Audience = audience
+	}
+}
+
+// MakerOption configures the claims a maker stamps on new tokens
+// and expects on the tokens it verifies
+type MakerOption func(*claimSet)
+
+// WithExpectedIssuer makes the maker issue tokens as issuer and reject tokens from anyone else
+func WithExpectedIssuer(issuer string) MakerOption {
+	return func(c *claimSet) {
+		c.issuer = issuer
+	}
+}
+
+// WithExpectedAudience makes the maker issue tokens for audience and reject tokens meant for anyone else
+func WithExpectedAudience(audience string) MakerOption {
+	return func(c *claimSet) {
+		c.audience = audience
+	}
+}
+
+type claimSet struct {
+	issuer   string
+	audience string
+}
+
+func newClaimSet(opts []MakerOption) claimSet {
+	var c claimSet
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// newPayload creates the payload of a new token stamped with the maker claims
+func (c claimSet) newPayload(username string, role string, duration time.Duration, opts []TokenOption) (*Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return nil, err
+	}
+	payload.Issuer = c.issuer
+	payload.Audience = c.audience
+	for _, opt := range opts {
+		opt(payload)
+	}
+	return payload, nil
+}
+
+// emptyPayload returns the payload a token gets decoded into so Valid checks the maker claims
+func (c claimSet) emptyPayload() *Payload {
+	return &Payload{
+		expectedIssuer:   c.issuer,
+		expectedAudience: c.audience,
+	}
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakerClaims(t *testing.T) {
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPrivateFile, edPublicFile := writeKeyFiles(t, edPrivateKey, edPublicKey)
+
+	testCases := []struct {
+		tokenType   string
+		privateFile string
+		publicFile  string
+	}{
+		{TypePaseto, "", ""},
+		{TypeJWT, "", ""},
+		{TypePasetoV4Public, edPrivateFile, edPublicFile},
+		{TypeJWTEdDSA, edPrivateFile, edPublicFile},
+	}
+
+	symetricKey := utils.RandomString(32)
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.tokenType, func(t *testing.T) {
+			maker, err := NewMaker(tc.tokenType, symetricKey, tc.privateFile, tc.publicFile,
+				WithExpectedIssuer("simplebank"), WithExpectedAudience("simplebank-api"))
+			require.NoError(t, err)
+
+			token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute,
+				WithScopes("accounts:read", "transfers:write"), WithClientID("client-app"))
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", payload.Issuer)
+			require.Equal(t, "simplebank-api", payload.Audience)
+
+			verified, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", verified.Issuer)
+			require.Equal(t, "simplebank-api", verified.Audience)
+			require.Equal(t, []string{"accounts:read", "transfers:write"}, verified.Scopes)
+			require.True(t, verified.HasScope("accounts:read"))
+			require.False(t, verified.HasScope("accounts:write"))
+			require.Equal(t, "client-app", verified.ClientID)
+
+			token, _, err = maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute, WithIssuer("someone-else"))
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(token)
+			require.EqualError(t, err, ErrInvalidIssuer.Error())
+
+			token, _, err = maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute, WithAudience("another-api"))
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(token)
+			require.EqualError(t, err, ErrInvalidAudience.Error())
+
+			// a maker without expectations accepts tokens from any issuer
+			lenient, err := NewMaker(tc.tokenType, symetricKey, tc.privateFile, tc.publicFile)
+			require.NoError(t, err)
+			_, err = lenient.VerifyToken(token)
+			require.NoError(t, err)
+		})
+	}
+}
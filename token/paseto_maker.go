@@ -10,35 +10,61 @@ import (
 
 type PasetoMaker struct {
 	paseto		paseto.V2
-	symetricKey	[]byte
+	keys		*KeyRing
+	claims		claimSet
 }
 
 
-func NewPasetoMaker(symetricKey string) (Maker, error){
+func NewPasetoMaker(symetricKey string, opts ...MakerOption) (Maker, error){
+	keys, err := singleKeyRing([]byte(symetricKey), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewPasetoKeyRingMaker(keys, opts...)
+}
 
-	if len(symetricKey) < chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("invalid symeric key: length should be geather than %d", chacha20poly1305.KeySize)
+// NewPasetoKeyRingMaker creates a v2.local maker encrypting with the current key of the ring
+func NewPasetoKeyRingMaker(keys *KeyRing, opts ...MakerOption) (Maker, error){
+	for _, key := range keys.keys {
+		if len(key.Secret) < chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("invalid symeric key: length should be geather than %d", chacha20poly1305.KeySize)
+		}
 	}
 	maker := &PasetoMaker{
 		paseto: *paseto.NewV2(),
-		symetricKey: []byte(symetricKey),
+		keys: keys,
+		claims: newClaimSet(opts),
 	}
 
 	return maker, nil
 }
 
-func (p *PasetoMaker)CreateToken(username string, duration time.Duration)(string, error){
-	payload, err := NewPayload(username, duration)
+func (p *PasetoMaker)CreateToken(username string, role string, duration time.Duration, opts ...TokenOption)(string, *Payload, error){
+	payload, err := p.claims.newPayload(username, role, duration, opts)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return p.paseto.Encrypt(p.symetricKey, payload, nil)
+	key := p.keys.Current()
+	token, err := p.paseto.Encrypt(key.Secret, payload, keyFooter{KeyID: key.ID})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
 }
 
 func (p *PasetoMaker)VerifyToken(token string)(*Payload, error){
-	payload := &Payload{}
+	payload := p.claims.emptyPayload()
+
+	kid, err := pasetoKeyID(token)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.keys.Lookup(kid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
 
-	err := p.paseto.Decrypt(token, p.symetricKey, payload, nil)
+	err = p.paseto.Decrypt(token, key.Secret, payload, nil)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
@@ -18,16 +18,18 @@ func TestPasetoMaker(t *testing.T){
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(duration)
 
-	token, err := pasetoMaker.CreateToken(username, duration)
+	token, payload, err := pasetoMaker.CreateToken(username, utils.DepositorRole, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := pasetoMaker.VerifyToken(token)
+	payload, err = pasetoMaker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, utils.DepositorRole)
 	require.WithinDuration(t, payload.ExpiredAt, expiredAt, time.Second)
 	require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
 }
@@ -37,8 +39,13 @@ func TestExpiredPaseto(t *testing.T){
 	maker, err := NewPasetoMaker(utils.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(utils.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
-	require.EqualError(t, err, ErrInvalidToken.Error())
-	require.Empty(t, token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
 }
@@ -0,0 +1,121 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker signs PASETO public tokens with an Ed25519 private key,
+// anyone holding the public key can verify them without being able to mint new ones
+type PasetoPublicMaker struct {
+	version string
+	paseto  *paseto.V2
+	keys    *KeyRing
+	claims  claimSet
+}
+
+// NewPasetoV2PublicMaker creates a v2.public maker, privateKey may be nil for a verify only maker
+func NewPasetoV2PublicMaker(privateKey crypto.PrivateKey, publicKey crypto.PublicKey, opts ...MakerOption) (Maker, error) {
+	keys, err := singleKeyRing(nil, privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newPasetoPublicMaker("v2", keys, opts...)
+}
+
+// NewPasetoV4PublicMaker creates a v4.public maker, privateKey may be nil for a verify only maker
+func NewPasetoV4PublicMaker(privateKey crypto.PrivateKey, publicKey crypto.PublicKey, opts ...MakerOption) (Maker, error) {
+	keys, err := singleKeyRing(nil, privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newPasetoPublicMaker("v4", keys, opts...)
+}
+
+func newPasetoPublicMaker(version string, keys *KeyRing, opts ...MakerOption) (Maker, error) {
+	for _, key := range keys.keys {
+		if key.PrivateKey != nil {
+			if _, ok := key.PrivateKey.(ed25519.PrivateKey); !ok {
+				return nil, fmt.Errorf("invalid private key: paseto %s.public needs an ed25519 key, got %T", version, key.PrivateKey)
+			}
+		}
+		if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("invalid public key: paseto %s.public needs an ed25519 key, got %T", version, key.PublicKey)
+		}
+	}
+
+	maker := &PasetoPublicMaker{
+		version: version,
+		paseto:  paseto.NewV2(),
+		keys:    keys,
+		claims:  newClaimSet(opts),
+	}
+	return maker, nil
+}
+
+func (p *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration, opts ...TokenOption) (string, *Payload, error) {
+	key := p.keys.Current()
+	if key.PrivateKey == nil {
+		return "", nil, ErrVerifyOnlyMaker
+	}
+
+	payload, err := p.claims.newPayload(username, role, duration, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	footer := keyFooter{KeyID: key.ID}
+	var token string
+	if p.version == "v4" {
+		var data []byte
+		data, err = json.Marshal(footer)
+		if err != nil {
+			return "", nil, err
+		}
+		token, err = pasetoV4Sign(key.PrivateKey.(ed25519.PrivateKey), payload, data)
+	} else {
+		token, err = p.paseto.Sign(key.PrivateKey, payload, footer)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+func (p *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	payload := p.claims.emptyPayload()
+
+	kid, err := pasetoKeyID(token)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.keys.Lookup(kid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if p.version == "v4" {
+		_, err = pasetoV4Verify(token, key.PublicKey.(ed25519.PublicKey), payload)
+	} else {
+		err = p.paseto.Verify(token, key.PublicKey, payload, nil)
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// JWKS exposes the public keys tokens can be verified with
+func (p *PasetoPublicMaker) JWKS() JWKS {
+	return p.keys.jwks("EdDSA")
+}
@@ -0,0 +1,165 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPasetoPublicMaker(t *testing.T, version string, privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) Maker {
+	var maker Maker
+	var err error
+	if privateKey != nil {
+		if version == "v2" {
+			maker, err = NewPasetoV2PublicMaker(privateKey, nil)
+		} else {
+			maker, err = NewPasetoV4PublicMaker(privateKey, nil)
+		}
+	} else if version == "v2" {
+		maker, err = NewPasetoV2PublicMaker(nil, publicKey)
+	} else {
+		maker, err = NewPasetoV4PublicMaker(nil, publicKey)
+	}
+	require.NoError(t, err)
+	return maker
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, version := range []string{"v2", "v4"} {
+		t.Run(version, func(t *testing.T) {
+			maker := newTestPasetoPublicMaker(t, version, privateKey, nil)
+
+			username := utils.RandomOwner()
+			duration := time.Minute
+
+			token, payload, err := maker.CreateToken(username, utils.DepositorRole, duration)
+			require.NoError(t, err)
+			require.NotEmpty(t, token)
+			require.NotEmpty(t, payload)
+			require.True(t, strings.HasPrefix(token, version+".public."))
+
+			// verifiers only need the public key
+			verifier := newTestPasetoPublicMaker(t, version, nil, publicKey)
+
+			payload, err = verifier.VerifyToken(token)
+			require.NoError(t, err)
+			require.NotZero(t, payload.ID)
+			require.Equal(t, username, payload.Username)
+			require.WithinDuration(t, time.Now(), payload.IssuedAt, time.Second)
+			require.WithinDuration(t, time.Now().Add(duration), payload.ExpiredAt, time.Second)
+
+			_, _, err = verifier.CreateToken(username, utils.DepositorRole, duration)
+			require.EqualError(t, err, ErrVerifyOnlyMaker.Error())
+		})
+	}
+}
+
+func TestExpiredPasetoPublic(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoV4PublicMaker(privateKey, nil)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicSignature(t *testing.T) {
+	_, privateKey1, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicKey2, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, version := range []string{"v2", "v4"} {
+		t.Run(version, func(t *testing.T) {
+			maker := newTestPasetoPublicMaker(t, version, privateKey1, nil)
+			verifier := newTestPasetoPublicMaker(t, version, nil, publicKey2)
+
+			token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := verifier.VerifyToken(token)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+
+			// tampering with the message breaks the signature
+			payload, err = maker.VerifyToken(token[:len(token)-2] + "AA")
+			require.Error(t, err)
+			require.Nil(t, payload)
+		})
+	}
+}
+
+// TestPasetoV4PublicVectors checks v4.public against the official test vectors
+// https://github.com/paseto-standard/test-vectors/blob/master/v4.json
+func TestPasetoV4PublicVectors(t *testing.T) {
+	secretKey, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" +
+		"1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	publicKey, err := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(publicKey), ed25519.PrivateKey(secretKey).Public())
+
+	const (
+		message = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+		footer  = `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`
+	)
+
+	testCases := []struct {
+		name     string
+		token    string
+		footer   string
+		implicit string
+	}{
+		{
+			name: "4-S-1",
+			token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+				"bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+		},
+		{
+			name: "4-S-2",
+			token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+				"v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw" +
+				".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			footer: footer,
+		},
+		{
+			name: "4-S-3",
+			token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+				"NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ" +
+				".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			footer:   footer,
+			implicit: `{"test-vector":"4-S-3"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := pasetoV4SignMessage(ed25519.PrivateKey(secretKey), []byte(message), []byte(tc.footer), []byte(tc.implicit))
+			require.Equal(t, tc.token, token)
+
+			gotMessage, gotFooter, err := pasetoV4VerifyMessage(tc.token, ed25519.PublicKey(publicKey), []byte(tc.implicit))
+			require.NoError(t, err)
+			require.Equal(t, message, string(gotMessage))
+			require.Equal(t, tc.footer, string(gotFooter))
+
+			// the signature covers the implicit assertion
+			_, _, err = pasetoV4VerifyMessage(tc.token, ed25519.PublicKey(publicKey), []byte(`{"test-vector":"other"}`))
+			require.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
@@ -10,15 +10,35 @@ import (
 // expired token error
 var ErrExpiredToken = errors.New("token has expired") 
 var ErrInvalidToken = errors.New("invalid token !");
+var ErrInvalidIssuer = errors.New("token was issued by an unexpected issuer")
+var ErrInvalidAudience = errors.New("token is not intended for this audience")
+
+// kinds of token told apart by the token_type claim
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
 
 type Payload struct {
     ID          uuid.UUID	`json:"id"`
 	Username	string		`json:"username"` 
+	Role		string		`json:"role"`
+	Issuer		string		`json:"issuer,omitempty"`
+	Audience	string		`json:"audience,omitempty"`
+	Scopes		[]string	`json:"scopes,omitempty"`
+	// TokenType keeps refresh tokens from being used as access tokens and the other way around
+	TokenType	string		`json:"token_type"`
+	// ClientID names the OAuth client a delegated token was issued to, it is empty on the user's own tokens
+	ClientID	string		`json:"client_id,omitempty"`
 	IssuedAt	time.Time	`json:"issued_at"`
 	ExpiredAt	time.Time	`json:"expired_at"`
+
+	// set by the maker before decoding, checked by Valid
+	expectedIssuer		string
+	expectedAudience	string
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -27,6 +47,8 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID: tokenId,
 		Username: username,
+		Role: role,
+		TokenType: AccessTokenType,
 		IssuedAt: time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -39,5 +61,21 @@ func (p *Payload)Valid() error {
 	if time.Now().After(p.ExpiredAt){
 		return ErrExpiredToken;
 	}
+	if p.expectedIssuer != "" && p.Issuer != p.expectedIssuer {
+		return ErrInvalidIssuer
+	}
+	if p.expectedAudience != "" && p.Audience != p.expectedAudience {
+		return ErrInvalidAudience
+	}
 	return nil;
 }
+
+// HasScope checks if the token was granted scope
+func (p *Payload)HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrRevokedToken = errors.New("token has been revoked")
+
+// RevocationStore keeps track of tokens that were killed before reaching their expiration
+type RevocationStore interface {
+	// Revoke denies a single token identified by its payload ID
+	Revoke(ctx context.Context, payload *Payload) error
+
+	// RevokeAll denies every token of a user issued at or before the given time
+	RevokeAll(ctx context.Context, username string, before time.Time) error
+
+	// IsRevoked checks if the token has been revoked one way or the other
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore, mainly used in tests
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	tokens  map[uuid.UUID]time.Time
+	cutoffs map[string]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:  make(map[uuid.UUID]time.Time),
+		cutoffs: make(map[string]time.Time),
+	}
+}
+
+func (m *MemoryRevocationStore) Revoke(ctx context.Context, payload *Payload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, expiredAt := range m.tokens {
+		if now.After(expiredAt) {
+			delete(m.tokens, id)
+		}
+	}
+	m.tokens[payload.ID] = payload.ExpiredAt
+	return nil
+}
+
+func (m *MemoryRevocationStore) RevokeAll(ctx context.Context, username string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cutoff, ok := m.cutoffs[username]; !ok || before.After(cutoff) {
+		m.cutoffs[username] = before
+	}
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.tokens[payload.ID]; ok {
+		return true, nil
+	}
+	cutoff, ok := m.cutoffs[payload.Username]
+	return ok && !payload.IssuedAt.After(cutoff), nil
+}
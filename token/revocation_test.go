@@ -0,0 +1,66 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brkss/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	payload1, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := NewPayload(payload1.Username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.Revoke(context.Background(), payload1)
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevocationStoreRevokeAll(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	username := utils.RandomOwner()
+	oldPayload, err := NewPayload(username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeAll(context.Background(), username, time.Now())
+	require.NoError(t, err)
+
+	// an older cutoff never moves the existing one back
+	err = store.RevokeAll(context.Background(), username, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	newPayload, err := NewPayload(username, utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -10,8 +10,41 @@ type Config struct {
 	DbDriver     		string 			`mapstructure:"DB_DRIVER"`
 	DbSource     		string 			`mapstructure:"DB_SOURCE"`
 	ServerAdress 		string 			`mapstructure:"SERVER_ADDRESS"`
+	TokenType 		string 			`mapstructure:"TOKEN_TYPE"`
 	TokenSymetricKey 	string 			`mapstructure:"TOKEN_SYMETRIC_KEY"`
+	TokenPrivateKeyFile 	string 			`mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TokenPublicKeyFile 	string 			`mapstructure:"TOKEN_PUBLIC_KEY_FILE"`
+	TokenKeyRingFile 	string 			`mapstructure:"TOKEN_KEYRING_FILE"`
+	TokenIssuer 		string 			`mapstructure:"TOKEN_ISSUER"`
+	TokenAudience 		string 			`mapstructure:"TOKEN_AUDIENCE"`
 	TokenDuration 		time.Duration 	`mapstructure:"TOKEN_DURATION"`
+	RefreshTokenDuration 	time.Duration 	`mapstructure:"REFRESH_TOKEN_DURATION"`
+	SMTPHost 		string 			`mapstructure:"SMTP_HOST"`
+	SMTPPort 		int 			`mapstructure:"SMTP_PORT"`
+	SMTPUsername 		string 			`mapstructure:"SMTP_USERNAME"`
+	SMTPPassword 		string 			`mapstructure:"SMTP_PASSWORD"`
+	EmailSender 		string 			`mapstructure:"EMAIL_SENDER"`
+	PasswordResetURL 	string 			`mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetTokenDuration 	time.Duration 	`mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	VerifyEmailURL 		string 			`mapstructure:"VERIFY_EMAIL_URL"`
+	RequireEmailVerification 	bool 	`mapstructure:"REQUIRE_EMAIL_VERIFICATION"`
+	LoginMaxAttempts 	int 			`mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP 	int 			`mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginAttemptWindow 	time.Duration 	`mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	LoginLockoutDuration 	time.Duration 	`mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration time.Duration 	`mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	OAuthCodeDuration 	time.Duration 	`mapstructure:"OAUTH_CODE_DURATION"`
+	FXRateProvider 		string 			`mapstructure:"FX_RATE_PROVIDER"`
+	FXRatesFile 		string 			`mapstructure:"FX_RATES_FILE"`
+	FXQuoteDuration 	time.Duration 	`mapstructure:"FX_QUOTE_DURATION"`
+	HoldDuration 		time.Duration 	`mapstructure:"HOLD_DURATION"`
+	HoldSweepInterval 	time.Duration 	`mapstructure:"HOLD_SWEEP_INTERVAL"`
+	RevocationSweepInterval 	time.Duration 	`mapstructure:"REVOCATION_SWEEP_INTERVAL"`
+	SchedulerInterval 	time.Duration 	`mapstructure:"SCHEDULER_INTERVAL"`
+	ScheduledTransferMaxAttempts 	int 	`mapstructure:"SCHEDULED_TRANSFER_MAX_ATTEMPTS"`
+	ScheduledTransferRetryDelay 	time.Duration 	`mapstructure:"SCHEDULED_TRANSFER_RETRY_DELAY"`
+	FeeScheduleFile 	string 			`mapstructure:"FEE_SCHEDULE_FILE"`
+	TransferApprovalThreshold 	int64 	`mapstructure:"TRANSFER_APPROVAL_THRESHOLD"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -0,0 +1,4 @@
+package utils
+
+// SupportedCurrencies are the currencies accounts can be opened in
+var SupportedCurrencies = []string{"USD", "EUR", "CAD", "MAD"}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// RatePrecision is the number of decimals exchange rates are written with
+const RatePrecision = 10
+
+// ParseRate reads an exchange rate written as a decimal number, rates must be positive
+func ParseRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok {
+		return nil, fmt.Errorf("invalid exchange rate %q", rate)
+	}
+	if r.Sign() <= 0 {
+		return nil, fmt.Errorf("exchange rate %q must be positive", rate)
+	}
+	return r, nil
+}
+
+// FormatRate writes rate the way it's stored on quotes and transfers
+func FormatRate(rate *big.Rat) string {
+	return rate.FloatString(RatePrecision)
+}
+
+// ConvertAmount converts amount at rate, the result is rounded down to the smallest currency unit
+func ConvertAmount(amount int64, rate string) (int64, error) {
+	r, err := ParseRate(rate)
+	if err != nil {
+		return 0, err
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), r)
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !result.IsInt64() {
+		return 0, errors.New("converted amount is out of range")
+	}
+	return result.Int64(), nil
+}
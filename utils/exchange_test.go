@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("0.92")
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(23, 25), rate)
+
+	for _, invalid := range []string{"", "abc", "0", "-1.5"} {
+		_, err := ParseRate(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	require.Equal(t, "0.9200000000", FormatRate(big.NewRat(23, 25)))
+	require.Equal(t, "0.3333333333", FormatRate(big.NewRat(1, 3)))
+}
+
+func TestConvertAmount(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		rate     string
+		expected int64
+	}{
+		{amount: 1000, rate: "1", expected: 1000},
+		{amount: 1000, rate: "0.92", expected: 920},
+		{amount: 1000, rate: "1.3456", expected: 1345},
+		// rounded down to the smallest unit
+		{amount: 3, rate: "0.5", expected: 1},
+		{amount: 1, rate: "0.9200000000", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		got, err := ConvertAmount(tc.amount, tc.rate)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, got, "%d at %s", tc.amount, tc.rate)
+	}
+
+	_, err := ConvertAmount(1000, "nope")
+	require.Error(t, err)
+
+	_, err = ConvertAmount(1<<62, "4")
+	require.Error(t, err)
+}
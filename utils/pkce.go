@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEMethodS256 is the only code challenge method accepted, plain challenges
+// offer no protection once the authorization request leaks
+const PKCEMethodS256 = "S256"
+
+// PKCEChallenge derives the S256 code challenge of a PKCE code verifier (RFC 7636)
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE checks verifier against the S256 challenge sent with the authorization request
+func VerifyPKCE(verifier string, challenge string) bool {
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
@@ -47,7 +47,6 @@ func RandomMoney() int64 {
 
 // generate random curreny for account
 func RandomCurrency() string {
-	currencies := []string{"USD", "EUR", "CAD", "MAD"}
-	n := len(currencies)
-	return currencies[rand.Intn(n)]
+	n := len(SupportedCurrencies)
+	return SupportedCurrencies[rand.Intn(n)]
 }
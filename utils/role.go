@@ -0,0 +1,8 @@
+package utils
+
+// roles a user can be granted, stored in users.role and carried in tokens
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+	AdminRole     = "admin"
+)
@@ -0,0 +1,21 @@
+package utils
+
+// scopes a token can be granted, routes require the matching scope
+const (
+	AccountsReadScope   = "accounts:read"
+	AccountsWriteScope  = "accounts:write"
+	TransfersReadScope  = "transfers:read"
+	TransfersWriteScope = "transfers:write"
+
+	// MFAChallengeScope marks the short lived token handed out between the password
+	// and the TOTP step of a login, it is only accepted by POST /login/mfa
+	MFAChallengeScope = "mfa:challenge"
+)
+
+// DefaultScopes are granted to a login that doesn't ask for narrower ones
+var DefaultScopes = []string{
+	AccountsReadScope,
+	AccountsWriteScope,
+	TransfersReadScope,
+	TransfersWriteScope,
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+)
+
+// holdSweepBatchSize bounds how many expired holds are released per sweep
+const holdSweepBatchSize = 100
+
+// HoldSweeper periodically releases the funds of pending transfers past their expiry
+type HoldSweeper struct {
+	store    db.Store
+	interval time.Duration
+}
+
+func NewHoldSweeper(store db.Store, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run sweeps every interval until ctx is done
+func (sweeper *HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweeper.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := sweeper.Sweep(ctx)
+			if err != nil {
+				log.Println("cannot sweep expired holds : ", err)
+			}
+			if expired > 0 {
+				log.Printf("released %d expired holds", expired)
+			}
+		}
+	}
+}
+
+// Sweep releases the holds that are due and returns how many were released,
+// holds captured or voided in the meantime are skipped
+func (sweeper *HoldSweeper) Sweep(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		ids, err := sweeper.store.ListExpiredTransferHolds(ctx, holdSweepBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		released := 0
+		for _, id := range ids {
+			_, err := sweeper.store.ExpireTransferTx(ctx, id)
+			if errors.Is(err, db.ErrTransferNotPending) {
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			released++
+			expired++
+		}
+
+		// a batch where nothing could be released would come back as is
+		if len(ids) < holdSweepBatchSize || released == 0 {
+			return expired, nil
+		}
+	}
+}
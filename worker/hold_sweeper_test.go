@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldSweeperSweep(t *testing.T) {
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, expired int, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredTransferHolds(gomock.Any(), gomock.Eq(int32(holdSweepBatchSize))).
+					Times(1).
+					Return([]int64{1, 2, 3}, nil)
+				store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Eq(int64(1))).Times(1)
+				// captured between the listing and the sweep
+				store.EXPECT().
+					ExpireTransferTx(gomock.Any(), gomock.Eq(int64(2))).
+					Times(1).
+					Return(db.ReleaseTransferTxResult{}, db.ErrTransferNotPending)
+				store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Eq(int64(3))).Times(1)
+			},
+			check: func(t *testing.T, expired int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, expired)
+			},
+		},
+		{
+			name: "FullBatch",
+			buildStubs: func(store *mockdb.MockStore) {
+				batch := make([]int64, holdSweepBatchSize)
+				for i := range batch {
+					batch[i] = int64(i + 1)
+				}
+				gomock.InOrder(
+					store.EXPECT().
+						ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+						Return(batch, nil),
+					store.EXPECT().
+						ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+						Return([]int64{}, nil),
+				)
+				store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Any()).Times(holdSweepBatchSize)
+			},
+			check: func(t *testing.T, expired int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, holdSweepBatchSize, expired)
+			},
+		},
+		{
+			name: "ListError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+				store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, expired int, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Zero(t, expired)
+			},
+		},
+		{
+			name: "ExpireError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]int64{1, 2}, nil)
+				store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Eq(int64(1))).Times(1)
+				store.EXPECT().
+					ExpireTransferTx(gomock.Any(), gomock.Eq(int64(2))).
+					Times(1).
+					Return(db.ReleaseTransferTxResult{}, sql.ErrConnDone)
+			},
+			check: func(t *testing.T, expired int, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Equal(t, 1, expired)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			sweeper := NewHoldSweeper(store, time.Minute)
+			expired, err := sweeper.Sweep(context.Background())
+			tc.check(t, expired, err)
+		})
+	}
+}
+
+func TestHoldSweeperRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(_ context.Context, _ int32) ([]int64, error) {
+			cancel()
+			return []int64{}, nil
+		})
+
+	done := make(chan struct{})
+	go func() {
+		NewHoldSweeper(store, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper didn't stop once its context was done")
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+)
+
+// RevocationSweeper periodically deletes revoked tokens past their expiry,
+// an expired token is rejected on its own so its revocation isn't needed anymore
+type RevocationSweeper struct {
+	store    db.Store
+	interval time.Duration
+}
+
+func NewRevocationSweeper(store db.Store, interval time.Duration) *RevocationSweeper {
+	return &RevocationSweeper{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run sweeps every interval until ctx is done
+func (sweeper *RevocationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweeper.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := sweeper.Sweep(ctx)
+			if err != nil {
+				log.Println("cannot sweep expired revoked tokens : ", err)
+			}
+			if deleted > 0 {
+				log.Printf("deleted %d expired revoked tokens", deleted)
+			}
+		}
+	}
+}
+
+// Sweep deletes the revoked tokens that expired and returns how many were deleted
+func (sweeper *RevocationSweeper) Sweep(ctx context.Context) (int64, error) {
+	return sweeper.store.DeleteExpiredRevokedTokens(ctx)
+}
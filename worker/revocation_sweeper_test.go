@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationSweeperSweep(t *testing.T) {
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, deleted int64, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any()).
+					Times(1).
+					Return(int64(3), nil)
+			},
+			check: func(t *testing.T, deleted int64, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(3), deleted)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			check: func(t *testing.T, deleted int64, err error) {
+				require.True(t, errors.Is(err, sql.ErrConnDone))
+				require.Zero(t, deleted)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			sweeper := NewRevocationSweeper(store, time.Minute)
+			deleted, err := sweeper.Sweep(context.Background())
+			tc.check(t, deleted, err)
+		})
+	}
+}
+
+func TestRevocationSweeperRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(_ context.Context) (int64, error) {
+			cancel()
+			return 0, nil
+		})
+
+	done := make(chan struct{})
+	go func() {
+		NewRevocationSweeper(store, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper didn't stop once its context was done")
+	}
+}
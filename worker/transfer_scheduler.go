@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/brkss/simplebank/schedule"
+)
+
+// scheduledTransferBatchSize bounds how many due scheduled transfers are loaded at once
+const scheduledTransferBatchSize = 100
+
+// TransferScheduler periodically makes the transfers of scheduled transfers that are due,
+// a run short of funds is retried after retryDelay until maxAttempts is reached.
+// Runs above approvalThreshold record a transfer pending approval instead of moving money
+type TransferScheduler struct {
+	store             db.Store
+	interval          time.Duration
+	maxAttempts       int
+	retryDelay        time.Duration
+	approvalThreshold int64
+}
+
+func NewTransferScheduler(
+	store db.Store,
+	interval time.Duration,
+	maxAttempts int,
+	retryDelay time.Duration,
+	approvalThreshold int64,
+) *TransferScheduler {
+	return &TransferScheduler{
+		store:             store,
+		interval:          interval,
+		maxAttempts:       maxAttempts,
+		retryDelay:        retryDelay,
+		approvalThreshold: approvalThreshold,
+	}
+}
+
+// Run executes the due scheduled transfers every interval until ctx is done
+func (scheduler *TransferScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runs, err := scheduler.RunDue(ctx)
+			if err != nil {
+				log.Println("cannot run scheduled transfers : ", err)
+			}
+			if runs > 0 {
+				log.Printf("ran %d scheduled transfers", runs)
+			}
+		}
+	}
+}
+
+// RunDue executes the scheduled transfers that are due and returns how many runs were recorded,
+// a scheduled transfer that can't be run is logged and left for the next time
+func (scheduler *TransferScheduler) RunDue(ctx context.Context) (int, error) {
+	runs := 0
+	for {
+		due, err := scheduler.store.ListDueScheduledTransfers(ctx, scheduledTransferBatchSize)
+		if err != nil {
+			return runs, err
+		}
+
+		recorded := 0
+		for _, scheduledTransfer := range due {
+			err := scheduler.execute(ctx, scheduledTransfer)
+			if errors.Is(err, db.ErrScheduledRunStale) {
+				continue
+			}
+			if err != nil {
+				log.Printf("cannot run scheduled transfer %d : %v", scheduledTransfer.ID, err)
+				continue
+			}
+			recorded++
+			runs++
+		}
+
+		// a batch where nothing could be recorded would come back as is
+		if len(due) < scheduledTransferBatchSize || recorded == 0 {
+			return runs, nil
+		}
+	}
+}
+
+// execute runs the next attempt of scheduledTransfer, its transfer and outcome are recorded
+// together so an attempt claimed by another run or recorded before a crash isn't paid twice
+func (scheduler *TransferScheduler) execute(ctx context.Context, scheduledTransfer db.ScheduledTransfer) error {
+	_, err := scheduler.store.RunScheduledTransferTx(ctx, db.RunScheduledTransferTxParams{
+		ScheduledTransfer: scheduledTransfer,
+		Transfer: db.TransferTxParams{
+			FromAccountId:   scheduledTransfer.FromAccountID,
+			ToAccountId:     scheduledTransfer.ToAccountID,
+			Amount:          scheduledTransfer.Amount,
+			RequireApproval: db.RequiresApproval(scheduler.approvalThreshold, scheduledTransfer.Amount),
+		},
+		Outcome: func(result db.TransferTxResult, err error) (db.ScheduledTransferRunOutcome, error) {
+			return scheduler.outcome(scheduledTransfer, result, err)
+		},
+	})
+	return err
+}
+
+// outcome works out the run to record for the transfer made by the next attempt of scheduledTransfer
+// and where the schedule goes from there, errors retrying or failing the schedule can't handle are returned
+func (scheduler *TransferScheduler) outcome(
+	scheduledTransfer db.ScheduledTransfer,
+	result db.TransferTxResult,
+	err error,
+) (db.ScheduledTransferRunOutcome, error) {
+	attempt := scheduledTransfer.Attempts + 1
+	outcome := db.ScheduledTransferRunOutcome{
+		Run: db.CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduledTransfer.ID,
+			ScheduledFor:        scheduledTransfer.NextRunAt.Time,
+			Attempt:             attempt,
+		},
+	}
+
+	switch {
+	case err == nil:
+		outcome.Run.Status = db.ScheduledRunStatusSucceeded
+		outcome.Run.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+		outcome.Progress = advance(scheduledTransfer)
+	case errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrLimitExceeded):
+		// funds may come in and limits reset, a later attempt can go through
+		outcome.Run.Error = err.Error()
+		if attempt < int64(scheduler.maxAttempts) {
+			outcome.Run.Status = db.ScheduledRunStatusRetrying
+			outcome.Progress = db.UpdateScheduledTransferProgressParams{
+				ID:        scheduledTransfer.ID,
+				RunCount:  scheduledTransfer.RunCount,
+				Status:    db.ScheduledTransferStatusActive,
+				NextRunAt: scheduledTransfer.NextRunAt,
+				Attempts:  attempt,
+				RetryAt:   sql.NullTime{Time: time.Now().Add(scheduler.retryDelay), Valid: true},
+			}
+		} else {
+			// this run is given up but the following ones still happen
+			outcome.Run.Status = db.ScheduledRunStatusFailed
+			outcome.Progress = advance(scheduledTransfer)
+		}
+	case err == sql.ErrNoRows || errors.Is(err, db.ErrCurrencyMismatch):
+		// the accounts can't take the transfer anymore, no later run would do better
+		outcome.Run.Status = db.ScheduledRunStatusFailed
+		outcome.Run.Error = err.Error()
+		outcome.Progress = db.UpdateScheduledTransferProgressParams{
+			ID:       scheduledTransfer.ID,
+			RunCount: scheduledTransfer.RunCount,
+			Status:   db.ScheduledTransferStatusFailed,
+		}
+	default:
+		return outcome, err
+	}
+
+	return outcome, nil
+}
+
+// advance moves scheduledTransfer on to the run following its current one,
+// completing it once its schedule, end date or maximum number of runs is reached
+func advance(scheduledTransfer db.ScheduledTransfer) db.UpdateScheduledTransferProgressParams {
+	progress := db.UpdateScheduledTransferProgressParams{
+		ID:       scheduledTransfer.ID,
+		RunCount: scheduledTransfer.RunCount + 1,
+		Status:   db.ScheduledTransferStatusCompleted,
+	}
+
+	if scheduledTransfer.MaxRuns.Valid && progress.RunCount >= scheduledTransfer.MaxRuns.Int64 {
+		return progress
+	}
+
+	s, err := schedule.Parse(scheduledTransfer.Schedule)
+	if err != nil {
+		// schedules are checked when they're saved, one that stopped parsing has nothing to run anymore
+		return progress
+	}
+	next := s.Next(scheduledTransfer.NextRunAt.Time)
+	if next.IsZero() || (scheduledTransfer.EndAt.Valid && next.After(scheduledTransfer.EndAt.Time)) {
+		return progress
+	}
+
+	progress.Status = db.ScheduledTransferStatusActive
+	progress.NextRunAt = sql.NullTime{Time: next, Valid: true}
+	return progress
+}
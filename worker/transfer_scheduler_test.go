@@ -0,0 +1,282 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/brkss/simplebank/db/mock"
+	db "github.com/brkss/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferSchedulerRunDue(t *testing.T) {
+	scheduledFor := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	scheduledTransfer := db.ScheduledTransfer{
+		ID:            1,
+		Owner:         "owner",
+		FromAccountID: 10,
+		ToAccountID:   20,
+		Amount:        500,
+		Currency:      "USD",
+		Schedule:      "0 9 1 * *",
+		RunCount:      2,
+		Status:        db.ScheduledTransferStatusActive,
+		NextRunAt:     sql.NullTime{Time: scheduledFor, Valid: true},
+	}
+	nextMonth := sql.NullTime{Time: time.Date(2030, time.February, 1, 9, 0, 0, 0, time.UTC), Valid: true}
+
+	retrying := scheduledTransfer
+	retrying.Attempts = 1
+
+	large := scheduledTransfer
+	large.Amount = 5000
+
+	testCases := []struct {
+		name       string
+		due        db.ScheduledTransfer
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, runs int, err error)
+	}{
+		{
+			name: "OK",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				result := db.TransferTxResult{Transfer: db.Transfer{ID: 99}}
+				runScheduledTransfer(t, store, result, nil, func(arg db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, scheduledTransfer, arg.ScheduledTransfer)
+					require.Equal(t, int64(10), arg.Transfer.FromAccountId)
+					require.Equal(t, int64(20), arg.Transfer.ToAccountId)
+					require.Equal(t, int64(500), arg.Transfer.Amount)
+					require.False(t, arg.Transfer.RequireApproval)
+
+					require.Equal(t, db.ScheduledTransferRunOutcome{
+						Run: db.CreateScheduledTransferRunParams{
+							ScheduledTransferID: 1,
+							ScheduledFor:        scheduledFor,
+							Attempt:             1,
+							Status:              db.ScheduledRunStatusSucceeded,
+							TransferID:          sql.NullInt64{Int64: 99, Valid: true},
+						},
+						Progress: db.UpdateScheduledTransferProgressParams{
+							ID:        1,
+							RunCount:  3,
+							Status:    db.ScheduledTransferStatusActive,
+							NextRunAt: nextMonth,
+						},
+					}, outcome)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "AboveApprovalThreshold",
+			due:  large,
+			buildStubs: func(store *mockdb.MockStore) {
+				result := db.TransferTxResult{Transfer: db.Transfer{ID: 99, Status: db.TransferStatusPendingApproval}}
+				runScheduledTransfer(t, store, result, nil, func(arg db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, int64(5000), arg.Transfer.Amount)
+					require.True(t, arg.Transfer.RequireApproval)
+					require.Equal(t, db.ScheduledRunStatusSucceeded, outcome.Run.Status)
+					require.Equal(t, sql.NullInt64{Int64: 99, Valid: true}, outcome.Run.TransferID)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "InsufficientFundsRetries",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				runScheduledTransfer(t, store, db.TransferTxResult{}, db.ErrInsufficientFunds, func(_ db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, db.ScheduledRunStatusRetrying, outcome.Run.Status)
+					require.Equal(t, db.ErrInsufficientFunds.Error(), outcome.Run.Error)
+					require.False(t, outcome.Run.TransferID.Valid)
+					require.Equal(t, int64(2), outcome.Progress.RunCount)
+					require.Equal(t, int64(1), outcome.Progress.Attempts)
+					require.Equal(t, scheduledTransfer.NextRunAt, outcome.Progress.NextRunAt)
+					require.WithinDuration(t, time.Now().Add(time.Hour), outcome.Progress.RetryAt.Time, time.Second)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "LimitExceededRetries",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				transferErr := fmt.Errorf("%w: daily outgoing total is 100", db.ErrLimitExceeded)
+				runScheduledTransfer(t, store, db.TransferTxResult{}, transferErr, func(_ db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, db.ScheduledRunStatusRetrying, outcome.Run.Status)
+					require.Equal(t, int64(1), outcome.Progress.Attempts)
+					require.True(t, outcome.Progress.RetryAt.Valid)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "InsufficientFundsGivesUp",
+			due:  retrying,
+			buildStubs: func(store *mockdb.MockStore) {
+				runScheduledTransfer(t, store, db.TransferTxResult{}, db.ErrInsufficientFunds, func(_ db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, db.ScheduledRunStatusFailed, outcome.Run.Status)
+					require.Equal(t, int64(2), outcome.Run.Attempt)
+					// the next month still runs
+					require.Equal(t, db.ScheduledTransferStatusActive, outcome.Progress.Status)
+					require.Equal(t, nextMonth, outcome.Progress.NextRunAt)
+					require.Zero(t, outcome.Progress.Attempts)
+					require.False(t, outcome.Progress.RetryAt.Valid)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "AccountGone",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				runScheduledTransfer(t, store, db.TransferTxResult{}, sql.ErrNoRows, func(_ db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome) {
+					require.Equal(t, db.ScheduledRunStatusFailed, outcome.Run.Status)
+					require.Equal(t, db.ScheduledTransferStatusFailed, outcome.Progress.Status)
+					require.False(t, outcome.Progress.NextRunAt.Valid)
+				})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			},
+		},
+		{
+			name: "TransientError",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RunScheduledTransferTxParams) (db.RunScheduledTransferTxResult, error) {
+						// the run is rolled back and left for the next time
+						_, err := arg.Outcome(db.TransferTxResult{}, sql.ErrConnDone)
+						require.ErrorIs(t, err, sql.ErrConnDone)
+						return db.RunScheduledTransferTxResult{}, err
+					})
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, runs)
+			},
+		},
+		{
+			name: "ClaimedByAnotherRun",
+			due:  scheduledTransfer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RunScheduledTransferTxResult{}, db.ErrScheduledRunStale)
+			},
+			check: func(t *testing.T, runs int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, runs)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ListDueScheduledTransfers(gomock.Any(), gomock.Eq(int32(scheduledTransferBatchSize))).
+				Times(1).
+				Return([]db.ScheduledTransfer{tc.due}, nil)
+			tc.buildStubs(store)
+
+			scheduler := NewTransferScheduler(store, time.Minute, 2, time.Hour, 1000)
+			runs, err := scheduler.RunDue(context.Background())
+			tc.check(t, runs, err)
+		})
+	}
+}
+
+// runScheduledTransfer stubs a run of a scheduled transfer whose transfer returns result and transferErr,
+// check gets the params of the run and the outcome the scheduler worked out for it
+func runScheduledTransfer(
+	t *testing.T,
+	store *mockdb.MockStore,
+	result db.TransferTxResult,
+	transferErr error,
+	check func(arg db.RunScheduledTransferTxParams, outcome db.ScheduledTransferRunOutcome),
+) {
+	store.EXPECT().
+		RunScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.RunScheduledTransferTxParams) (db.RunScheduledTransferTxResult, error) {
+			outcome, err := arg.Outcome(result, transferErr)
+			require.NoError(t, err)
+			check(arg, outcome)
+			return db.RunScheduledTransferTxResult{Transfer: result}, nil
+		})
+}
+
+func TestTransferSchedulerListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListDueScheduledTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+
+	scheduler := NewTransferScheduler(store, time.Minute, 2, time.Hour, 1000)
+	_, err := scheduler.RunDue(context.Background())
+	require.True(t, errors.Is(err, sql.ErrConnDone))
+}
+
+func TestAdvance(t *testing.T) {
+	scheduledFor := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	scheduledTransfer := db.ScheduledTransfer{
+		ID:        1,
+		Schedule:  "@every 24h",
+		RunCount:  4,
+		NextRunAt: sql.NullTime{Time: scheduledFor, Valid: true},
+		Attempts:  1,
+	}
+
+	progress := advance(scheduledTransfer)
+	require.Equal(t, int64(5), progress.RunCount)
+	require.Equal(t, db.ScheduledTransferStatusActive, progress.Status)
+	require.Equal(t, scheduledFor.Add(24*time.Hour), progress.NextRunAt.Time)
+	require.Zero(t, progress.Attempts)
+
+	lastRun := scheduledTransfer
+	lastRun.MaxRuns = sql.NullInt64{Int64: 5, Valid: true}
+	progress = advance(lastRun)
+	require.Equal(t, db.ScheduledTransferStatusCompleted, progress.Status)
+	require.False(t, progress.NextRunAt.Valid)
+
+	ending := scheduledTransfer
+	ending.EndAt = sql.NullTime{Time: scheduledFor.Add(time.Hour), Valid: true}
+	progress = advance(ending)
+	require.Equal(t, db.ScheduledTransferStatusCompleted, progress.Status)
+	require.False(t, progress.NextRunAt.Valid)
+}